@@ -18,6 +18,17 @@ const (
 	BrilliantMove
 )
 
+func (q MoveQuality) String() string {
+	switch q {
+	case GreatMove:
+		return "great"
+	case BrilliantMove:
+		return "brilliant"
+	default:
+		return "best"
+	}
+}
+
 var (
 	bestMoveColor      = color.RGBA{155, 199, 0, 200}
 	greatMoveColor     = color.RGBA{0, 121, 211, 200}
@@ -36,7 +47,8 @@ type BoostRoom struct {
 	Name      string
 	Time      float64
 	BoostTime float64
-	Quality   MoveQuality
+	// Quality is the label curated by hand
+	Quality MoveQuality
 }
 
 type Room struct {
@@ -123,8 +135,8 @@ var RoomMap = map[string]Room{
 		{Name: "cp 0-1", Time: 14.1, BoostTime: 5.0, Quality: GreatMove},
 	}},
 	"4c": {Name: "4c", BoostlessTime: 14.7, Difficulty: Easy, BoostStrats: []BoostRoom{
-		{Name: "cp 1-2", Time: 11.7, BoostTime: 9.7, Quality: BestMove},
-		{Name: "cp 1-2", Time: 12.2, BoostTime: 2.0, Quality: GreatMove},
+		{Name: "cp 1-2 (Late)", Time: 11.7, BoostTime: 9.7, Quality: BestMove},
+		{Name: "cp 1-2 (Early)", Time: 12.2, BoostTime: 2.0, Quality: GreatMove},
 	}},
 	"4e": {Name: "4e", BoostlessTime: 18.0, Difficulty: Easy, BoostStrats: []BoostRoom{
 		{Name: "cp 0-1", Time: 12.7, BoostTime: 3.0, Quality: BestMove},
@@ -173,7 +185,7 @@ var RoomMap = map[string]Room{
 		{Name: "cp 1-2", Time: 10.6, BoostTime: 8.4, Quality: BestMove},
 	}},
 	"3f": {Name: "3f", BoostlessTime: 26.4, Difficulty: Hard, BoostStrats: []BoostRoom{
-		{Name: "cp 1-2 ", Time: 20.0, BoostTime: 8.2, Quality: BestMove},
+		{Name: "cp 1-2", Time: 20.0, BoostTime: 8.2, Quality: BestMove},
 	}},
 	"3g": {Name: "3g", BoostlessTime: 19.4, Difficulty: Hard, BoostStrats: []BoostRoom{
 		{Name: "cp 2-3", Time: 15.4, BoostTime: 14.1, Quality: BestMove},
@@ -400,6 +412,13 @@ func mergeSortedResults(a, b []calcResult) []calcResult {
 }
 
 func calcSeedInternal(roomList []string, splits map[string]Room) ([]CalcSeedResult, error) {
+	// A missing room would count as 0 seconds
+	for _, room := range roomList {
+		if _, exists := splits[room]; !exists {
+			return nil, fmt.Errorf("room %q is not in the splits", room)
+		}
+	}
+
 	boostlessTime := calcBoostless(roomList, splits)
 
 	res := make([]CalcSeedResult, 0, 5)
@@ -447,6 +466,8 @@ func CalcSeed(roomList []string) ([]CalcSeedResult, error) {
 	return calcSeedInternal(roomList, RoomMap)
 }
 
+// CalcSeedCustom calcs a seed with a full split set, use MergeSplits to get
+// one from overrides
 func CalcSeedCustom(roomList []string, splits map[string]Room) ([]CalcSeedResult, error) {
 	if roomList[len(roomList)-1] != "finish room" {
 		roomList = append(roomList, "finish room")
@@ -0,0 +1,121 @@
+package calc
+
+import (
+	"fmt"
+	"math"
+)
+
+// RoomTimeDeviation is the standard deviation of a room time as a fraction of
+// the time, how much a run differs from the splits
+var RoomTimeDeviation = 0.04
+
+// DuelPlayer is the best route of one player in a duel
+type DuelPlayer struct {
+	Result CalcSeedResult
+	// RoomTimes holds how long each room takes on the route, pacelocks included
+	RoomTimes []float64
+	// Deviation is the standard deviation of the total time
+	Deviation float64
+}
+
+// DuelResult compares the best routes of two players on a seed
+type DuelResult struct {
+	// Rooms includes the finish room
+	Rooms []string
+	A, B  DuelPlayer
+	// Delta holds the time of A minus the time of B after each room, negative
+	// while A is ahead
+	Delta []float64
+	// WinProbability is the chance A finishes first
+	WinProbability float64
+}
+
+// Duel calcs the best route of two players with their own splits and how
+// likely the first one is to win, assuming room times are independent and
+// normally distributed with RoomTimeDeviation
+func Duel(roomList []string, a, b map[string]Room) (DuelResult, error) {
+	if len(roomList) == 0 {
+		return DuelResult{}, fmt.Errorf("the seed has no rooms")
+	}
+	rooms := append([]string{}, roomList...)
+	if rooms[len(rooms)-1] != "finish room" {
+		rooms = append(rooms, "finish room")
+	}
+
+	playerA, err := duelPlayer(rooms, a)
+	if err != nil {
+		return DuelResult{}, fmt.Errorf("error calculating the first player: %w", err)
+	}
+	playerB, err := duelPlayer(rooms, b)
+	if err != nil {
+		return DuelResult{}, fmt.Errorf("error calculating the second player: %w", err)
+	}
+
+	delta := make([]float64, len(rooms))
+	total := 0.0
+	for i := range rooms {
+		total += playerA.RoomTimes[i] - playerB.RoomTimes[i]
+		delta[i] = total
+	}
+
+	return DuelResult{
+		Rooms:          rooms,
+		A:              playerA,
+		B:              playerB,
+		Delta:          delta,
+		WinProbability: winProbability(playerA, playerB),
+	}, nil
+}
+
+func duelPlayer(rooms []string, splits map[string]Room) (DuelPlayer, error) {
+	results, err := CalcSeedCustom(rooms, splits)
+	if err != nil {
+		return DuelPlayer{}, err
+	}
+
+	best := results[0]
+	roomTimes := RoomTimes(rooms, splits, best)
+
+	variance := 0.0
+	for _, t := range roomTimes {
+		variance += math.Pow(t*RoomTimeDeviation, 2)
+	}
+
+	return DuelPlayer{
+		Result:    best,
+		RoomTimes: roomTimes,
+		Deviation: math.Sqrt(variance),
+	}, nil
+}
+
+// RoomTimes returns how long each room takes on the route of a result. A
+// pacelock counts towards the room it is waited in, so the times add up to
+// the boost time of the result.
+func RoomTimes(rooms []string, splits map[string]Room, result CalcSeedResult) []float64 {
+	times := make([]float64, len(rooms))
+	for i, room := range rooms {
+		times[i] = splits[room].BoostlessTime
+	}
+
+	for _, boost := range result.BoostRooms {
+		times[boost.Ind] = splits[rooms[boost.Ind]].BoostStrats[boost.StratInd].Time + boost.Pacelock
+	}
+
+	return times
+}
+
+func winProbability(a, b DuelPlayer) float64 {
+	lead := b.Result.BoostTime - a.Result.BoostTime
+	deviation := math.Hypot(a.Deviation, b.Deviation)
+	if deviation == 0 {
+		switch {
+		case lead > 0:
+			return 1
+		case lead < 0:
+			return 0
+		}
+		return 0.5
+	}
+
+	return 0.5 * (1 + math.Erf(lead/(deviation*math.Sqrt2)))
+}
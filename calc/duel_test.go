@@ -0,0 +1,100 @@
+package calc
+
+import (
+	"math"
+	"testing"
+)
+
+var testRooms = []string{"1a", "2b", "3c", "4e", "5a", "1c", "2f", "3g"}
+
+// fasterSplits returns the community splits with every time scaled by factor
+func fasterSplits(t *testing.T, factor float64) map[string]Room {
+	t.Helper()
+
+	overrides := make(SplitOverrides)
+	for name, room := range RoomMap {
+		override := RoomOverride{BoostlessTime: room.BoostlessTime * factor, Strats: make(map[string]StratOverride)}
+		for _, strat := range room.BoostStrats {
+			override.Strats[strat.Name] = StratOverride{Time: strat.Time * factor, BoostTime: strat.BoostTime * factor}
+		}
+		overrides[name] = override
+	}
+
+	splits, err := MergeSplits(RoomMap, overrides)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return splits
+}
+
+func TestDuelWinProbability(t *testing.T) {
+	faster := fasterSplits(t, 0.97)
+	slower := fasterSplits(t, 1.05)
+
+	tests := []struct {
+		name string
+		a, b map[string]Room
+		// favoured is 1 if a should win more often, -1 if b should and 0 for a coin flip
+		favoured int
+	}{
+		{"same splits", RoomMap, RoomMap, 0},
+		{"faster first", faster, RoomMap, 1},
+		{"faster second", RoomMap, faster, -1},
+		{"far apart", faster, slower, 1},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ab, err := Duel(testRooms, test.a, test.b)
+			if err != nil {
+				t.Fatal(err)
+			}
+			ba, err := Duel(testRooms, test.b, test.a)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if sum := ab.WinProbability + ba.WinProbability; math.Abs(sum-1) > 1e-9 {
+				t.Errorf("the win probabilities of both sides add up to %f", sum)
+			}
+
+			switch {
+			case test.favoured == 0 && math.Abs(ab.WinProbability-0.5) > 1e-9:
+				t.Errorf("expected a coin flip, got %f", ab.WinProbability)
+			case test.favoured > 0 && ab.WinProbability <= 0.5:
+				t.Errorf("expected the first player to be favoured, got %f", ab.WinProbability)
+			case test.favoured < 0 && ab.WinProbability >= 0.5:
+				t.Errorf("expected the second player to be favoured, got %f", ab.WinProbability)
+			}
+
+			final := ab.Delta[len(ab.Delta)-1]
+			if want := ab.A.Result.BoostTime - ab.B.Result.BoostTime; math.Abs(final-want) > 1e-9 {
+				t.Errorf("the final delta is %f, want %f", final, want)
+			}
+		})
+	}
+}
+
+func TestRoomTimesAddUp(t *testing.T) {
+	rooms := append(append([]string{}, testRooms...), "finish room")
+	results, err := CalcSeed(append([]string{}, rooms...))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, result := range results[:10] {
+		total := 0.0
+		for _, time := range RoomTimes(rooms, RoomMap, result) {
+			total += time
+		}
+		if math.Abs(total-result.BoostTime) > 1e-9 {
+			t.Errorf("room times add up to %f, the boost time is %f", total, result.BoostTime)
+		}
+	}
+}
+
+func TestDuelNoRooms(t *testing.T) {
+	if _, err := Duel(nil, RoomMap, RoomMap); err == nil {
+		t.Error("expected an error")
+	}
+}
@@ -0,0 +1,155 @@
+package calc
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"slices"
+)
+
+// QualitySamples is how many seeds AnalyzeQualities calcs
+var QualitySamples = 2000
+
+// BrilliantMargin is how much faster on average the best route has to be
+// with a strat than without it for the strat to be brilliant
+var BrilliantMargin = 1.0
+
+// SlotDifficulty returns the room difficulty allowed in the given 1-based room slot.
+// Slots 1-6 take easy rooms, slots 7-8 take hard rooms.
+func SlotDifficulty(slot int) Difficulty {
+	if slot >= 1 && slot <= 6 {
+		return Easy
+	}
+	return Hard
+}
+
+// DrawSeed draws 8 rooms of the splits like a real seed, easy rooms in the
+// first slots and hard ones in the last. The same r draws the same rooms.
+func DrawSeed(r *rand.Rand, splits map[string]Room) ([]string, error) {
+	names := make([]string, 0, len(splits))
+	for name := range splits {
+		if name != "finish room" {
+			names = append(names, name)
+		}
+	}
+	// Sorted so the pools are the same on every start
+	slices.Sort(names)
+
+	pools := make(map[Difficulty][]string)
+	for _, name := range names {
+		difficulty := splits[name].Difficulty
+		pools[difficulty] = append(pools[difficulty], name)
+	}
+
+	for _, difficulty := range []Difficulty{Easy, Hard} {
+		pool := pools[difficulty]
+		r.Shuffle(len(pool), func(a, b int) { pool[a], pool[b] = pool[b], pool[a] })
+	}
+
+	rooms := make([]string, 0, 8)
+	for slot := 1; slot <= 8; slot++ {
+		difficulty := SlotDifficulty(slot)
+		if len(pools[difficulty]) == 0 {
+			return nil, fmt.Errorf("not enough rooms to draw slot %d", slot)
+		}
+		rooms = append(rooms, pools[difficulty][0])
+		pools[difficulty] = pools[difficulty][1:]
+	}
+	return rooms, nil
+}
+
+// StratAnalysis is how a strat does across the seeds AnalyzeQualities calcs
+type StratAnalysis struct {
+	// Seeds is how many seeds had the room of the strat
+	Seeds int
+	// Optimal is how many of them boost the room with the strat in the best route
+	Optimal int
+	// Margin is how much faster the best route is on average than the best
+	// one without the strat, over the seeds where it is optimal
+	Margin float64
+	// Quality is the label the analysis gives the strat
+	Quality MoveQuality
+}
+
+// OptimalRate is the share of the seeds with the room where the strat is in the best route
+func (a StratAnalysis) OptimalRate() float64 {
+	if a.Seeds == 0 {
+		return 0
+	}
+	return float64(a.Optimal) / float64(a.Seeds)
+}
+
+// AnalyzeQualities calcs QualitySamples seeds drawn from the splits and
+// labels every strat: the strat of a room that is most often in the best
+// route is the best move, the others are brilliant if the best route gains
+// BrilliantMargin with them and great otherwise. A room never boosted in a
+// best route has no best move. The same splits always get the same labels.
+// It returns the analysis of every strat by room and leaves the splits as is.
+func AnalyzeQualities(splits map[string]Room) (map[string][]StratAnalysis, error) {
+	analysis := make(map[string][]StratAnalysis, len(splits))
+	for name, room := range splits {
+		analysis[name] = make([]StratAnalysis, len(room.BoostStrats))
+	}
+
+	r := rand.New(rand.NewPCG(1, 2))
+	for sample := 0; sample < QualitySamples; sample++ {
+		rooms, err := DrawSeed(r, splits)
+		if err != nil {
+			return nil, err
+		}
+		rooms = append(rooms, "finish room")
+
+		results, err := calcSeedInternal(rooms, splits)
+		if err != nil {
+			return nil, err
+		}
+		best := results[0]
+
+		for ind, name := range rooms {
+			for stratInd := range analysis[name] {
+				analysis[name][stratInd].Seeds++
+			}
+
+			k := slices.IndexFunc(best.BoostRooms, func(b CalcResultBoost) bool { return b.Ind == ind })
+			if k < 0 {
+				continue
+			}
+			stratInd := best.BoostRooms[k].StratInd
+
+			// The results are sorted, so the first one without the strat is the best without it
+			for _, result := range results {
+				if !slices.ContainsFunc(result.BoostRooms, func(b CalcResultBoost) bool {
+					return b.Ind == ind && b.StratInd == stratInd
+				}) {
+					analysis[name][stratInd].Margin += result.BoostTime - best.BoostTime
+					break
+				}
+			}
+			analysis[name][stratInd].Optimal++
+		}
+	}
+
+	for _, strats := range analysis {
+		mostOptimal := -1
+		for stratInd := range strats {
+			if strats[stratInd].Optimal == 0 {
+				continue
+			}
+			strats[stratInd].Margin /= float64(strats[stratInd].Optimal)
+			if mostOptimal < 0 || strats[stratInd].Optimal > strats[mostOptimal].Optimal {
+				mostOptimal = stratInd
+			}
+		}
+
+		for stratInd := range strats {
+			strats[stratInd].Quality = GreatMove
+			switch {
+			case stratInd == mostOptimal:
+				strats[stratInd].Quality = BestMove
+			case strats[stratInd].Optimal > 0 && strats[stratInd].Margin >= BrilliantMargin:
+				strats[stratInd].Quality = BrilliantMove
+			}
+		}
+	}
+
+	return analysis, nil
+}
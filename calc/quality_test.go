@@ -0,0 +1,87 @@
+package calc
+
+import (
+	"maps"
+	"reflect"
+	"slices"
+	"testing"
+)
+
+func TestAnalyzeQualities(t *testing.T) {
+	before := maps.Clone(RoomMap)
+	for name, room := range before {
+		room.BoostStrats = slices.Clone(room.BoostStrats)
+		before[name] = room
+	}
+
+	analysis, err := AnalyzeQualities(RoomMap)
+	if err != nil {
+		t.Fatal(err)
+	}
+	again, err := AnalyzeQualities(RoomMap)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(analysis, again) {
+		t.Error("the same splits got different labels")
+	}
+	if !reflect.DeepEqual(before, RoomMap) {
+		t.Error("the analysis changed the splits")
+	}
+
+	// The labels of the fixed seed
+	tests := map[string][]MoveQuality{
+		"1a":          {BestMove, GreatMove},
+		"2b":          {BestMove, BrilliantMove, GreatMove},
+		"4c":          {BestMove, BrilliantMove},
+		"4h":          {BestMove, BrilliantMove},
+		"finish room": {BestMove},
+	}
+	for name, want := range tests {
+		got := make([]MoveQuality, len(analysis[name]))
+		for k, strat := range analysis[name] {
+			got[k] = strat.Quality
+		}
+		if !slices.Equal(got, want) {
+			t.Errorf("%s: got %v, want %v", name, got, want)
+		}
+	}
+
+	for name, strats := range analysis {
+		best := slices.IndexFunc(strats, func(a StratAnalysis) bool { return a.Quality == BestMove })
+		if best < 0 {
+			continue
+		}
+		for k, strat := range strats {
+			if strat.Optimal > strats[best].Optimal {
+				t.Errorf("%s: strat %d is optimal more often than the best move", name, k)
+			}
+		}
+	}
+}
+
+func TestAnalyzeQualitiesNeverOptimal(t *testing.T) {
+	slow := BoostRoom{Name: "slow", Time: 14, BoostTime: 1}
+	strats := RoomMap["1a"].BoostStrats
+
+	tests := []struct {
+		name   string
+		strats []BoostRoom
+	}{
+		{"with other strats", append(slices.Clone(strats), slow)},
+		{"only strat", []BoostRoom{slow}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			analysis, err := AnalyzeQualities(withStrats("1a", test.strats...))
+			if err != nil {
+				t.Fatal(err)
+			}
+			strat := analysis["1a"][len(test.strats)-1]
+			if strat.Optimal != 0 || strat.Quality != GreatMove {
+				t.Errorf("the slow strat is optimal in %d seeds and labeled %v", strat.Optimal, strat.Quality)
+			}
+		})
+	}
+}
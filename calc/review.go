@@ -0,0 +1,230 @@
+package calc
+
+import (
+	"fmt"
+	"math"
+)
+
+// ReviewGrade is how good a decision of a run was, like in a chess game review
+type ReviewGrade int
+
+const (
+	GradeBest ReviewGrade = iota
+	GradeGood
+	GradeInaccuracy
+	GradeMistake
+	GradeBlunder
+)
+
+// gradeLimits are the most time a decision can lose and still get each grade
+var gradeLimits = []struct {
+	Grade ReviewGrade
+	Lost  float64
+}{
+	{GradeBest, 0.05},
+	{GradeGood, 0.5},
+	{GradeInaccuracy, 1.5},
+	{GradeMistake, 3},
+}
+
+// AccuracyScale is the time a decision loses to score about 37% accuracy,
+// accuracy falls off exponentially with the time lost
+var AccuracyScale = 2.0
+
+func (g ReviewGrade) String() string {
+	switch g {
+	case GradeBest:
+		return "best"
+	case GradeGood:
+		return "good"
+	case GradeInaccuracy:
+		return "inaccuracy"
+	case GradeMistake:
+		return "mistake"
+	default:
+		return "blunder"
+	}
+}
+
+// gradeOf grades a decision by the time it lost
+func gradeOf(lost float64) ReviewGrade {
+	for _, limit := range gradeLimits {
+		if lost <= limit.Lost {
+			return limit.Grade
+		}
+	}
+	return GradeBlunder
+}
+
+// ReviewMove is the decision taken in a room: boost it with a strat or not
+type ReviewMove struct {
+	// StratInd is the strat boosted with, -1 if the room wasn't boosted
+	StratInd int
+	// BestStratInd is the decision of the best continuation from here, -1
+	// to not boost
+	BestStratInd int
+	// Lost is how much slower the best continuation gets with this decision
+	Lost  float64
+	Grade ReviewGrade
+	// Decision is false when there was only one way to finish the run from
+	// here
+	Decision bool
+	// Expected is the time of the room on the route with the splits, its
+	// pacelock included, and Actual the time of the run
+	Expected float64
+	Actual   float64
+}
+
+// Review grades the decisions of a run
+type Review struct {
+	// Rooms includes the finish room
+	Rooms []string
+	Route RouteEvaluation
+	// Moves holds the decision of every room
+	Moves []ReviewMove
+	// Accuracy is the average accuracy of the decisions before the finish
+	// room from 0 to 100
+	Accuracy float64
+	// Actual is the time of the run
+	Actual float64
+}
+
+// runState is where a run stands at the start of a room
+type runState struct {
+	boosts int
+	// sinceBoost is the time since the last boost was used
+	sinceBoost float64
+}
+
+// play takes a decision in a room, returning the time of the room with its
+// pacelock and the state after it. ok is false if there's no boost left.
+func (r runState) play(room Room, stratInd int) (float64, runState, bool) {
+	if stratInd < 0 {
+		if r.boosts > 0 {
+			r.sinceBoost += room.BoostlessTime
+		}
+		return room.BoostlessTime, r, true
+	}
+	if r.boosts == 3 {
+		return 0, r, false
+	}
+
+	strat := room.BoostStrats[stratInd]
+	pacelock := 0.0
+	if r.boosts > 0 {
+		pacelock = max(0, 60-(r.sinceBoost+strat.BoostTime))
+	}
+	return strat.Time + pacelock, runState{boosts: r.boosts + 1, sinceBoost: strat.Time - strat.BoostTime}, true
+}
+
+// bestRest is the time of the fastest way to finish a run from a room, +Inf
+// if a route can't be finished from there
+func bestRest(rooms []Room, ind int, state runState) float64 {
+	if ind == len(rooms) {
+		if state.boosts < 2 {
+			return math.Inf(1)
+		}
+		return 0
+	}
+
+	best := math.Inf(1)
+	for stratInd := -1; stratInd < len(rooms[ind].BoostStrats); stratInd++ {
+		if t, next, ok := state.play(rooms[ind], stratInd); ok {
+			best = min(best, t+bestRest(rooms, ind+1, next))
+		}
+	}
+	return best
+}
+
+// ReviewRun grades every decision of a run on a seed. A decision loses the
+// time the best way to finish gets slower by taking it. Pacelock depends on
+// the time between boosts, so the continuations are calced from the time
+// the run actually took to get there. splitTimes holds the time of the run in
+// every room, the finish room included.
+func ReviewRun(roomList []string, route []RouteBoost, splitTimes []float64, splits map[string]Room) (Review, error) {
+	if len(roomList) == 0 {
+		return Review{}, fmt.Errorf("the seed has no rooms")
+	}
+	rooms := append([]string{}, roomList...)
+	if rooms[len(rooms)-1] != "finish room" {
+		rooms = append(rooms, "finish room")
+	}
+
+	if len(splitTimes) != len(rooms) {
+		return Review{}, fmt.Errorf("expected %d split times, got %d", len(rooms), len(splitTimes))
+	}
+
+	evaluation, err := EvaluateRoute(rooms, route, splits)
+	if err != nil {
+		return Review{}, err
+	}
+
+	seed := make([]Room, len(rooms))
+	for ind, name := range rooms {
+		seed[ind] = splits[name]
+	}
+	played := make([]int, len(rooms))
+	for ind := range played {
+		played[ind] = -1
+	}
+	for _, boost := range evaluation.Result.BoostRooms {
+		played[boost.Ind] = boost.StratInd
+	}
+
+	review := Review{
+		Rooms: rooms,
+		Route: evaluation,
+		Moves: make([]ReviewMove, len(rooms)),
+	}
+
+	expected := RoomTimes(rooms, splits, evaluation.Result)
+	accuracy, decisions := 0.0, 0
+	state := runState{}
+	for ind := range rooms {
+		// How fast the run can finish with each decision
+		move := ReviewMove{StratInd: played[ind], Expected: expected[ind], Actual: splitTimes[ind]}
+		best, playedTime, options := math.Inf(1), math.Inf(1), 0
+		for stratInd := -1; stratInd < len(seed[ind].BoostStrats); stratInd++ {
+			t, next, ok := state.play(seed[ind], stratInd)
+			if !ok {
+				continue
+			}
+			t += bestRest(seed, ind+1, next)
+			if math.IsInf(t, 1) {
+				continue
+			}
+
+			options++
+			if t < best {
+				best, move.BestStratInd = t, stratInd
+			}
+			if stratInd == played[ind] {
+				playedTime = t
+			}
+		}
+
+		move.Lost = max(0, playedTime-best)
+		move.Grade = gradeOf(move.Lost)
+		move.Decision = options > 1
+		if move.Decision && rooms[ind] != "finish room" {
+			accuracy += 100 * math.Exp(-move.Lost/AccuracyScale)
+			decisions++
+		}
+		review.Moves[ind] = move
+		review.Actual += splitTimes[ind]
+
+		// The time after a boost isn't in the splits, so it's taken from
+		// the strat, every other room takes the time of the run
+		_, state, _ = state.play(seed[ind], played[ind])
+		if played[ind] < 0 && state.boosts > 0 {
+			state.sinceBoost += splitTimes[ind] - seed[ind].BoostlessTime
+		}
+	}
+
+	review.Accuracy = 100
+	if decisions > 0 {
+		review.Accuracy = accuracy / float64(decisions)
+	}
+
+	return review, nil
+}
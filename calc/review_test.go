@@ -0,0 +1,116 @@
+package calc
+
+import (
+	"math"
+	"slices"
+	"testing"
+)
+
+func TestGradeOf(t *testing.T) {
+	tests := []struct {
+		lost  float64
+		grade ReviewGrade
+	}{
+		{0, GradeBest},
+		{0.05, GradeBest},
+		{0.06, GradeGood},
+		{0.5, GradeGood},
+		{0.51, GradeInaccuracy},
+		{1.5, GradeInaccuracy},
+		{1.51, GradeMistake},
+		{3, GradeMistake},
+		{3.01, GradeBlunder},
+		{60, GradeBlunder},
+	}
+
+	for _, test := range tests {
+		if grade := gradeOf(test.lost); grade != test.grade {
+			t.Errorf("%.2fs lost: got %s, want %s", test.lost, grade, test.grade)
+		}
+	}
+}
+
+// reviewAsPlanned reviews a route of the calc run exactly at the splits
+func reviewAsPlanned(t *testing.T, result CalcSeedResult) Review {
+	t.Helper()
+
+	rooms := append(slices.Clone(testRooms), "finish room")
+	review, err := ReviewRun(testRooms, routeOf(testRooms, RoomMap, result), RoomTimes(rooms, RoomMap, result), RoomMap)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return review
+}
+
+func TestReviewRun(t *testing.T) {
+	results, err := CalcSeed(slices.Clone(testRooms))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	best := reviewAsPlanned(t, results[0])
+	if best.Accuracy != 100 || math.Abs(best.Actual-results[0].BoostTime) > 1e-9 {
+		t.Errorf("best route: %.1f%% accuracy in %.2fs, want 100%% in %.2fs", best.Accuracy, best.Actual, results[0].BoostTime)
+	}
+	for k, move := range best.Moves {
+		if move.Grade != GradeBest {
+			t.Errorf("best route: room %d graded %s", k, move.Grade)
+		}
+	}
+
+	// Every room after the third boost is just run through
+	three := slices.IndexFunc(results, func(result CalcSeedResult) bool {
+		return len(result.BoostRooms) == 3 && result.BoostRooms[2].Ind < len(testRooms)-1
+	})
+	if three < 0 {
+		t.Fatal("no three boost route with rooms after the last boost")
+	}
+	threeBoost := reviewAsPlanned(t, results[three])
+	for ind := results[three].BoostRooms[2].Ind + 1; ind < len(threeBoost.Moves); ind++ {
+		if threeBoost.Moves[ind].Decision {
+			t.Errorf("three boost route: room %d after the last boost is a decision", ind)
+		}
+	}
+
+	worst := reviewAsPlanned(t, results[len(results)-1])
+	lost := 0.0
+	for _, move := range worst.Moves {
+		lost += move.Lost
+	}
+	if worst.Accuracy >= 100 || math.Abs(lost-worst.Route.Delta) > 1e-9 {
+		t.Errorf("worst route: %.1f%% accuracy, %.2fs lost over the moves, %.2fs behind", worst.Accuracy, lost, worst.Route.Delta)
+	}
+}
+
+func TestReviewRunRealSplits(t *testing.T) {
+	rooms := append(slices.Clone(testRooms), "finish room")
+	route := []RouteBoost{{Room: "2b", Strat: RoomMap["2b"].BoostStrats[0].Name}, {Room: "1c", Strat: RoomMap["1c"].BoostStrats[0].Name}}
+	evaluation, err := EvaluateRoute(testRooms, route, RoomMap)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	planned := RoomTimes(rooms, RoomMap, evaluation.Result)
+	asPlanned, err := ReviewRun(testRooms, route, planned, RoomMap)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Running the rooms between the boosts much faster brings pacelock
+	// into the second boost, waiting for a later room gets better
+	fast := slices.Clone(planned)
+	for ind := 2; ind < 5; ind++ {
+		fast[ind] -= 6
+	}
+	fastReview, err := ReviewRun(testRooms, route, fast, RoomMap)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if asPlanned.Moves[1] != fastReview.Moves[1] {
+		t.Errorf("the first boost was graded differently before the run got faster: %+v and %+v", asPlanned.Moves[1], fastReview.Moves[1])
+	}
+	if fastReview.Moves[5].Lost <= asPlanned.Moves[5].Lost {
+		t.Errorf("the second boost lost %.2fs as planned and %.2fs in the faster run", asPlanned.Moves[5].Lost, fastReview.Moves[5].Lost)
+	}
+}
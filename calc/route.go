@@ -0,0 +1,125 @@
+package calc
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// RouteBoost is a boost of a route by the names of its room and strat
+type RouteBoost struct {
+	Room  string
+	Strat string
+}
+
+// RouteEvaluation is how a route does on a seed compared to every other route
+type RouteEvaluation struct {
+	// Result is the route with its time and pacelocks
+	Result CalcSeedResult
+	Best   CalcSeedResult
+	// Rank is 1 plus how many routes are faster
+	Rank   int
+	Routes int
+	// Delta is how much slower than the best route it is
+	Delta float64
+}
+
+// rankTolerance is how close two route times are to count as tied
+const rankTolerance = 1e-9
+
+// EvaluateRoute calcs a route of 2 or 3 boosts on a seed and ranks it among
+// all routes. Strat names are matched ignoring case and surrounding spaces.
+func EvaluateRoute(roomList []string, route []RouteBoost, splits map[string]Room) (RouteEvaluation, error) {
+	if len(roomList) == 0 {
+		return RouteEvaluation{}, fmt.Errorf("the seed has no rooms")
+	}
+	rooms := append([]string{}, roomList...)
+	if rooms[len(rooms)-1] != "finish room" {
+		rooms = append(rooms, "finish room")
+	}
+
+	if len(route) < 2 || len(route) > 3 {
+		return RouteEvaluation{}, fmt.Errorf("a route has 2 or 3 boosts, got %d", len(route))
+	}
+
+	boosts := make([]CalcResultBoost, 0, len(route))
+	for _, boost := range route {
+		ind := slices.Index(rooms, strings.ToLower(strings.TrimSpace(boost.Room)))
+		if ind < 0 {
+			return RouteEvaluation{}, fmt.Errorf("room %q is not in the seed", boost.Room)
+		}
+		if slices.ContainsFunc(boosts, func(b CalcResultBoost) bool { return b.Ind == ind }) {
+			return RouteEvaluation{}, fmt.Errorf("room %q is boosted twice", boost.Room)
+		}
+
+		room, exists := splits[rooms[ind]]
+		if !exists {
+			return RouteEvaluation{}, fmt.Errorf("room %q is not in the splits", rooms[ind])
+		}
+		stratInd, err := findStrat(room, boost.Strat)
+		if err != nil {
+			return RouteEvaluation{}, err
+		}
+
+		boosts = append(boosts, CalcResultBoost{Ind: ind, StratInd: stratInd})
+	}
+	slices.SortFunc(boosts, func(a, b CalcResultBoost) int { return a.Ind - b.Ind })
+
+	results, err := calcSeedInternal(rooms, splits)
+	if err != nil {
+		return RouteEvaluation{}, err
+	}
+
+	match := slices.IndexFunc(results, func(result CalcSeedResult) bool {
+		if len(result.BoostRooms) != len(boosts) {
+			return false
+		}
+		for k, boost := range result.BoostRooms {
+			if boost.Ind != boosts[k].Ind || boost.StratInd != boosts[k].StratInd {
+				return false
+			}
+		}
+		return true
+	})
+	if match < 0 {
+		return RouteEvaluation{}, fmt.Errorf("route %v is not in the calc results, this is a programming error", boosts)
+	}
+
+	// Routes tied with this one don't push it down
+	evaluation := RouteEvaluation{
+		Result: results[match],
+		Best:   results[0],
+		Routes: len(results),
+		Delta:  results[match].BoostTime - results[0].BoostTime,
+	}
+	for _, result := range results {
+		if result.BoostTime >= evaluation.Result.BoostTime-rankTolerance {
+			break
+		}
+		evaluation.Rank++
+	}
+	evaluation.Rank++
+
+	return evaluation, nil
+}
+
+// findStrat returns the index of the only strat of a room with the name
+func findStrat(room Room, name string) (int, error) {
+	name = strings.TrimSpace(name)
+	found := -1
+	names := make([]string, len(room.BoostStrats))
+	for k, strat := range room.BoostStrats {
+		names[k] = strat.Name
+		if !strings.EqualFold(strings.TrimSpace(strat.Name), name) {
+			continue
+		}
+		if found >= 0 {
+			return -1, fmt.Errorf("room %q has more than one strat named %q", room.Name, name)
+		}
+		found = k
+	}
+	if found < 0 {
+		return -1, fmt.Errorf("room %q has no strat %q, pick one of %s", room.Name, name, strings.Join(names, ", "))
+	}
+	return found, nil
+}
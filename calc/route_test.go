@@ -0,0 +1,113 @@
+package calc
+
+import (
+	"maps"
+	"slices"
+	"testing"
+)
+
+// withStrats returns the community splits with the strats of a room replaced
+func withStrats(room string, strats ...BoostRoom) map[string]Room {
+	splits := maps.Clone(RoomMap)
+	r := splits[room]
+	r.BoostStrats = strats
+	splits[room] = r
+	return splits
+}
+
+// routeOf names the boosts of a calc result
+func routeOf(rooms []string, splits map[string]Room, result CalcSeedResult) []RouteBoost {
+	rooms = append(slices.Clone(rooms), "finish room")
+	route := make([]RouteBoost, len(result.BoostRooms))
+	for k, boost := range result.BoostRooms {
+		route[k] = RouteBoost{Room: rooms[boost.Ind], Strat: splits[rooms[boost.Ind]].BoostStrats[boost.StratInd].Name}
+	}
+	return route
+}
+
+func TestEvaluateRouteTies(t *testing.T) {
+	strats := RoomMap["1a"].BoostStrats
+	twin := strats[0]
+	twin.Name = "cp 1-2 twin"
+	splits := withStrats("1a", append(slices.Clone(strats), twin)...)
+
+	results, err := CalcSeedCustom(slices.Clone(testRooms), splits)
+	if err != nil {
+		t.Fatal(err)
+	}
+	match := slices.IndexFunc(results, func(result CalcSeedResult) bool {
+		return slices.ContainsFunc(result.BoostRooms, func(b CalcResultBoost) bool { return b.Ind == 0 && b.StratInd == 0 })
+	})
+	if match < 0 {
+		t.Fatal("no route boosts 1a with cp 1-2")
+	}
+
+	route := routeOf(testRooms, splits, results[match])
+	twinRoute := slices.Clone(route)
+	twinRoute[0].Strat = twin.Name
+
+	a, err := EvaluateRoute(testRooms, route, splits)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := EvaluateRoute(testRooms, twinRoute, splits)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a.Rank != b.Rank || a.Delta != b.Delta {
+		t.Errorf("tied routes rank %d and %d, %.2fs and %.2fs behind", a.Rank, b.Rank, a.Delta, b.Delta)
+	}
+
+	faster := 0
+	for _, result := range results {
+		if result.BoostTime < a.Result.BoostTime-rankTolerance {
+			faster++
+		}
+	}
+	if a.Rank != faster+1 {
+		t.Errorf("got rank %d with %d faster routes", a.Rank, faster)
+	}
+}
+
+func TestEvaluateRouteErrors(t *testing.T) {
+	strats := RoomMap["1a"].BoostStrats
+	duplicate := withStrats("1a", strats[0], strats[0])
+	route := []RouteBoost{{Room: "1a", Strat: "cp 1-2"}, {Room: "3c", Strat: RoomMap["3c"].BoostStrats[0].Name}}
+
+	tests := []struct {
+		name   string
+		rooms  []string
+		route  []RouteBoost
+		splits map[string]Room
+	}{
+		{"no rooms", nil, route, RoomMap},
+		{"one boost", testRooms, route[:1], RoomMap},
+		{"room not in seed", testRooms, []RouteBoost{{Room: "5e", Strat: "cp 1-2"}, route[1]}, RoomMap},
+		{"unknown strat", testRooms, []RouteBoost{{Room: "1a", Strat: "cp 9-9"}, route[1]}, RoomMap},
+		{"ambiguous strat", testRooms, route, duplicate},
+		{"same room twice", testRooms, []RouteBoost{route[0], {Room: "1a", Strat: "cp 0-1"}}, RoomMap},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if _, err := EvaluateRoute(test.rooms, test.route, test.splits); err == nil {
+				t.Error("expected an error")
+			}
+		})
+	}
+}
+
+func TestEvaluateRouteSecondStrat(t *testing.T) {
+	rooms := []string{"1a", "2b", "3c", "4c", "5a", "1c", "2f", "3g"}
+	for _, strat := range RoomMap["4c"].BoostStrats {
+		route := []RouteBoost{{Room: "1a", Strat: "cp 1-2"}, {Room: "4C", Strat: " " + strat.Name}}
+		evaluation, err := EvaluateRoute(rooms, route, RoomMap)
+		if err != nil {
+			t.Fatalf("%s: %v", strat.Name, err)
+		}
+		boost := evaluation.Result.BoostRooms[1]
+		if got := RoomMap["4c"].BoostStrats[boost.StratInd].Name; boost.Ind != 3 || got != strat.Name {
+			t.Errorf("asked for %s, evaluated room %d strat %s", strat.Name, boost.Ind, got)
+		}
+	}
+}
@@ -0,0 +1,160 @@
+package calc
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"slices"
+	"strings"
+)
+
+// LoadSplits reads a split set from a JSON file mapping room names to rooms,
+// in the same shape as RoomMap, and validates it
+func LoadSplits(path string) (map[string]Room, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading splits: %w", err)
+	}
+
+	var splits map[string]Room
+	if err := json.Unmarshal(data, &splits); err != nil {
+		return nil, fmt.Errorf("error parsing splits %s: %w", path, err)
+	}
+
+	// Strat names are typed by users, spaces around them are never meant
+	for _, room := range splits {
+		for i := range room.BoostStrats {
+			room.BoostStrats[i].Name = strings.TrimSpace(room.BoostStrats[i].Name)
+		}
+	}
+
+	if err := ValidateSplits(splits); err != nil {
+		return nil, fmt.Errorf("invalid splits %s: %w", path, err)
+	}
+
+	return splits, nil
+}
+
+// ValidateSplits checks that a split set can be used to calc seeds: every room
+// is keyed by its lowercase name, has a difficulty, positive times and boost
+// times within the room, and the finish room is there. Strats are found by
+// name, so their names must be trimmed and unique within a room ignoring case.
+func ValidateSplits(splits map[string]Room) error {
+	var errs []error
+
+	if _, exists := splits["finish room"]; !exists {
+		errs = append(errs, fmt.Errorf("missing the finish room"))
+	}
+
+	for key, room := range splits {
+		if key != strings.ToLower(room.Name) {
+			errs = append(errs, fmt.Errorf("room %q is named %q", key, room.Name))
+		}
+		if room.Difficulty != Easy && room.Difficulty != Hard {
+			errs = append(errs, fmt.Errorf("room %q has an unknown difficulty %d", key, room.Difficulty))
+		}
+		if !finite(room.BoostlessTime) || room.BoostlessTime <= 0 {
+			errs = append(errs, fmt.Errorf("room %q has no boostless time", key))
+		}
+
+		names := make(map[string]bool, len(room.BoostStrats))
+		for _, strat := range room.BoostStrats {
+			name := strings.ToLower(strat.Name)
+			switch {
+			case name == "" || name != strings.TrimSpace(name):
+				errs = append(errs, fmt.Errorf("strat %q of room %q needs a name without spaces around it", strat.Name, key))
+			case names[name]:
+				errs = append(errs, fmt.Errorf("room %q has more than one strat named %q", key, strat.Name))
+			}
+			names[name] = true
+
+			if !finite(strat.Time) || strat.Time <= 0 {
+				errs = append(errs, fmt.Errorf("strat %q of room %q has no time", strat.Name, key))
+			}
+			if !finite(strat.BoostTime) || strat.BoostTime < 0 || strat.BoostTime > strat.Time {
+				errs = append(errs, fmt.Errorf("strat %q of room %q boosts outside the room", strat.Name, key))
+			}
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// StratOverride replaces the times of a boost strat. A zero BoostTime keeps
+// the boost time of the base splits.
+type StratOverride struct {
+	Time      float64 `json:"time"`
+	BoostTime float64 `json:"boost_time,omitempty"`
+}
+
+// RoomOverride replaces the times of a room. A zero BoostlessTime keeps the
+// base boostless time and strats missing from Strats keep their base times.
+type RoomOverride struct {
+	BoostlessTime float64                  `json:"boostless_time,omitempty"`
+	Strats        map[string]StratOverride `json:"strats,omitempty"`
+}
+
+// SplitOverrides changes some times of a split set, keyed by lowercase room
+// name and strat name
+type SplitOverrides map[string]RoomOverride
+
+// MergeSplits returns a copy of base with the overrides laid over it. Rooms
+// and strats keep their names, difficulty and order, so results calculated
+// with the merged splits can be named using either set. Overrides for rooms
+// or strats base doesn't have are an error, and so is a merged set that
+// doesn't pass ValidateSplits.
+func MergeSplits(base map[string]Room, overrides SplitOverrides) (map[string]Room, error) {
+	var errs []error
+
+	for name, override := range overrides {
+		room, exists := base[name]
+		if !exists {
+			errs = append(errs, fmt.Errorf("unknown room %q", name))
+			continue
+		}
+		for strat := range override.Strats {
+			if !slices.ContainsFunc(room.BoostStrats, func(b BoostRoom) bool { return b.Name == strat }) {
+				errs = append(errs, fmt.Errorf("room %q has no strat %q", name, strat))
+			}
+		}
+	}
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+
+	merged := make(map[string]Room, len(base))
+	for name, room := range base {
+		room.BoostStrats = slices.Clone(room.BoostStrats)
+
+		if override, exists := overrides[name]; exists {
+			if override.BoostlessTime > 0 {
+				room.BoostlessTime = override.BoostlessTime
+			}
+			for i, strat := range room.BoostStrats {
+				stratOverride, exists := override.Strats[strat.Name]
+				if !exists {
+					continue
+				}
+				room.BoostStrats[i].Time = stratOverride.Time
+				if stratOverride.BoostTime > 0 {
+					room.BoostStrats[i].BoostTime = stratOverride.BoostTime
+				}
+			}
+		}
+
+		merged[name] = room
+	}
+
+	if err := ValidateSplits(merged); err != nil {
+		return nil, err
+	}
+
+	return merged, nil
+}
+
+// finite reports whether a time is neither NaN nor infinite
+func finite(t float64) bool {
+	return !math.IsNaN(t) && !math.IsInf(t, 0)
+}
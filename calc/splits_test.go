@@ -0,0 +1,69 @@
+package calc
+
+import (
+	"math"
+	"slices"
+	"testing"
+)
+
+func TestValidateSplits(t *testing.T) {
+	if err := ValidateSplits(RoomMap); err != nil {
+		t.Fatalf("the community splits are invalid: %v", err)
+	}
+
+	withStrats := func(strats ...BoostRoom) map[string]Room {
+		splits := map[string]Room{"finish room": RoomMap["finish room"]}
+		splits["1a"] = Room{Name: "1a", BoostlessTime: 13.6, Difficulty: Easy, BoostStrats: strats}
+		return splits
+	}
+
+	tests := []struct {
+		name   string
+		splits map[string]Room
+		valid  bool
+	}{
+		{"distinct names", withStrats(BoostRoom{Name: "cp 1-2", Time: 10, BoostTime: 9}, BoostRoom{Name: "cp 0-1", Time: 12, BoostTime: 4}), true},
+		{"duplicate names", withStrats(BoostRoom{Name: "cp 1-2", Time: 10, BoostTime: 9}, BoostRoom{Name: "cp 1-2", Time: 12, BoostTime: 2}), false},
+		{"duplicate names in another case", withStrats(BoostRoom{Name: "cp 1-2 (late)", Time: 10, BoostTime: 9}, BoostRoom{Name: "CP 1-2 (Late)", Time: 12, BoostTime: 2}), false},
+		{"trailing space", withStrats(BoostRoom{Name: "cp 1-2 ", Time: 10, BoostTime: 9}), false},
+		{"no name", withStrats(BoostRoom{Time: 10, BoostTime: 9}), false},
+		{"NaN time", withStrats(BoostRoom{Name: "cp 1-2", Time: math.NaN(), BoostTime: 9}), false},
+		{"NaN boost time", withStrats(BoostRoom{Name: "cp 1-2", Time: 10, BoostTime: math.NaN()}), false},
+		{"infinite time", withStrats(BoostRoom{Name: "cp 1-2", Time: math.Inf(1), BoostTime: 9}), false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := ValidateSplits(test.splits)
+			if test.valid && err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+			if !test.valid && err == nil {
+				t.Errorf("expected an error")
+			}
+		})
+	}
+}
+
+func TestMergeSplitsOverridesOneStrat(t *testing.T) {
+	merged, err := MergeSplits(RoomMap, SplitOverrides{
+		"4c": {Strats: map[string]StratOverride{"cp 1-2 (Early)": {Time: 11.9}}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for k, strat := range merged["4c"].BoostStrats {
+		want := RoomMap["4c"].BoostStrats[k].Time
+		if strat.Name == "cp 1-2 (Early)" {
+			want = 11.9
+		}
+		if strat.Time != want {
+			t.Errorf("strat %q has time %.1f, want %.1f", strat.Name, strat.Time, want)
+		}
+	}
+
+	if slices.Equal(merged["4c"].BoostStrats, RoomMap["4c"].BoostStrats) {
+		t.Errorf("the override changed nothing")
+	}
+}
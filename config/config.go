@@ -0,0 +1,323 @@
+// Package config loads the bot configuration from defaults, an optional JSON
+// config file, the environment (and .env) and command line flags, each
+// overriding the previous one.
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/joho/godotenv"
+)
+
+// Duration is a time.Duration written like "5m" in config files
+type Duration time.Duration
+
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	var text string
+	if err := json.Unmarshal(data, &text); err != nil {
+		return fmt.Errorf("expected a duration like \"5m\": %w", err)
+	}
+
+	duration, err := time.ParseDuration(text)
+	if err != nil {
+		return err
+	}
+	*d = Duration(duration)
+	return nil
+}
+
+// Config holds every setting of the bot
+type Config struct {
+	BotToken string `json:"bot_token"`
+	// GuildID is the home guild, which gets announcements by default
+	GuildID string `json:"guild_id"`
+	Debug   bool   `json:"debug"`
+	// GuildCommands registers the commands in the home guild only, where
+	// changes show up right away, instead of globally
+	GuildCommands bool `json:"guild_commands"`
+	// HTTPAddr is where the web UI listens, it is off when empty
+	HTTPAddr string `json:"http_addr"`
+
+	// DataDir holds the files the bot persists
+	DataDir string `json:"data_dir"`
+	// AssetDir holds the font and images directories
+	AssetDir string `json:"asset_dir"`
+	// SplitsFile replaces the built-in community splits when set
+	SplitsFile string `json:"splits_file"`
+
+	StatsProvider string `json:"stats_provider"`
+	HypixelAPIKey string `json:"hypixel_api_key"`
+
+	// AnnouncementThreshold is the default boost time in seconds under which
+	// seeds are announced, guilds can change it with /config
+	AnnouncementThreshold float64 `json:"announcement_threshold"`
+
+	// ButtonTimeout is how long result buttons stay after the last click
+	ButtonTimeout Duration `json:"button_timeout"`
+	// ShowCalcTimeout is how long the ShowCalc button stays after the others are removed
+	ShowCalcTimeout Duration `json:"show_calc_timeout"`
+	// SeedCacheTTL is how long a reported seed isn't announced again
+	SeedCacheTTL Duration `json:"seed_cache_ttl"`
+	// StatsCacheTTL is how long a fetched player count is reused
+	StatsCacheTTL Duration `json:"stats_cache_ttl"`
+	// PlayerCountInterval is how often the player count is sampled for charts
+	PlayerCountInterval Duration `json:"player_count_interval"`
+	// AlertCooldown is the least time between two seed alerts DMed to a user
+	AlertCooldown Duration `json:"alert_cooldown"`
+
+	// ShutdownTimeout is how long shutting down may take before the bot exits anyway
+	ShutdownTimeout Duration `json:"shutdown_timeout"`
+	// UnregisterCommands removes the slash commands on shutdown
+	UnregisterCommands bool `json:"unregister_commands"`
+	// FinalizeMessages removes the buttons of live messages on shutdown
+	// instead of restoring them on the next start
+	FinalizeMessages bool `json:"finalize_messages"`
+}
+
+// Default returns the configuration used for anything that isn't set
+func Default() Config {
+	return Config{
+		DataDir:               "data",
+		AssetDir:              ".",
+		AnnouncementThreshold: 130,
+		ButtonTimeout:         Duration(5 * time.Minute),
+		ShowCalcTimeout:       Duration(5 * time.Minute),
+		SeedCacheTTL:          Duration(1 * time.Hour),
+		StatsCacheTTL:         Duration(1 * time.Minute),
+		PlayerCountInterval:   Duration(10 * time.Minute),
+		AlertCooldown:         Duration(10 * time.Minute),
+		ShutdownTimeout:       Duration(10 * time.Second),
+	}
+}
+
+// setting is a config field that can be set from the environment and a flag
+type setting struct {
+	flag  string
+	env   string
+	usage string
+	set   func(c *Config, value string) error
+	// boolean flags can be given without a value
+	boolean bool
+}
+
+func stringSetting(flag, env, usage string, field func(c *Config) *string) setting {
+	return setting{flag: flag, env: env, usage: usage, set: func(c *Config, value string) error {
+		*field(c) = value
+		return nil
+	}}
+}
+
+func boolSetting(flag, env, usage string, field func(c *Config) *bool) setting {
+	return setting{flag: flag, env: env, usage: usage, boolean: true, set: func(c *Config, value string) error {
+		parsed, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("expected true or false, got %q", value)
+		}
+		*field(c) = parsed
+		return nil
+	}}
+}
+
+func floatSetting(flag, env, usage string, field func(c *Config) *float64) setting {
+	return setting{flag: flag, env: env, usage: usage, set: func(c *Config, value string) error {
+		parsed, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return fmt.Errorf("expected a number, got %q", value)
+		}
+		*field(c) = parsed
+		return nil
+	}}
+}
+
+func durationSetting(flag, env, usage string, field func(c *Config) *Duration) setting {
+	return setting{flag: flag, env: env, usage: usage, set: func(c *Config, value string) error {
+		parsed, err := time.ParseDuration(value)
+		if err != nil {
+			return fmt.Errorf("expected a duration like 5m, got %q", value)
+		}
+		*field(c) = Duration(parsed)
+		return nil
+	}}
+}
+
+var settings = []setting{
+	stringSetting("token", "BOT_TOKEN", "Discord bot token", func(c *Config) *string { return &c.BotToken }),
+	stringSetting("guild", "GUILD_ID", "home guild ID", func(c *Config) *string { return &c.GuildID }),
+	boolSetting("guild-commands", "GUILD_COMMANDS", "register the commands in the home guild only", func(c *Config) *bool { return &c.GuildCommands }),
+	boolSetting("debug", "DEBUG", "log debug messages", func(c *Config) *bool { return &c.Debug }),
+	stringSetting("http", "HTTP_ADDR", "listen address of the web UI, off when empty", func(c *Config) *string { return &c.HTTPAddr }),
+	stringSetting("data", "DATA_DIR", "directory for persisted data", func(c *Config) *string { return &c.DataDir }),
+	stringSetting("assets", "ASSET_DIR", "directory containing the font and images directories", func(c *Config) *string { return &c.AssetDir }),
+	stringSetting("splits", "SPLITS_FILE", "JSON file replacing the built-in splits", func(c *Config) *string { return &c.SplitsFile }),
+	stringSetting("stats", "STATS_PROVIDER", "player count source: hypixel or fake", func(c *Config) *string { return &c.StatsProvider }),
+	stringSetting("hypixel-key", "HYPIXEL_API_KEY", "Hypixel API key", func(c *Config) *string { return &c.HypixelAPIKey }),
+	floatSetting("threshold", "ANNOUNCEMENT_THRESHOLD", "default announcement threshold in seconds", func(c *Config) *float64 { return &c.AnnouncementThreshold }),
+	durationSetting("button-timeout", "BUTTON_TIMEOUT", "how long result buttons stay", func(c *Config) *Duration { return &c.ButtonTimeout }),
+	durationSetting("showcalc-timeout", "SHOW_CALC_TIMEOUT", "how long the ShowCalc button stays after the others", func(c *Config) *Duration { return &c.ShowCalcTimeout }),
+	durationSetting("seed-cache-ttl", "SEED_CACHE_TTL", "how long a reported seed isn't announced again", func(c *Config) *Duration { return &c.SeedCacheTTL }),
+	durationSetting("stats-cache-ttl", "STATS_CACHE_TTL", "how long a fetched player count is reused", func(c *Config) *Duration { return &c.StatsCacheTTL }),
+	durationSetting("playercount-interval", "PLAYER_COUNT_INTERVAL", "how often the player count is sampled", func(c *Config) *Duration { return &c.PlayerCountInterval }),
+	durationSetting("alert-cooldown", "ALERT_COOLDOWN", "least time between two seed alerts to a user", func(c *Config) *Duration { return &c.AlertCooldown }),
+	durationSetting("shutdown-timeout", "SHUTDOWN_TIMEOUT", "how long shutting down may take", func(c *Config) *Duration { return &c.ShutdownTimeout }),
+	boolSetting("unregister-commands", "UNREGISTER_COMMANDS", "remove the slash commands on shutdown", func(c *Config) *bool { return &c.UnregisterCommands }),
+	boolSetting("finalize-messages", "FINALIZE_MESSAGES", "remove the buttons of live messages on shutdown", func(c *Config) *bool { return &c.FinalizeMessages }),
+}
+
+// Load builds the configuration from the defaults, the config file given by
+// -config or CONFIG_FILE, the environment and .env, and the flags in args
+func Load(args []string) (*Config, error) {
+	flags := flag.NewFlagSet("atlantis_calc", flag.ContinueOnError)
+	configFile := flags.String("config", "", "JSON config file")
+
+	// Flags are applied last, so only remember them while parsing
+	flagValues := make(map[string]string)
+	for _, st := range settings {
+		name := st.flag
+		register := flags.Func
+		if st.boolean {
+			register = flags.BoolFunc
+		}
+		register(name, fmt.Sprintf("%s (env %s)", st.usage, st.env), func(value string) error {
+			flagValues[name] = value
+			return nil
+		})
+	}
+
+	if err := flags.Parse(args); err != nil {
+		return nil, err
+	}
+
+	// .env is optional, the environment may be set some other way
+	if err := godotenv.Load(); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("error reading .env: %w", err)
+	}
+
+	if *configFile == "" {
+		*configFile = os.Getenv("CONFIG_FILE")
+	}
+
+	config := Default()
+	if *configFile != "" {
+		if err := config.readFile(*configFile); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, st := range settings {
+		value, exists := os.LookupEnv(st.env)
+		if !exists {
+			continue
+		}
+		if err := st.set(&config, value); err != nil {
+			return nil, fmt.Errorf("invalid %s: %w", st.env, err)
+		}
+	}
+
+	for _, st := range settings {
+		value, exists := flagValues[st.flag]
+		if !exists {
+			continue
+		}
+		if err := st.set(&config, value); err != nil {
+			return nil, fmt.Errorf("invalid -%s: %w", st.flag, err)
+		}
+	}
+
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+
+	return &config, nil
+}
+
+func (c *Config) readFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("error reading config file: %w", err)
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(c); err != nil {
+		return fmt.Errorf("error parsing config file %s: %w", path, err)
+	}
+
+	return nil
+}
+
+// AssetPath returns the path of a file in the asset directory
+func (c *Config) AssetPath(name string) string {
+	return filepath.Join(c.AssetDir, name)
+}
+
+// Validate reports every setting that can't work
+func (c *Config) Validate() error {
+	var errs []error
+
+	if c.BotToken == "" {
+		errs = append(errs, fmt.Errorf("the bot token is not set"))
+	}
+
+	if c.GuildCommands && c.GuildID == "" {
+		errs = append(errs, fmt.Errorf("guild commands need the guild ID"))
+	}
+
+	switch c.StatsProvider {
+	case "", "hypixel", "fake":
+	default:
+		errs = append(errs, fmt.Errorf("unknown stats provider %q", c.StatsProvider))
+	}
+
+	if c.DataDir == "" {
+		errs = append(errs, fmt.Errorf("the data directory is not set"))
+	}
+
+	for _, asset := range []string{"font/minecraft_font.ttf", "images/background.png"} {
+		if _, err := os.Stat(c.AssetPath(asset)); err != nil {
+			errs = append(errs, fmt.Errorf("asset directory %s is missing %s", c.AssetDir, asset))
+		}
+	}
+
+	if c.SplitsFile != "" {
+		if _, err := os.Stat(c.SplitsFile); err != nil {
+			errs = append(errs, fmt.Errorf("splits file: %w", err))
+		}
+	}
+
+	if !(c.AnnouncementThreshold > 0) || math.IsInf(c.AnnouncementThreshold, 0) {
+		errs = append(errs, fmt.Errorf("the announcement threshold must be a positive number"))
+	}
+
+	durations := []struct {
+		name  string
+		value Duration
+	}{
+		{"button timeout", c.ButtonTimeout},
+		{"ShowCalc timeout", c.ShowCalcTimeout},
+		{"seed cache TTL", c.SeedCacheTTL},
+		{"stats cache TTL", c.StatsCacheTTL},
+		{"player count interval", c.PlayerCountInterval},
+		{"alert cooldown", c.AlertCooldown},
+		{"shutdown timeout", c.ShutdownTimeout},
+	}
+	for _, duration := range durations {
+		if duration.value <= 0 {
+			errs = append(errs, fmt.Errorf("the %s must be positive", duration.name))
+		}
+	}
+
+	return errors.Join(errs...)
+}
@@ -0,0 +1,242 @@
+package config
+
+import (
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// assetDir creates the assets Validate looks for
+func assetDir(t *testing.T) string {
+	t.Helper()
+
+	dir := t.TempDir()
+	for _, asset := range []string{"font/minecraft_font.ttf", "images/background.png"} {
+		path := filepath.Join(dir, asset)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+// clearEnv unsets every setting for the test, so the environment running it
+// can't leak in
+func clearEnv(t *testing.T) {
+	t.Helper()
+
+	for _, env := range append([]string{"CONFIG_FILE"}, settingEnvs()...) {
+		value, exists := os.LookupEnv(env)
+		if !exists {
+			continue
+		}
+		t.Setenv(env, value)
+		os.Unsetenv(env)
+	}
+}
+
+func settingEnvs() []string {
+	var envs []string
+	for _, st := range settings {
+		envs = append(envs, st.env)
+	}
+	return envs
+}
+
+func TestLoadPrecedence(t *testing.T) {
+	assets := assetDir(t)
+
+	tests := []struct {
+		name      string
+		file      string
+		env       map[string]string
+		args      []string
+		threshold float64
+		timeout   time.Duration
+		debug     bool
+	}{
+		{
+			name:      "defaults",
+			threshold: 130,
+			timeout:   5 * time.Minute,
+		},
+		{
+			name:      "file over default",
+			file:      `{"announcement_threshold": 100, "button_timeout": "1m", "debug": true}`,
+			threshold: 100,
+			timeout:   time.Minute,
+			debug:     true,
+		},
+		{
+			name:      "env over file",
+			file:      `{"announcement_threshold": 100, "button_timeout": "1m", "debug": true}`,
+			env:       map[string]string{"ANNOUNCEMENT_THRESHOLD": "110", "BUTTON_TIMEOUT": "2m", "DEBUG": "false"},
+			threshold: 110,
+			timeout:   2 * time.Minute,
+		},
+		{
+			name:      "flag over env",
+			file:      `{"announcement_threshold": 100, "button_timeout": "1m"}`,
+			env:       map[string]string{"ANNOUNCEMENT_THRESHOLD": "110", "BUTTON_TIMEOUT": "2m", "DEBUG": "false"},
+			args:      []string{"-threshold", "120", "-button-timeout", "3m", "-debug"},
+			threshold: 120,
+			timeout:   3 * time.Minute,
+			debug:     true,
+		},
+		{
+			name:      "flag over file",
+			file:      `{"announcement_threshold": 100}`,
+			args:      []string{"-threshold", "120"},
+			threshold: 120,
+			timeout:   5 * time.Minute,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			clearEnv(t)
+			for env, value := range test.env {
+				t.Setenv(env, value)
+			}
+
+			args := []string{"-token", "token", "-assets", assets}
+			if test.file != "" {
+				path := filepath.Join(t.TempDir(), "config.json")
+				if err := os.WriteFile(path, []byte(test.file), 0644); err != nil {
+					t.Fatal(err)
+				}
+				args = append(args, "-config", path)
+			}
+
+			config, err := Load(append(args, test.args...))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if config.AnnouncementThreshold != test.threshold {
+				t.Errorf("got threshold %v, want %v", config.AnnouncementThreshold, test.threshold)
+			}
+			if time.Duration(config.ButtonTimeout) != test.timeout {
+				t.Errorf("got button timeout %v, want %v", time.Duration(config.ButtonTimeout), test.timeout)
+			}
+			if config.Debug != test.debug {
+				t.Errorf("got debug %v, want %v", config.Debug, test.debug)
+			}
+		})
+	}
+}
+
+func TestLoadConfigFileFromEnv(t *testing.T) {
+	clearEnv(t)
+	path := filepath.Join(t.TempDir(), "config.json")
+	if err := os.WriteFile(path, []byte(`{"bot_token": "token", "guild_id": "guild"}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("CONFIG_FILE", path)
+
+	config, err := Load([]string{"-assets", assetDir(t)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if config.BotToken != "token" || config.GuildID != "guild" {
+		t.Errorf("the config file wasn't read: %+v", config)
+	}
+}
+
+func TestLoadRejectsBadValues(t *testing.T) {
+	tests := []struct {
+		name string
+		file string
+		env  map[string]string
+		args []string
+		err  string
+	}{
+		{name: "unknown file field", file: `{"bot_tokn": "token"}`, err: "unknown field"},
+		{name: "bad file duration", file: `{"button_timeout": "soon"}`, err: "error parsing config file"},
+		{name: "bad env number", env: map[string]string{"ANNOUNCEMENT_THRESHOLD": "low"}, err: "invalid ANNOUNCEMENT_THRESHOLD"},
+		{name: "bad env bool", env: map[string]string{"DEBUG": "maybe"}, err: "invalid DEBUG"},
+		{name: "bad flag duration", args: []string{"-alert-cooldown", "10"}, err: "invalid -alert-cooldown"},
+		{name: "unknown flag", args: []string{"-nope"}, err: "not defined"},
+		{name: "invalid result", args: []string{"-threshold", "0"}, err: "threshold must be a positive number"},
+	}
+
+	assets := assetDir(t)
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			clearEnv(t)
+			for env, value := range test.env {
+				t.Setenv(env, value)
+			}
+
+			args := []string{"-token", "token", "-assets", assets}
+			if test.file != "" {
+				path := filepath.Join(t.TempDir(), "config.json")
+				if err := os.WriteFile(path, []byte(test.file), 0644); err != nil {
+					t.Fatal(err)
+				}
+				args = append(args, "-config", path)
+			}
+
+			_, err := Load(append(args, test.args...))
+			if err == nil || !strings.Contains(err.Error(), test.err) {
+				t.Errorf("got error %v, want it to contain %q", err, test.err)
+			}
+		})
+	}
+}
+
+func TestValidate(t *testing.T) {
+	assets := assetDir(t)
+	valid := func() Config {
+		config := Default()
+		config.BotToken = "token"
+		config.AssetDir = assets
+		return config
+	}
+
+	if config := valid(); config.Validate() != nil {
+		t.Fatalf("the valid config was rejected: %v", config.Validate())
+	}
+
+	tests := []struct {
+		name   string
+		change func(c *Config)
+		err    string
+	}{
+		{"no token", func(c *Config) { c.BotToken = "" }, "bot token is not set"},
+		{"guild commands without guild", func(c *Config) { c.GuildCommands = true }, "need the guild ID"},
+		{"unknown stats provider", func(c *Config) { c.StatsProvider = "mojang" }, "unknown stats provider"},
+		{"no data dir", func(c *Config) { c.DataDir = "" }, "data directory is not set"},
+		{"missing assets", func(c *Config) { c.AssetDir = t.TempDir() }, "is missing font/minecraft_font.ttf"},
+		{"missing splits file", func(c *Config) { c.SplitsFile = filepath.Join(assets, "splits.json") }, "splits file"},
+		{"zero threshold", func(c *Config) { c.AnnouncementThreshold = 0 }, "threshold must be a positive number"},
+		{"negative threshold", func(c *Config) { c.AnnouncementThreshold = -5 }, "threshold must be a positive number"},
+		{"NaN threshold", func(c *Config) { c.AnnouncementThreshold = math.NaN() }, "threshold must be a positive number"},
+		{"infinite threshold", func(c *Config) { c.AnnouncementThreshold = math.Inf(1) }, "threshold must be a positive number"},
+		{"zero button timeout", func(c *Config) { c.ButtonTimeout = 0 }, "button timeout must be positive"},
+		{"negative shutdown timeout", func(c *Config) { c.ShutdownTimeout = Duration(-time.Second) }, "shutdown timeout must be positive"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			config := valid()
+			test.change(&config)
+			if err := config.Validate(); err == nil || !strings.Contains(err.Error(), test.err) {
+				t.Errorf("got error %v, want it to contain %q", err, test.err)
+			}
+		})
+	}
+
+	// Every problem is reported at once
+	config := valid()
+	config.BotToken = ""
+	config.DataDir = ""
+	if err := config.Validate(); err == nil || !strings.Contains(err.Error(), "bot token") || !strings.Contains(err.Error(), "data directory") {
+		t.Errorf("expected both problems, got %v", err)
+	}
+}
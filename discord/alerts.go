@@ -0,0 +1,331 @@
+package discord
+
+import (
+	"bytes"
+	"fmt"
+	"maps"
+	"math"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"atlantis_calc/calc"
+
+	"github.com/bwmarrin/discordgo"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	alertsFile = "alerts.json"
+	// alertsComponentPrefix starts the custom ID of the unsubscribe button
+	alertsComponentPrefix = "alerts"
+)
+
+// alertCooldown is the least time between two alerts to a user
+var alertCooldown = 10 * time.Minute
+
+// AlertSubscription is a user's wish to get DMed seeds
+type AlertSubscription struct {
+	// Threshold is the boost time a seed has to beat
+	Threshold float64 `json:"threshold"`
+	// Rooms must all be in the seed
+	Rooms []string `json:"rooms,omitempty"`
+	// LastSent is when the user got their last alert
+	LastSent time.Time `json:"last_sent,omitempty"`
+}
+
+// Matches reports whether a seed is one the subscriber wants
+func (a AlertSubscription) Matches(rooms []string, result calc.CalcSeedResult) bool {
+	// Written so a NaN threshold matches nothing
+	if !(result.BoostTime < a.Threshold) {
+		return false
+	}
+	for _, room := range a.Rooms {
+		if !slices.Contains(rooms, room) {
+			return false
+		}
+	}
+	return true
+}
+
+// AlertStore keeps the alert subscriptions of every user on disk
+type AlertStore struct {
+	mutex         sync.RWMutex
+	subscriptions map[string]AlertSubscription
+}
+
+// NewAlertStore loads the stored subscriptions
+func NewAlertStore() (*AlertStore, error) {
+	store := &AlertStore{subscriptions: make(map[string]AlertSubscription)}
+	if err := loadJSON(alertsFile, &store.subscriptions); err != nil {
+		return nil, err
+	}
+	return store, nil
+}
+
+// Get returns the subscription of a user, ok is false if they have none
+func (as *AlertStore) Get(userID string) (AlertSubscription, bool) {
+	as.mutex.RLock()
+	defer as.mutex.RUnlock()
+
+	subscription, exists := as.subscriptions[userID]
+	return subscription, exists
+}
+
+// Set subscribes a user or replaces their subscription and persists it. The
+// subscription is only kept if it is saved.
+func (as *AlertStore) Set(userID string, subscription AlertSubscription) error {
+	if math.IsNaN(subscription.Threshold) || math.IsInf(subscription.Threshold, 0) {
+		return fmt.Errorf("threshold %v is not a time", subscription.Threshold)
+	}
+
+	as.mutex.Lock()
+	defer as.mutex.Unlock()
+
+	updated := maps.Clone(as.subscriptions)
+	updated[userID] = subscription
+	if err := saveJSON(alertsFile, updated); err != nil {
+		return err
+	}
+	as.subscriptions = updated
+	return nil
+}
+
+// Delete unsubscribes a user
+func (as *AlertStore) Delete(userID string) error {
+	as.mutex.Lock()
+	defer as.mutex.Unlock()
+
+	if _, exists := as.subscriptions[userID]; !exists {
+		return nil
+	}
+	updated := maps.Clone(as.subscriptions)
+	delete(updated, userID)
+	if err := saveJSON(alertsFile, updated); err != nil {
+		return err
+	}
+	as.subscriptions = updated
+	return nil
+}
+
+// Due returns the users whose subscription matches a seed and who weren't
+// alerted within the cooldown
+func (as *AlertStore) Due(rooms []string, result calc.CalcSeedResult, now time.Time) []string {
+	as.mutex.RLock()
+	defer as.mutex.RUnlock()
+
+	var userIDs []string
+	for userID, subscription := range as.subscriptions {
+		if subscription.Matches(rooms, result) && now.Sub(subscription.LastSent) >= alertCooldown {
+			userIDs = append(userIDs, userID)
+		}
+	}
+	slices.Sort(userIDs)
+	return userIDs
+}
+
+// MarkSent starts the cooldown of the users who got an alert at now
+func (as *AlertStore) MarkSent(userIDs []string, now time.Time) error {
+	as.mutex.Lock()
+	defer as.mutex.Unlock()
+
+	updated := maps.Clone(as.subscriptions)
+	for _, userID := range userIDs {
+		// The user may have unsubscribed while the alert was sent
+		if subscription, exists := updated[userID]; exists {
+			subscription.LastSent = now
+			updated[userID] = subscription
+		}
+	}
+	if err := saveJSON(alertsFile, updated); err != nil {
+		return err
+	}
+	as.subscriptions = updated
+	return nil
+}
+
+var alerts *AlertStore
+
+var alertsCommand = &discordgo.ApplicationCommand{
+	Name:        "alerts",
+	Description: "Get a DM when someone finds a seed you like",
+	Options: []*discordgo.ApplicationCommandOption{
+		{
+			Type:        discordgo.ApplicationCommandOptionString,
+			Name:        "threshold",
+			Description: "Boost time the seed has to beat like 2:05",
+			Required:    true,
+		},
+		{
+			Type:        discordgo.ApplicationCommandOptionString,
+			Name:        "rooms_must_include",
+			Description: "Rooms the seed needs, separated by commas",
+		},
+	},
+}
+
+func alertsHandler(s Session, i *discordgo.InteractionCreate) {
+	logUserInteraction(i, "command", "alerts")
+
+	respond := func(content string, components []discordgo.MessageComponent) {
+		err := s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+			Type: discordgo.InteractionResponseChannelMessageWithSource,
+			Data: &discordgo.InteractionResponseData{
+				Content:    content,
+				Components: components,
+				Flags:      discordgo.MessageFlagsEphemeral,
+			},
+		})
+		if err != nil {
+			log.Errorf("Failed to respond to alerts command: %v", err)
+		}
+	}
+
+	var subscription AlertSubscription
+	for _, opt := range i.ApplicationCommandData().Options {
+		switch opt.Name {
+		case "threshold":
+			threshold, err := ParseTime(opt.StringValue())
+			if err != nil {
+				respond(err.Error(), nil)
+				return
+			}
+			if threshold <= 0 {
+				respond("Your threshold has to be more than 0 seconds.", nil)
+				return
+			}
+			subscription.Threshold = threshold
+		case "rooms_must_include":
+			rooms, err := parseAlertRooms(opt.StringValue())
+			if err != nil {
+				respond(err.Error(), nil)
+				return
+			}
+			subscription.Rooms = rooms
+		}
+	}
+
+	userID := interactionUserID(i)
+	if previous, exists := alerts.Get(userID); exists {
+		subscription.LastSent = previous.LastSent
+	}
+	if err := alerts.Set(userID, subscription); err != nil {
+		log.Errorf("Failed to save alerts of user %s: %v", userID, err)
+		respond("I couldn't save your alerts, go tell the developer.", nil)
+		return
+	}
+
+	content := fmt.Sprintf("I'll DM you seeds under %s", FormatTime(subscription.Threshold))
+	if len(subscription.Rooms) > 0 {
+		content += fmt.Sprintf(" with %s", strings.Join(subscription.Rooms, ", "))
+	}
+	content += ". Make sure you accept DMs from server members."
+	respond(content, alertComponents())
+}
+
+// parseAlertRooms reads a list of rooms separated by commas
+func parseAlertRooms(text string) ([]string, error) {
+	var rooms []string
+	for _, room := range strings.Split(text, ",") {
+		room = strings.ToLower(strings.TrimSpace(room))
+		if room == "" {
+			continue
+		}
+		if _, exists := calc.RoomMap[room]; !exists || room == "finish room" {
+			return nil, fmt.Errorf("I don't know a room called \"%s\".", room)
+		}
+		if !slices.Contains(rooms, room) {
+			rooms = append(rooms, room)
+		}
+	}
+	return rooms, nil
+}
+
+func alertComponents() []discordgo.MessageComponent {
+	return []discordgo.MessageComponent{
+		discordgo.ActionsRow{
+			Components: []discordgo.MessageComponent{
+				discordgo.Button{
+					Label:    "Unsubscribe",
+					CustomID: alertsComponentPrefix + "|unsubscribe",
+					Style:    discordgo.DangerButton,
+					Emoji: &discordgo.ComponentEmoji{
+						Name: "🔕",
+					},
+				},
+			},
+		},
+	}
+}
+
+// alertsButtonHandler unsubscribes the user who clicked
+func alertsButtonHandler(s Session, i *discordgo.InteractionCreate) {
+	logUserInteraction(i, "button click", i.MessageComponentData().CustomID)
+
+	content := "Done, no more seed alerts for you."
+	userID := interactionUserID(i)
+	if err := alerts.Delete(userID); err != nil {
+		log.Errorf("Failed to unsubscribe user %s: %v", userID, err)
+		content = "I couldn't unsubscribe you, go tell the developer."
+	}
+
+	err := s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{
+			Content: content,
+			Flags:   discordgo.MessageFlagsEphemeral,
+		},
+	})
+	if err != nil {
+		log.Errorf("Failed to respond to unsubscribe: %v", err)
+	}
+}
+
+// sendSeedAlerts DMs a found seed to every subscriber who wants it. A user
+// whose DMs fail is logged and doesn't stop the others.
+func sendSeedAlerts(rooms []string, result calc.CalcSeedResult, content string) {
+	now := time.Now()
+	userIDs := alerts.Due(rooms, result, now)
+	if len(userIDs) == 0 {
+		return
+	}
+
+	img, err := drawCalcResults(rooms, []calc.CalcSeedResult{result})
+	if err != nil {
+		log.Errorf("Error drawing seed results: %v", err)
+		return
+	}
+
+	sent := make([]string, 0, len(userIDs))
+	for _, userID := range userIDs {
+		channel, err := s.UserChannelCreate(userID)
+		if err != nil {
+			log.Warnf("Failed to open DM with user %s: %v", userID, err)
+			continue
+		}
+
+		_, err = s.ChannelMessageSendComplex(channel.ID, &discordgo.MessageSend{
+			Content:    content,
+			Components: alertComponents(),
+			Files: []*discordgo.File{
+				{
+					Name:   "seed.png",
+					Reader: bytes.NewReader(img.Bytes()),
+				},
+			},
+		})
+		if err != nil {
+			log.Warnf("Failed to DM seed alert to user %s: %v", userID, err)
+			continue
+		}
+		sent = append(sent, userID)
+	}
+
+	if len(sent) == 0 {
+		return
+	}
+	if err := alerts.MarkSent(sent, now); err != nil {
+		log.Errorf("Failed to save alert times: %v", err)
+	}
+}
@@ -0,0 +1,160 @@
+package discord
+
+import (
+	"math"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"atlantis_calc/calc"
+)
+
+// useDataDir points DataDir at a temporary directory for the test
+func useDataDir(t *testing.T) string {
+	t.Helper()
+
+	dir, previous := t.TempDir(), DataDir
+	DataDir = dir
+	t.Cleanup(func() { DataDir = previous })
+	return dir
+}
+
+// breakDataDir makes every save fail until the test ends
+func breakDataDir(t *testing.T) {
+	t.Helper()
+
+	file := filepath.Join(t.TempDir(), "file")
+	if err := os.WriteFile(file, nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	previous := DataDir
+	DataDir = filepath.Join(file, "data")
+	t.Cleanup(func() { DataDir = previous })
+}
+
+func TestAlertMatches(t *testing.T) {
+	rooms := []string{"1a", "2b", "3c", "4e", "5a", "1c", "2f", "3g"}
+	result := calc.CalcSeedResult{BoostTime: 120}
+
+	tests := []struct {
+		name         string
+		subscription AlertSubscription
+		matches      bool
+	}{
+		{"faster than the threshold", AlertSubscription{Threshold: 125}, true},
+		{"as fast as the threshold", AlertSubscription{Threshold: 120}, false},
+		{"slower than the threshold", AlertSubscription{Threshold: 110}, false},
+		{"rooms in the seed", AlertSubscription{Threshold: 125, Rooms: []string{"2b", "3g"}}, true},
+		{"room missing", AlertSubscription{Threshold: 125, Rooms: []string{"2b", "5e"}}, false},
+		{"NaN threshold", AlertSubscription{Threshold: math.NaN()}, false},
+	}
+
+	for _, test := range tests {
+		if got := test.subscription.Matches(rooms, result); got != test.matches {
+			t.Errorf("%s: got %v, want %v", test.name, got, test.matches)
+		}
+	}
+}
+
+func TestAlertsDueCooldown(t *testing.T) {
+	useDataDir(t)
+	store, err := NewAlertStore()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rooms := []string{"1a"}
+	result := calc.CalcSeedResult{BoostTime: 120}
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	for _, userID := range []string{"a", "b", "slow"} {
+		threshold := 125.0
+		if userID == "slow" {
+			threshold = 100
+		}
+		if err := store.Set(userID, AlertSubscription{Threshold: threshold}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if due := store.Due(rooms, result, now); !slices.Equal(due, []string{"a", "b"}) {
+		t.Fatalf("got %v due", due)
+	}
+	// Only a got the DM, b is still due
+	if err := store.MarkSent([]string{"a"}, now); err != nil {
+		t.Fatal(err)
+	}
+	if due := store.Due(rooms, result, now.Add(time.Minute)); !slices.Equal(due, []string{"b"}) {
+		t.Errorf("got %v due within the cooldown", due)
+	}
+	if due := store.Due(rooms, result, now.Add(alertCooldown)); !slices.Equal(due, []string{"a", "b"}) {
+		t.Errorf("got %v due after the cooldown", due)
+	}
+
+	reloaded, err := NewAlertStore()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if subscription, _ := reloaded.Get("a"); !subscription.LastSent.Equal(now) {
+		t.Errorf("the alert time wasn't saved, got %v", subscription.LastSent)
+	}
+}
+
+func TestAlertsSetKeepsFailedSaveOut(t *testing.T) {
+	useDataDir(t)
+	store, err := NewAlertStore()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Set("a", AlertSubscription{Threshold: 125}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := store.Set("b", AlertSubscription{Threshold: math.NaN()}); err == nil {
+		t.Error("a NaN threshold was accepted")
+	}
+	if err := store.Set("b", AlertSubscription{Threshold: math.Inf(1)}); err == nil {
+		t.Error("an infinite threshold was accepted")
+	}
+
+	breakDataDir(t)
+	if err := store.Set("c", AlertSubscription{Threshold: 125}); err == nil {
+		t.Fatal("expected the save to fail")
+	}
+	if err := store.Delete("a"); err == nil {
+		t.Fatal("expected the save to fail")
+	}
+
+	for userID, want := range map[string]bool{"a": true, "b": false, "c": false} {
+		if _, exists := store.Get(userID); exists != want {
+			t.Errorf("user %s subscribed: %v, want %v", userID, exists, want)
+		}
+	}
+}
+
+func TestParseAlertRooms(t *testing.T) {
+	tests := []struct {
+		text  string
+		rooms []string
+		valid bool
+	}{
+		{"", nil, true},
+		{"1a", []string{"1a"}, true},
+		{" 1A , 2b,,", []string{"1a", "2b"}, true},
+		{"1a, 1a", []string{"1a"}, true},
+		{"1a, 9z", nil, false},
+		{"finish room", nil, false},
+	}
+
+	for _, test := range tests {
+		rooms, err := parseAlertRooms(test.text)
+		if test.valid != (err == nil) {
+			t.Errorf("%q: got error %v", test.text, err)
+			continue
+		}
+		if !slices.Equal(rooms, test.rooms) {
+			t.Errorf("%q: got %v, want %v", test.text, rooms, test.rooms)
+		}
+	}
+}
@@ -1,6 +1,7 @@
 package discord
 
 import (
+	"context"
 	"regexp"
 	"strconv"
 	"bytes"
@@ -12,102 +13,301 @@ import (
 	"slices"
 	"sort"
 	"strings"
+	"syscall"
 	"time"
 
 	"atlantis_calc/calc"
+	"atlantis_calc/config"
 
 	"github.com/bwmarrin/discordgo"
-	"github.com/joho/godotenv"
 	log "github.com/sirupsen/logrus"
 )
 
-func StartDiscordBot() error {
-	slices.Sort(roomOptions)
+// StartDiscordBot connects to Discord with the given configuration and serves
+// the bot until interrupted
+func StartDiscordBot(c *config.Config) error {
+	if c.Debug {
+		log.SetLevel(log.DebugLevel)
+	}
+
+	session, err := NewDiscordSession(c.BotToken)
+	if err != nil {
+		return fmt.Errorf("invalid bot token, couldn't initiate a session: %w", err)
+	}
+
+	if err := Setup(session, c); err != nil {
+		return err
+	}
 
 	log.SetReportCaller(true)
-	s.AddHandler(func(s *discordgo.Session, r *discordgo.Ready) {
+	session.AddHandler(func(s *discordgo.Session, r *discordgo.Ready) {
 		log.Infof("Logged in as %v#%v", s.State.User.Username, s.State.User.Discriminator)
 	})
 
-	s.AddHandler(func(s *discordgo.Session, i *discordgo.InteractionCreate) {
-		switch i.Type {
-		case discordgo.InteractionApplicationCommand:
-			if h, ok := commandHandlers[i.ApplicationCommandData().Name]; ok {
-				h(s, i)
-			}
-		case discordgo.InteractionApplicationCommandAutocomplete:
-			autocompleteHandler(s, i)
-		case discordgo.InteractionMessageComponent:
-			buttonHandler(s, i)
-		}
+	session.AddHandler(func(_ *discordgo.Session, i *discordgo.InteractionCreate) {
+		HandleInteraction(session, i)
 	})
 
-	err := s.Open()
+	err = session.Open()
 	if err != nil {
 		log.Errorf("Cannot open the session: %v", err)
 		return err
 	}
+	defer session.Close()
+
+	logBotPermissions(session.Session, cfg.GuildID)
+
+	if err := migrateMessageStates(); err != nil {
+		log.Errorf("Cannot migrate message states: %v", err)
+	}
+	if err := messageStore.Load(); err != nil {
+		log.Errorf("Cannot restore message states: %v", err)
+	}
 
-	logBotPermissions()
+	background, stopBackground := context.WithCancel(context.Background())
+	defer stopBackground()
 
-	log.Info("Adding commands...")
-	registeredCommands := make([]*discordgo.ApplicationCommand, len(commands))
-	for i, v := range commands {
-		cmd, err := s.ApplicationCommandCreate(s.State.User.ID, "", v)
+	if playerCountStats != nil {
+		playerCountHistory, err = NewPlayerCountHistory(playerCountRetention)
 		if err != nil {
-			log.Errorf("Cannot create '%v' command: %v", v.Name, err)
-			return err
+			log.Errorf("Cannot load player count history: %v", err)
+		} else {
+			go samplePlayerCounts(background, playerCountStats, playerCountHistory, playerCountSampleInterval)
 		}
-		registeredCommands[i] = cmd
 	}
 
-	defer s.Close()
+	go runDailyChallenges(background)
+
+	var web *http.Server
+	if cfg.HTTPAddr != "" {
+		web = serveWebUI(cfg.HTTPAddr)
+	}
+
+	log.Info("Registering commands...")
+	commandGuildID := ""
+	if cfg.GuildCommands {
+		commandGuildID = cfg.GuildID
+	}
+	registeredCommands, err := registerCommands(session, session.State.User.ID, commandGuildID, commands)
+	if err != nil {
+		log.Errorf("Cannot register commands: %v", err)
+		return err
+	}
+
+	// The scope we don't register in may still hold the commands from before
+	// guild commands were switched, which would show every command twice
+	if cfg.GuildCommands {
+		err = clearCommands(session, session.State.User.ID, "")
+	} else if cfg.GuildID != "" {
+		err = clearCommands(session, session.State.User.ID, cfg.GuildID)
+	}
+	if err != nil {
+		log.Errorf("Cannot remove unused commands: %v", err)
+	}
+
 	stop := make(chan os.Signal, 1)
-	signal.Notify(stop, os.Interrupt)
+	signal.Notify(stop, os.Interrupt, syscall.SIGTERM)
 	log.Info("Press Ctrl+C to exit")
 	<-stop
 
 	log.Info("Shutting down...")
 
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(cfg.ShutdownTimeout))
+	defer cancel()
+	if err := shutdown(ctx, session, stopBackground, web, registeredCommands); err != nil {
+		log.Errorf("Unclean shutdown: %v", err)
+	}
+
 	return nil
 }
 
-var (
-	BotToken = ""
-	GuildID  = ""
-)
-
-var s *discordgo.Session
+// HandleInteraction dispatches an interaction to the handler of its command or component
+func HandleInteraction(s Session, i *discordgo.InteractionCreate) {
+	if !beginInteraction() {
+		respondShuttingDown(s, i)
+		return
+	}
+	defer endInteraction()
 
-func init() {
-	if err := godotenv.Load(); err != nil {
-		log.Fatalf("failed to open .env")
+	switch i.Type {
+	case discordgo.InteractionApplicationCommand:
+		if h, ok := commandHandlers[i.ApplicationCommandData().Name]; ok {
+			h(s, i)
+		}
+	case discordgo.InteractionApplicationCommandAutocomplete:
+		autocompleteHandler(s, i)
+	case discordgo.InteractionMessageComponent:
+		prefix, _, _ := strings.Cut(i.MessageComponentData().CustomID, "|")
+		if h, ok := componentHandlers[prefix]; ok {
+			h(s, i)
+			return
+		}
+		buttonHandler(s, i)
 	}
+}
 
-	BotToken = os.Getenv("BOT_TOKEN")
-	GuildID = os.Getenv("GUILD_ID")
+// componentHandlers handle the buttons of commands other than /calc, by the
+// part of the custom ID before the first "|"
+var componentHandlers = map[string]func(s Session, i *discordgo.InteractionCreate){
+	seedsComponentPrefix:  seedsButtonHandler,
+	alertsComponentPrefix: alertsButtonHandler,
+	quizComponentPrefix:   quizComponentHandler,
+}
 
-	if os.Getenv("DEBUG") == "true" {
-		log.SetLevel(log.DebugLevel)
+var s Session
+
+// cfg is the configuration passed to Setup
+var cfg *config.Config
+
+// Setup prepares the handlers to run against session with the given
+// configuration, loading the splits and the stored guild configs.
+// StartDiscordBot calls it with a real session; call it with a FakeSession to
+// run handlers offline.
+func Setup(session Session, c *config.Config) error {
+	s = session
+	cfg = c
+
+	DataDir = c.DataDir
+	AssetDir = c.AssetDir
+	buttonDuration = time.Duration(c.ButtonTimeout)
+	longButtonDuration = time.Duration(c.ShowCalcTimeout)
+	playerCountSampleInterval = time.Duration(c.PlayerCountInterval)
+	alertCooldown = time.Duration(c.AlertCooldown)
+	defaultAnnouncementThreshold = c.AnnouncementThreshold
+	seedCache = NewSeedCache(time.Duration(c.SeedCacheTTL))
+
+	if c.SplitsFile != "" {
+		splits, err := calc.LoadSplits(c.SplitsFile)
+		if err != nil {
+			return err
+		}
+		calc.RoomMap = splits
+		log.Infof("Using splits from %s", c.SplitsFile)
 	}
+	roomOptions = calc.GetRooms()
+	slices.Sort(roomOptions)
 
 	var err error
-	s, err = discordgo.New("Bot " + BotToken)
+	stratAnalysis, err = calc.AnalyzeQualities(calc.RoomMap)
+	if err != nil {
+		return fmt.Errorf("error analyzing strat qualities: %w", err)
+	}
+	names := make([]string, 0, len(stratAnalysis))
+	for name := range stratAnalysis {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	disagreements := 0
+	for _, name := range names {
+		for k, strat := range calc.RoomMap[name].BoostStrats {
+			analysis := stratAnalysis[name][k]
+			if strat.Quality != analysis.Quality {
+				disagreements++
+				log.Infof("Strat %q of room %s is labeled %v but computes as %v (best in %.0f%% of seeds, %.1fs margin)",
+					strat.Name, name, strat.Quality, analysis.Quality, analysis.OptimalRate()*100, analysis.Margin)
+			}
+		}
+	}
+	if disagreements > 0 {
+		log.Warnf("%d strats are labeled differently than the calc computes, the computed labels are used", disagreements)
+	}
+
+	messageStore = NewMessageStateStore(messageStatesFile, expireMessage)
+
+	guildConfigs, err = NewGuildConfigStore()
+	if err != nil {
+		return fmt.Errorf("error loading guild configs: %w", err)
+	}
+
+	personalSplits, err = NewPersonalSplitsStore()
+	if err != nil {
+		return fmt.Errorf("error loading personal splits: %w", err)
+	}
+
+	seedHistory, err = NewSeedHistory()
+	if err != nil {
+		return fmt.Errorf("error loading seed history: %w", err)
+	}
+
+	alerts, err = NewAlertStore()
 	if err != nil {
-		log.Fatalf("Invalid bot token, couldn't initiate a session: %v", err)
+		return fmt.Errorf("error loading alerts: %w", err)
 	}
+
+	tournaments, err = NewTournamentStore()
+	if err != nil {
+		return fmt.Errorf("error loading tournaments: %w", err)
+	}
+
+	dailies, err = NewDailyStore()
+	if err != nil {
+		return fmt.Errorf("error loading daily seeds: %w", err)
+	}
+
+	quizStats, err = NewQuizStatsStore()
+	if err != nil {
+		return fmt.Errorf("error loading quiz stats: %w", err)
+	}
+
+	// The home guild always had announcements, keep them on unless it opts out
+	if c.GuildID != "" {
+		if err := guildConfigs.SetFeatureDefault(c.GuildID, FeatureAnnouncements, true); err != nil {
+			log.Errorf("Cannot enable announcements for guild %s: %v", c.GuildID, err)
+		}
+	}
+	// Announcements used to be on everywhere, tell whoever upgrades
+	if !guildConfigs.AnyEnabled(FeatureAnnouncements) {
+		log.Warn("No guild has seed announcements on, they are opt-in now. " +
+			"Turn them on with /config feature announcements in each guild, or set GUILD_ID for the home guild.")
+	}
+
+	playerCountStats = nil
+	provider, err := newStatsProvider(c.StatsProvider, c.HypixelAPIKey)
+	if err != nil {
+		log.Warnf("Player counts are disabled: %v", err)
+	} else {
+		playerCountStats = NewCachedStatsProvider(provider, time.Duration(c.StatsCacheTTL))
+	}
+
+	return nil
 }
 
 var commands = []*discordgo.ApplicationCommand{
 	{
 		Name:        "calc",
 		Description: "Choose 8 rooms",
-		Options:     generateOptions(),
+		Options: append(generateOptions(), &discordgo.ApplicationCommandOption{
+			Type:        discordgo.ApplicationCommandOptionBoolean,
+			Name:        "use_mine",
+			Description: "Calc with the splits you saved with /mysplits",
+		}),
 	},
 	{
 		Name:        "playercount",
 		Description: "Find out the current player count in PKD!",
+		Options: []*discordgo.ApplicationCommandOption{
+			{
+				Type:        discordgo.ApplicationCommandOptionString,
+				Name:        "period",
+				Description: "Show a chart of the player count over this period",
+				Choices: []*discordgo.ApplicationCommandOptionChoice{
+					{Name: "24h", Value: "24h"},
+					{Name: "7d", Value: "7d"},
+				},
+			},
+		},
 	},
+	configCommand,
+	mysplitsCommand,
+	duelCommand,
+	seedsCommand,
+	leaderboardCommand,
+	alertsCommand,
+	tournamentCommand,
+	dailyCommand,
+	quizCommand,
+	checkrouteCommand,
+	reviewCommand,
 	{
 		Name:        "allsplits",
 		Description: "Check splits that are used in the calc",
@@ -127,54 +327,25 @@ var commands = []*discordgo.ApplicationCommand{
 	},
 }
 
-func tournamentHandler(s *discordgo.Session, i *discordgo.InteractionCreate) {
-	if i.Type != discordgo.InteractionApplicationCommand {
-		err := fmt.Errorf("expected interaction type to be InteractionApplicationCommand, but found %v", i.Type)
-		log.Warn(err)
-
-		s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
-			Type: discordgo.InteractionResponseChannelMessageWithSource,
-			Data: &discordgo.InteractionResponseData{
-				Content: "I'm sorry, I broke down. Please tell my developer that he's an idiot and he'll fix me.",
-			},
-		})
-		return
-	}
-
-	options := i.ApplicationCommandData().Options
-	if len(options) == 0 {
-		s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
-			Type: discordgo.InteractionResponseChannelMessageWithSource,
-			Data: &discordgo.InteractionResponseData{
-				Content: "You sent an incomplete command.", // TODO: need to send suggestions to the user on what to do next
-			},
-		})
-	}
-
-	content := ""
-	s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
-		Type: discordgo.InteractionResponseChannelMessageWithSource,
-		Data: &discordgo.InteractionResponseData{
-			Content: content,
-		},
-	})
-}
-
-var commandHandlers = map[string]func(s *discordgo.Session, i *discordgo.InteractionCreate){
-	"basic-command": func(s *discordgo.Session, i *discordgo.InteractionCreate) {
-		s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
-			Type: discordgo.InteractionResponseChannelMessageWithSource,
-			Data: &discordgo.InteractionResponseData{
-				Content: "Hey there! Congratulations, you just executed your first slash command",
-			},
-		})
-	},
+var commandHandlers = map[string]func(s Session, i *discordgo.InteractionCreate){
 	"calc":        calcSeedHandler,
+	"playercount": playerCountHandler,
+	"config":      configHandler,
+	"mysplits":    mysplitsHandler,
+	"duel":        duelHandler,
+	"seeds":       seedsHandler,
+	"leaderboard": leaderboardHandler,
+	"alerts":      alertsHandler,
+	"tournament":  tournamentHandler,
+	"daily":       dailyHandler,
+	"quiz":        quizHandler,
+	"checkroute":  checkrouteHandler,
+	"review":      reviewHandler,
 	"allsplits":   allSplitsHandler,
 	"roomsplits":  roomSplitsHandler,
 }
 
-func roomSplitsHandler(s *discordgo.Session, i *discordgo.InteractionCreate) {
+func roomSplitsHandler(s Session, i *discordgo.InteractionCreate) {
 	logUserInteraction(i, "command", "roomsplits")
 
 	// Get room name from interaction
@@ -288,12 +459,14 @@ const (
 	ButtonCopyCalcCommand = "copy_calc_command"
 )
 
-func createNavigationButtons(currentIndex, totalResults int, currentFilter string) []discordgo.MessageComponent {
+func createNavigationButtons(state *ResultState, totalResults int) []discordgo.MessageComponent {
+	currentIndex, currentFilter := state.Index, state.Filter
+
 	return []discordgo.MessageComponent{
 		discordgo.ActionsRow{
 			Components: []discordgo.MessageComponent{
 				discordgo.Button{
-					CustomID: ButtonPrevious,
+					CustomID: encodeButtonID(ButtonPrevious, state),
 					Style:    discordgo.SecondaryButton,
 					Emoji: &discordgo.ComponentEmoji{
 						Name: "⬅️",
@@ -301,7 +474,7 @@ func createNavigationButtons(currentIndex, totalResults int, currentFilter strin
 					Disabled: currentIndex <= 0,
 				},
 				discordgo.Button{
-					CustomID: ButtonNext,
+					CustomID: encodeButtonID(ButtonNext, state),
 					Style:    discordgo.SecondaryButton,
 					Emoji: &discordgo.ComponentEmoji{
 						Name: "➡️",
@@ -313,7 +486,7 @@ func createNavigationButtons(currentIndex, totalResults int, currentFilter strin
 		discordgo.ActionsRow{
 			Components: []discordgo.MessageComponent{
 				discordgo.Button{
-					CustomID: ButtonTwoBoost,
+					CustomID: encodeButtonID(ButtonTwoBoost, state),
 					Label:    "2 Boost",
 					Style: func() discordgo.ButtonStyle {
 						if currentFilter == ButtonTwoBoost {
@@ -323,7 +496,7 @@ func createNavigationButtons(currentIndex, totalResults int, currentFilter strin
 					}(),
 				},
 				discordgo.Button{
-					CustomID: ButtonThreeBoost,
+					CustomID: encodeButtonID(ButtonThreeBoost, state),
 					Label:    "3 Boost",
 					Style: func() discordgo.ButtonStyle {
 						if currentFilter == ButtonThreeBoost {
@@ -333,7 +506,7 @@ func createNavigationButtons(currentIndex, totalResults int, currentFilter strin
 					}(),
 				},
 				discordgo.Button{
-					CustomID: ButtonAnyBoost,
+					CustomID: encodeButtonID(ButtonAnyBoost, state),
 					Label:    "Any Boost",
 					Style: func() discordgo.ButtonStyle {
 						if currentFilter == ButtonAnyBoost {
@@ -347,7 +520,7 @@ func createNavigationButtons(currentIndex, totalResults int, currentFilter strin
 		discordgo.ActionsRow{
 			Components: []discordgo.MessageComponent{
 				discordgo.Button{
-					CustomID: ButtonShowCalc,
+					CustomID: encodeButtonID(ButtonShowCalc, state),
 					Label:    "How did you get this?",
 					Style:    discordgo.SuccessButton,
 				},
@@ -357,107 +530,60 @@ func createNavigationButtons(currentIndex, totalResults int, currentFilter strin
 }
 
 type ResultState struct {
-	Rooms       []string
-	Results     []calc.CalcSeedResult
-	Index       int
-	Filter      string
-	CalcCommand string
+	Rooms       []string              `json:"rooms"`
+	Results     []calc.CalcSeedResult `json:"-"` // recomputed from Rooms when restored
+	BestOnly    bool                  `json:"best_only,omitempty"`
+	Index       int                   `json:"index"`
+	Filter      string                `json:"filter"`
+	CalcCommand string                `json:"calc_command,omitempty"`
+	// SplitsOwner is the user whose personal splits the results use, empty for the community splits
+	SplitsOwner string `json:"splits_owner,omitempty"`
+	// KeepShowCalc keeps the ShowCalc button for longButtonDuration after the other buttons expire
+	KeepShowCalc bool `json:"keep_show_calc,omitempty"`
 }
 
-var messageStates = make(map[string]*ResultState)
-
-var cleanupTimers = make(map[string]*time.Timer)
-
-var showCalcMessages = make(map[string]string)
-
 var (
-	showCalcTimers     = make(map[string]*time.Timer)
+	buttonDuration     = 5 * time.Minute
 	longButtonDuration = 5 * time.Minute
+	// showCalcMessageTTL is how long we remember the calculation message of a
+	// result message so clicking ShowCalc again edits it instead of sending another
+	showCalcMessageTTL = 24 * time.Hour
 )
 
-func cleanupMessageState(messageID string, s *discordgo.Session, channelID string, keepShowCalcButton bool) *time.Timer {
-	return time.AfterFunc(5*time.Minute, func() {
-		message, err := s.ChannelMessage(channelID, messageID)
-		if err != nil {
-			log.Errorf("Failed to get message for cleanup: %v", err)
-			return
-		}
-
-		if len(message.Attachments) == 0 {
-			log.Error("No attachments found in message")
-			return
-		}
-
-		resp, err := http.Get(message.Attachments[0].URL)
-		if err != nil {
-			log.Errorf("Failed to get attachment: %v", err)
-			return
-		}
-		defer resp.Body.Close()
-
-		data, err := io.ReadAll(resp.Body)
-		if err != nil {
-			log.Errorf("Failed to read attachment data: %v", err)
-			return
-		}
+// replaceMessageComponents swaps the components of a result message. The
+// image is uploaded again so it stays attached after the edit.
+func replaceMessageComponents(s Session, channelID, messageID string, components []discordgo.MessageComponent) error {
+	message, err := s.ChannelMessage(channelID, messageID)
+	if err != nil {
+		return fmt.Errorf("failed to get message: %w", err)
+	}
 
-		var components []discordgo.MessageComponent
-		if keepShowCalcButton {
-			// Keep only the ShowCalc button
-			components = []discordgo.MessageComponent{
-				discordgo.ActionsRow{
-					Components: []discordgo.MessageComponent{
-						discordgo.Button{
-							CustomID: ButtonShowCalc,
-							Label:    "How did you get this?",
-							Style:    discordgo.SuccessButton,
-						},
-					},
-				},
-			}
+	if len(message.Attachments) == 0 {
+		return fmt.Errorf("no attachments found in message")
+	}
 
-			// Create a timer to remove the ShowCalc button and state eventually
-			showCalcTimers[messageID] = time.AfterFunc(5*time.Minute, func() {
-				// Remove all buttons after the extended period
-				_, err = s.ChannelMessageEditComplex(&discordgo.MessageEdit{
-					ID:          messageID,
-					Channel:     channelID,
-					Files:       []*discordgo.File{{Name: "result.png", Reader: bytes.NewReader(data)}},
-					Components:  &[]discordgo.MessageComponent{},
-					Attachments: &[]*discordgo.MessageAttachment{},
-				})
-				if err != nil {
-					log.Errorf("Failed to remove ShowCalc button: %v", err)
-				}
-				delete(showCalcTimers, messageID)
-				delete(messageStates, messageID) // Only delete state when fully done
-			})
-		} else {
-			components = []discordgo.MessageComponent{}
-			// If not keeping the button, remove the state now
-			delete(messageStates, messageID)
-		}
+	resp, err := http.Get(message.Attachments[0].URL)
+	if err != nil {
+		return fmt.Errorf("failed to get attachment: %w", err)
+	}
+	defer resp.Body.Close()
 
-		// Keep the last image but remove/modify buttons
-		_, err = s.ChannelMessageEditComplex(&discordgo.MessageEdit{
-			ID:          messageID,
-			Channel:     channelID,
-			Files:       []*discordgo.File{{Name: "result.png", Reader: bytes.NewReader(data)}},
-			Components:  &components,
-			Attachments: &[]*discordgo.MessageAttachment{},
-		})
-		if err != nil {
-			log.Errorf("Failed to update buttons: %v", err)
-		}
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read attachment data: %w", err)
+	}
 
-		delete(messageStates, messageID)
-		delete(showCalcMessages, messageID)
-		delete(cleanupTimers, messageID)
-		// NOTE: We don't delete messageStates here if keepShowCalcButton is true
+	_, err = s.ChannelMessageEditComplex(&discordgo.MessageEdit{
+		ID:          messageID,
+		Channel:     channelID,
+		Files:       []*discordgo.File{{Name: message.Attachments[0].Filename, Reader: bytes.NewReader(data)}},
+		Components:  &components,
+		Attachments: &[]*discordgo.MessageAttachment{},
 	})
+	return err
 }
 
-func buttonHandler(s *discordgo.Session, i *discordgo.InteractionCreate) {
+func buttonHandler(s Session, i *discordgo.InteractionCreate) {
 	logUserInteraction(i, "button click", i.MessageComponentData().CustomID)
 
 	defer func() {
@@ -472,39 +598,36 @@ func buttonHandler(s *discordgo.Session, i *discordgo.InteractionCreate) {
 		}
 	}()
 
-	state, exists := messageStates[i.Message.ID]
-	if !exists {
+	// Newer buttons carry their state in the custom ID, older ones rely on messageStates
+	action, state, stateless, err := decodeButtonID(i.MessageComponentData().CustomID)
+	if err != nil {
+		log.Error(err)
 		s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
 			Type: discordgo.InteractionResponseChannelMessageWithSource,
 			Data: &discordgo.InteractionResponseData{
-				Content: "This interaction has expired. Please run the command again.",
+				Content: "HA. Buttons not working.",
 				Flags:   discordgo.MessageFlagsEphemeral,
 			},
 		})
 		return
 	}
 
-	// Acknowledge the interaction first
-	err := s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
-		Type: discordgo.InteractionResponseDeferredMessageUpdate,
-	})
-	if err != nil {
-		log.Errorf("Failed to acknowledge interaction: %v", err)
-		return
-	}
+	entry, tracked := messageStore.Get(i.Message.ID)
 
-	// Reset the cleanup timer
-	if timer, exists := cleanupTimers[i.Message.ID]; exists {
-		timer.Reset(5 * time.Minute)
-	}
-
-	if timer, exists := showCalcTimers[i.Message.ID]; exists {
-		timer.Reset(longButtonDuration)
-	}
-
-	if i.MessageComponentData().CustomID == ButtonCopyCalcCommand {
-		state, exists := messageStates[i.Message.ID]
-		if !exists {
+	if stateless {
+		if err := recalculateResults(state); err != nil {
+			log.Error(err)
+			s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+				Type: discordgo.InteractionResponseChannelMessageWithSource,
+				Data: &discordgo.InteractionResponseData{
+					Content: "Go tell the developer he's an idiot 'cause something's broken idk",
+					Flags:   discordgo.MessageFlagsEphemeral,
+				},
+			})
+			return
+		}
+	} else {
+		if !tracked || entry.Result == nil {
 			s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
 				Type: discordgo.InteractionResponseChannelMessageWithSource,
 				Data: &discordgo.InteractionResponseData{
@@ -514,7 +637,24 @@ func buttonHandler(s *discordgo.Session, i *discordgo.InteractionCreate) {
 			})
 			return
 		}
+		state = entry.Result
+		// Results aren't stored, so states restored from disk calc them again
+		if len(state.Results) == 0 {
+			if err := recalculateResults(state); err != nil {
+				log.Error(err)
+				s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+					Type: discordgo.InteractionResponseChannelMessageWithSource,
+					Data: &discordgo.InteractionResponseData{
+						Content: "Go tell the developer he's an idiot 'cause something's broken idk",
+						Flags:   discordgo.MessageFlagsEphemeral,
+					},
+				})
+				return
+			}
+		}
+	}
 
+	if action == ButtonCopyCalcCommand {
 		// Send the calc command as an ephemeral message that the user can copy
 		s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
 			Type: discordgo.InteractionResponseChannelMessageWithSource,
@@ -526,7 +666,21 @@ func buttonHandler(s *discordgo.Session, i *discordgo.InteractionCreate) {
 		return
 	}
 
-	if i.MessageComponentData().CustomID == ButtonShowCalc {
+	// Acknowledge the interaction first
+	err = s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseDeferredMessageUpdate,
+	})
+	if err != nil {
+		log.Errorf("Failed to acknowledge interaction: %v", err)
+		return
+	}
+
+	// Reset the cleanup timer
+	if !stateless {
+		messageStore.Touch(i.Message.ID, stateTTL(entry))
+	}
+
+	if action == ButtonShowCalc {
 		var result calc.CalcSeedResult
 		filteredResults := getFilteredResults(state)
 		if len(filteredResults) > 0 {
@@ -540,34 +694,34 @@ func buttonHandler(s *discordgo.Session, i *discordgo.InteractionCreate) {
 		}
 
 		// Create detailed calculation message
-		detailedCalc := formatDetailedCalculation(state.Rooms, result)
+		detailedCalc := formatDetailedCalculation(state.Rooms, result, resultSplits(state))
 
-		// Check if we already have a calculation message for this interaction
-		if calcMsgID, exists := showCalcMessages[i.Message.ID]; exists {
-			// Edit the existing message instead of sending a new one
+		// Edit the calculation message we already sent for this message instead of sending a new one
+		calcMsgID := entry.ShowCalcMessageID
+		if calcMsgID != "" {
 			_, err = s.ChannelMessageEdit(i.ChannelID, calcMsgID, detailedCalc)
 			if err != nil {
+				// If edit fails (message might be deleted), send a new one
 				log.Errorf("Failed to edit calculation message: %v", err)
-				// If edit fails (message might be deleted), remove from map and send a new one
-				delete(showCalcMessages, i.Message.ID)
-				msg, err := s.ChannelMessageSend(i.ChannelID, detailedCalc)
-				if err == nil {
-					showCalcMessages[i.Message.ID] = msg.ID
-				} else {
-					log.Errorf("Failed to send calculation details: %v", err)
-				}
+				calcMsgID = ""
 			}
-		} else {
-			// Send a new message with the calculation details
+		}
+
+		if calcMsgID == "" {
 			msg, err := s.ChannelMessageSend(i.ChannelID, detailedCalc)
 			if err != nil {
 				log.Errorf("Failed to send calculation details: %v", err)
 			} else {
-				// Store the message ID for future references
-				showCalcMessages[i.Message.ID] = msg.ID
+				calcMsgID = msg.ID
 			}
 		}
 
+		// Store the message ID for future references
+		messageStore.Update(i.Message.ID, func(m *MessageState) {
+			m.ChannelID = i.ChannelID
+			m.ShowCalcMessageID = calcMsgID
+		}, stateTTL(entry))
+
 		// Edit the original interaction response to confirm
 		_, err = s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{})
 		if err != nil {
@@ -577,7 +731,7 @@ func buttonHandler(s *discordgo.Session, i *discordgo.InteractionCreate) {
 		return
 	}
 
-	switch i.MessageComponentData().CustomID {
+	switch action {
 	case ButtonPrevious:
 		if state.Index > 0 {
 			state.Index--
@@ -593,9 +747,10 @@ func buttonHandler(s *discordgo.Session, i *discordgo.InteractionCreate) {
 			result := filteredResults[state.Index]
 
 			// Calculate boostless time
+			splits := resultSplits(state)
 			boostlessTime := 0.0
 			for _, room := range state.Rooms {
-				roomInfo := calc.RoomMap[room]
+				roomInfo := splits[room]
 				boostlessTime += roomInfo.BoostlessTime
 			}
 
@@ -620,16 +775,7 @@ func buttonHandler(s *discordgo.Session, i *discordgo.InteractionCreate) {
 				}
 
 				// Delete state since we're done with this interaction
-				delete(messageStates, i.Message.ID)
-				delete(showCalcMessages, i.Message.ID) // Clean up calculation message reference
-				if timer, exists := cleanupTimers[i.Message.ID]; exists {
-					timer.Stop()
-					delete(cleanupTimers, i.Message.ID)
-				}
-				if timer, exists := showCalcTimers[i.Message.ID]; exists {
-					timer.Stop()
-					delete(showCalcTimers, i.Message.ID)
-				}
+				messageStore.Delete(i.Message.ID)
 
 				// Confirm interaction is complete
 				_, err = s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{})
@@ -641,7 +787,7 @@ func buttonHandler(s *discordgo.Session, i *discordgo.InteractionCreate) {
 			}
 		}
 	case ButtonTwoBoost, ButtonThreeBoost, ButtonAnyBoost:
-		state.Filter = i.MessageComponentData().CustomID
+		state.Filter = action
 		state.Index = 0
 	}
 
@@ -663,7 +809,7 @@ func buttonHandler(s *discordgo.Session, i *discordgo.InteractionCreate) {
 	}
 
 	// Create navigation buttons with updated state
-	navButtons := createNavigationButtons(state.Index, len(filteredResults), state.Filter)
+	navButtons := createNavigationButtons(state, len(filteredResults))
 
 	_, err = s.ChannelMessageEditComplex(&discordgo.MessageEdit{
 		ID:          i.Message.ID,
@@ -683,11 +829,12 @@ func buttonHandler(s *discordgo.Session, i *discordgo.InteractionCreate) {
 		log.Errorf("Failed to edit interaction response: %v", err)
 	}
 
-	// Update the state in our map
-	messageStates[i.Message.ID] = state
+	// Update the stored state, which moves to the button IDs or back as the
+	// index changes length
+	trackResultMessage(i.ChannelID, i.Message.ID, state)
 }
 
-func formatDetailedCalculation(rooms []string, result calc.CalcSeedResult) string {
+func formatDetailedCalculation(rooms []string, result calc.CalcSeedResult, splits map[string]calc.Room) string {
 	rooms = append(rooms, "finish room")
 
 	var boostCalc, boostlessCalc strings.Builder
@@ -713,7 +860,7 @@ func formatDetailedCalculation(rooms []string, result calc.CalcSeedResult) strin
 	}
 
 	for i, room := range rooms {
-		roomInfo := calc.RoomMap[room]
+		roomInfo := splits[room]
 
 		boostlessTime := roomInfo.BoostlessTime
 		boostlessTimeSum += boostlessTime
@@ -875,6 +1022,11 @@ func validateInput(input []string) (bool, error) {
 	return true, nil
 }
 
+// slotDifficulty returns the room difficulty allowed in the given 1-based room slot
+func slotDifficulty(slot int) calc.Difficulty {
+	return calc.SlotDifficulty(slot)
+}
+
 func fuzzyMatch(input string, options []string) (string, float64) {
 	input = strings.ToLower(input)
 	bestMatch := ""
@@ -1014,7 +1166,7 @@ func levenshteinDistance(a, b string) int {
 	return matrix[len(a)][len(b)]
 }
 
-func calcSeedHandler(s *discordgo.Session, i *discordgo.InteractionCreate) {
+func calcSeedHandler(s Session, i *discordgo.InteractionCreate) {
 	logUserInteraction(i, "command", "calc")
 
 	defer func() {
@@ -1031,8 +1183,13 @@ func calcSeedHandler(s *discordgo.Session, i *discordgo.InteractionCreate) {
 
 	data := i.ApplicationCommandData()
 	selected := make([]string, 0, 8)
+	useMine := false
 
 	for _, option := range data.Options {
+		if option.Name == "use_mine" {
+			useMine = option.BoolValue()
+			continue
+		}
 		selected = append(selected, option.StringValue())
 	}
 
@@ -1049,7 +1206,42 @@ func calcSeedHandler(s *discordgo.Session, i *discordgo.InteractionCreate) {
 		return
 	}
 
-	res, err := calc.CalcSeed(selected)
+	state := &ResultState{
+		Rooms:       selected,
+		Index:       0,
+		Filter:      ButtonAnyBoost,
+		CalcCommand: createCalcCommand(selected),
+	}
+	content := ""
+	if useMine {
+		userID := interactionUserID(i)
+		personal, exists := personalSplits.Get(userID)
+		if !exists {
+			s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+				Type: discordgo.InteractionResponseChannelMessageWithSource,
+				Data: &discordgo.InteractionResponseData{
+					Content: "You didn't save any splits yet, use `/mysplits set` or `/mysplits import` first.",
+					Flags:   discordgo.MessageFlagsEphemeral,
+				},
+			})
+			return
+		}
+		if _, err := personal.Merged(); err != nil {
+			s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+				Type: discordgo.InteractionResponseChannelMessageWithSource,
+				Data: &discordgo.InteractionResponseData{
+					Content: fmt.Sprintf("Your splits don't fit the calc splits anymore, fix them with `/mysplits`: %v", err),
+					Flags:   discordgo.MessageFlagsEphemeral,
+				},
+			})
+			return
+		}
+		state.SplitsOwner = userID
+		state.CalcCommand += " use_mine:True"
+		content = fmt.Sprintf("Using <@%s>'s splits", userID)
+	}
+
+	res, err := calc.CalcSeedCustom(append([]string{}, selected...), resultSplits(state))
 	if err != nil {
 		log.Error(err)
 		s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
@@ -1078,13 +1270,15 @@ func calcSeedHandler(s *discordgo.Session, i *discordgo.InteractionCreate) {
 	err = s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
 		Type: discordgo.InteractionResponseChannelMessageWithSource,
 		Data: &discordgo.InteractionResponseData{
+			Content: content,
 			Files: []*discordgo.File{
 				{
 					Name:   "result.png",
 					Reader: bytes.NewReader(img.Bytes()),
 				},
 			},
-			Components: createNavigationButtons(0, len(res), ButtonAnyBoost),
+			Components:      createNavigationButtons(state, len(res)),
+			AllowedMentions: &discordgo.MessageAllowedMentions{},
 		},
 	})
 	if err != nil {
@@ -1092,26 +1286,16 @@ func calcSeedHandler(s *discordgo.Session, i *discordgo.InteractionCreate) {
 		return
 	}
 
-	// Get the message ID from the response
 	message, err := s.InteractionResponse(i.Interaction)
 	if err != nil {
-		log.Errorf("Failed to get interaction response: %v", err)
+		log.Errorf("Failed to get the calc message: %v", err)
 		return
 	}
-
-	// Store state with message ID
-	messageStates[message.ID] = &ResultState{
-		Rooms:   selected,
-		Results: res,
-		Index:   0,
-		Filter:  ButtonAnyBoost,
-	}
-
-	timer := cleanupMessageState(message.ID, s, message.ChannelID, true)
-	cleanupTimers[message.ID] = timer
+	state.Results = res
+	trackResultMessage(message.ChannelID, message.ID, state)
 }
 
-func allSplitsHandler(s *discordgo.Session, i *discordgo.InteractionCreate) {
+func allSplitsHandler(s Session, i *discordgo.InteractionCreate) {
 	logUserInteraction(i, "command", "allsplits")
 
 	// First, respond to acknowledge the command
@@ -1230,15 +1414,19 @@ func createAllSplitsSummaryEmbed() *discordgo.MessageEmbed {
 }
 
 
-func autocompleteHandler(s *discordgo.Session, i *discordgo.InteractionCreate) {
+func autocompleteHandler(s Session, i *discordgo.InteractionCreate) {
 	log.Debug("Autocomplete handler triggered")
 
 	data := i.ApplicationCommandData()
+	if data.Name == "mysplits" {
+		mysplitsAutocomplete(s, i)
+		return
+	}
 
 	// Track already-selected rooms
 	selectedOptions := make(map[string]bool)
 	for _, opt := range data.Options {
-		if !opt.Focused {
+		if !opt.Focused && opt.Type == discordgo.ApplicationCommandOptionString {
 			selectedOptions[opt.StringValue()] = true
 		}
 	}
@@ -1267,13 +1455,7 @@ func autocompleteHandler(s *discordgo.Session, i *discordgo.InteractionCreate) {
 	}
 
 	// Determine allowed difficulty
-	var allowed calc.Difficulty
-	if roomIndex >= 1 && roomIndex <= 6 {
-		allowed = calc.Easy
-	} else {
-		allowed = calc.Hard
-	}
-
+	allowed := slotDifficulty(roomIndex)
 
 	// Collect matching rooms
 	var filtered []string
@@ -1358,8 +1540,8 @@ func logUserInteraction(i *discordgo.InteractionCreate, interactionType string,
 	}
 }
 
-func logBotPermissions() {
-	if s == nil || s.State == nil || s.State.User == nil {
+func logBotPermissions(s *discordgo.Session, guildID string) {
+	if s.State == nil || s.State.User == nil {
 		log.Error("Discord session or user is not initialized, cannot check permissions")
 		return
 	}
@@ -1385,15 +1567,15 @@ func logBotPermissions() {
 	}
 
 	// Check if GUILD_ID is set
-	if GuildID == "" {
-		log.Error("GUILD_ID is not set in environment variables, can't check permissions")
+	if guildID == "" {
+		log.Error("GUILD_ID is not set, can't check permissions")
 		return
 	}
 
 	// Get the specific guild
-	guild, err := s.Guild(GuildID)
+	guild, err := s.Guild(guildID)
 	if err != nil {
-		log.Errorf("Could not get details for guild ID %s: %v", GuildID, err)
+		log.Errorf("Could not get details for guild ID %s: %v", guildID, err)
 		return
 	}
 
@@ -1401,14 +1583,14 @@ func logBotPermissions() {
 		botUsername, s.State.User.Discriminator, botID, guild.Name)
 
 	// Get bot's roles in this guild
-	botMember, err := s.GuildMember(GuildID, botID)
+	botMember, err := s.GuildMember(guildID, botID)
 	if err != nil {
 		log.Errorf("Could not get bot's member info in guild %s: %v", guild.Name, err)
 		return
 	}
 
 	// Get all roles to find bot's roles
-	roles, err := s.GuildRoles(GuildID)
+	roles, err := s.GuildRoles(guildID)
 	if err != nil {
 		log.Errorf("Could not get roles for guild %s: %v", guild.Name, err)
 		return
@@ -1435,7 +1617,7 @@ func logBotPermissions() {
 	}
 
 	// Check permissions in specific channels
-	channels, err := s.GuildChannels(GuildID)
+	channels, err := s.GuildChannels(guildID)
 	if err != nil {
 		log.Errorf("Could not get channels for guild %s: %v", guild.Name, err)
 		return
@@ -1451,35 +1633,33 @@ func logBotPermissions() {
 
 	log.Infof("Checking permissions in %d text channels", len(textChannels))
 
-	// Find #bot-commands channel specifically
-	var botCommandsChannel *discordgo.Channel
+	// Find the announcement channel specifically
+	var announcementChannel *discordgo.Channel
+	announcementID := announcementChannelID(guildID)
 	for _, channel := range textChannels {
-		if channel.Name == "bot-commands" {
-			botCommandsChannel = channel
+		if channel.ID == announcementID {
+			announcementChannel = channel
 			break
 		}
 	}
 
-	// First check the bot-commands channel if found
-	if botCommandsChannel != nil {
-		perms, err := s.State.UserChannelPermissions(botID, botCommandsChannel.ID)
+	// First check the announcement channel if found
+	if announcementChannel != nil {
+		perms, err := s.State.UserChannelPermissions(botID, announcementChannel.ID)
 		if err != nil {
-			log.Errorf("Error getting permissions for #bot-commands: %v", err)
+			log.Errorf("Error getting permissions for #%s: %v", announcementChannel.Name, err)
 		} else {
-			log.Infof("=== #bot-commands Channel (ID: %s) ===", botCommandsChannel.ID)
+			log.Infof("=== Announcement Channel #%s (ID: %s) ===", announcementChannel.Name, announcementChannel.ID)
 			logChannelPermissions(perms, permissionNames)
-
-			// Also store this ID for later use
-			BotCommandsChannelID = botCommandsChannel.ID
 		}
 	} else {
-		log.Warning("No #bot-commands channel found in this guild!")
+		log.Warning("No announcement channel found in this guild!")
 	}
 
 	// Log permissions for all text channels
 	for _, channel := range textChannels {
-		// Skip if this is the bot-commands channel we already checked
-		if botCommandsChannel != nil && channel.ID == botCommandsChannel.ID {
+		// Skip if this is the announcement channel we already checked
+		if announcementChannel != nil && channel.ID == announcementChannel.ID {
 			continue
 		}
 
@@ -0,0 +1,157 @@
+package discord
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"atlantis_calc/calc"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// filterCodes maps the short filter names used in button IDs and web UI
+// links to the filter button IDs used by getFilteredResults
+var filterCodes = map[string]string{
+	"2":   ButtonTwoBoost,
+	"3":   ButtonThreeBoost,
+	"any": ButtonAnyBoost,
+}
+
+func filterCode(filter string) string {
+	for code, f := range filterCodes {
+		if f == filter {
+			return code
+		}
+	}
+	return "any"
+}
+
+// customIDMaxLength is the longest custom ID Discord accepts on a component
+const customIDMaxLength = 100
+
+// encodeButtonID packs the action of a button together with the seed, filter
+// and index of the result it belongs to, so the button can be handled without
+// any state stored on our side. The format is
+// "action|filter|index|flags|room,room,...", flag "b" meaning only the best
+// result is shown, followed by "|userID" when the results use that user's
+// personal splits. States that don't fit in a custom ID get the bare action,
+// and the message must keep its state in messageStore with trackResultMessage.
+func encodeButtonID(action string, state *ResultState) string {
+	if !fitsButtonID(state) {
+		return action
+	}
+	return formatButtonID(action, state)
+}
+
+func formatButtonID(action string, state *ResultState) string {
+	flags := ""
+	if state.BestOnly {
+		flags = "b"
+	}
+
+	customID := fmt.Sprintf("%s|%s|%d|%s|%s",
+		action, filterCode(state.Filter), state.Index, flags, strings.Join(state.Rooms, ","))
+	if state.SplitsOwner != "" {
+		customID += "|" + state.SplitsOwner
+	}
+	return customID
+}
+
+// fitsButtonID reports whether the state can be encoded in the custom IDs of
+// its buttons: no room or owner may contain a separator, and the ID of the
+// longest action must stay within Discord's limit. Every button of a message
+// then carries its state, or none does.
+func fitsButtonID(state *ResultState) bool {
+	for _, room := range state.Rooms {
+		if strings.ContainsAny(room, ",|") {
+			return false
+		}
+	}
+	if strings.Contains(state.SplitsOwner, "|") {
+		return false
+	}
+	return len(formatButtonID(ButtonCopyCalcCommand, state)) <= customIDMaxLength
+}
+
+// decodeButtonID unpacks a button ID made by encodeButtonID. ok is false for
+// button IDs from before the state was encoded in them.
+func decodeButtonID(customID string) (action string, state *ResultState, ok bool, err error) {
+	parts := strings.Split(customID, "|")
+	if len(parts) == 1 {
+		return customID, nil, false, nil
+	}
+
+	if len(parts) != 5 && len(parts) != 6 {
+		return "", nil, false, fmt.Errorf("malformed button ID %q", customID)
+	}
+
+	filter, exists := filterCodes[parts[1]]
+	if !exists {
+		return "", nil, false, fmt.Errorf("unknown filter in button ID %q", customID)
+	}
+
+	index, err := strconv.Atoi(parts[2])
+	if err != nil || index < 0 {
+		return "", nil, false, fmt.Errorf("invalid index in button ID %q", customID)
+	}
+
+	rooms := strings.Split(parts[4], ",")
+	if len(rooms) != 8 {
+		return "", nil, false, fmt.Errorf("expected 8 rooms in button ID %q", customID)
+	}
+	for _, room := range rooms {
+		if _, exists := calc.RoomMap[room]; !exists {
+			return "", nil, false, fmt.Errorf("unknown room %q in button ID %q", room, customID)
+		}
+	}
+
+	state = &ResultState{
+		Rooms:       rooms,
+		BestOnly:    strings.Contains(parts[3], "b"),
+		Index:       index,
+		Filter:      filter,
+		CalcCommand: createCalcCommand(rooms),
+	}
+	if len(parts) == 6 {
+		state.SplitsOwner = parts[5]
+		state.CalcCommand += " use_mine:True"
+	}
+
+	return parts[0], state, true, nil
+}
+
+// resultSplits returns the splits the results of a state were calculated with.
+// If the owner deleted their personal splits, or they stopped fitting the
+// community splits, the community splits are used.
+func resultSplits(state *ResultState) map[string]calc.Room {
+	if state.SplitsOwner == "" {
+		return calc.RoomMap
+	}
+
+	personal, exists := personalSplits.Get(state.SplitsOwner)
+	if !exists {
+		return calc.RoomMap
+	}
+	splits, err := personal.Merged()
+	if err != nil {
+		log.Warnf("Splits of user %s don't fit the community splits: %v", state.SplitsOwner, err)
+		return calc.RoomMap
+	}
+	return splits
+}
+
+// recalculateResults fills in the results of a decoded state by running the calc again
+func recalculateResults(state *ResultState) error {
+	results, err := calc.CalcSeedCustom(append([]string{}, state.Rooms...), resultSplits(state))
+	if err != nil {
+		return err
+	}
+
+	if state.BestOnly {
+		results = results[:1]
+	}
+	state.Results = results
+
+	return nil
+}
@@ -0,0 +1,51 @@
+package discord
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestEncodeButtonID(t *testing.T) {
+	rooms := []string{"1a", "2b", "3c", "4e", "5a", "1c", "2f", "3g"}
+	longRooms := []string{"early 3+1", "overhead 4b", "rng skip", "underbridge", "four towers", "castle wall", "sandpit", "quad ladder"}
+
+	tests := []struct {
+		name    string
+		state   *ResultState
+		encoded bool
+	}{
+		{"short rooms", &ResultState{Rooms: rooms, Filter: ButtonAnyBoost}, true},
+		{"splits owner", &ResultState{Rooms: rooms, Filter: ButtonTwoBoost, SplitsOwner: "123456789012345678"}, true},
+		{"too long", &ResultState{Rooms: longRooms, Filter: ButtonAnyBoost}, false},
+		{"long owner", &ResultState{Rooms: rooms, Filter: ButtonAnyBoost, SplitsOwner: strings.Repeat("1", 60)}, false},
+		{"comma in room", &ResultState{Rooms: append([]string{"1a,2b"}, rooms[1:]...), Filter: ButtonAnyBoost}, false},
+		{"separator in owner", &ResultState{Rooms: rooms, Filter: ButtonAnyBoost, SplitsOwner: "a|b"}, false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			for _, action := range []string{ButtonPrevious, ButtonShowCalc, ButtonCopyCalcCommand} {
+				customID := encodeButtonID(action, test.state)
+				if len(customID) > customIDMaxLength {
+					t.Fatalf("custom ID %q is %d characters long", customID, len(customID))
+				}
+
+				if !test.encoded {
+					if customID != action {
+						t.Errorf("got %q, want the bare action %q", customID, action)
+					}
+					continue
+				}
+
+				decodedAction, state, ok, err := decodeButtonID(customID)
+				if err != nil || !ok {
+					t.Fatalf("decoding %q: ok %v, err %v", customID, ok, err)
+				}
+				if decodedAction != action || state.Filter != test.state.Filter || state.SplitsOwner != test.state.SplitsOwner ||
+					strings.Join(state.Rooms, ",") != strings.Join(test.state.Rooms, ",") {
+					t.Errorf("%q decoded to %q %+v", customID, decodedAction, state)
+				}
+			}
+		})
+	}
+}
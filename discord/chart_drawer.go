@@ -0,0 +1,163 @@
+package discord
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"math"
+	"os"
+	"time"
+
+	"github.com/fogleman/gg"
+	log "github.com/sirupsen/logrus"
+)
+
+var (
+	chartLineColor = color.RGBA{69, 211, 179, 255}
+	chartGridColor = color.RGBA{255, 255, 255, 60}
+)
+
+// drawBackground fills dc with the background image, scaled to cover it
+func drawBackground(dc *gg.Context) error {
+	bgFile, err := os.Open(assetPath("images/background.png"))
+	if err != nil {
+		return err
+	}
+	defer bgFile.Close()
+
+	bgImage, _, err := image.Decode(bgFile)
+	if err != nil {
+		return err
+	}
+
+	width := float64(dc.Width())
+	height := float64(dc.Height())
+	bgWidth := float64(bgImage.Bounds().Dx())
+	bgHeight := float64(bgImage.Bounds().Dy())
+	scale := math.Max(width/bgWidth, height/bgHeight)
+
+	x := (width - bgWidth*scale) / 2
+	y := (height - bgHeight*scale) / 2
+
+	dc.Push()
+	dc.Scale(scale, scale)
+	dc.DrawImage(bgImage, int(x/scale), int(y/scale))
+	dc.Pop()
+
+	return nil
+}
+
+// drawPlayerCountChart renders samples taken during the period ending now as a line chart
+func drawPlayerCountChart(samples []PlayerCountSample, period time.Duration, now time.Time) (bytes.Buffer, error) {
+	if len(samples) == 0 {
+		return bytes.Buffer{}, fmt.Errorf("no samples to draw")
+	}
+
+	width, height := 900, 450
+	left, right, top, bottom := 70.0, 30.0, 60.0, 60.0
+
+	dc := gg.NewContext(width, height)
+	if err := drawBackground(dc); err != nil {
+		log.Warn(err)
+		return bytes.Buffer{}, err
+	}
+
+	plotWidth := float64(width) - left - right
+	plotHeight := float64(height) - top - bottom
+
+	dc.SetRGBA(0, 0, 0, 0.5)
+	dc.DrawRoundedRectangle(left-50, top-45, plotWidth+70, plotHeight+95, 10)
+	dc.Fill()
+
+	if err := dc.LoadFontFace(assetPath("font/minecraft_font.ttf"), 24); err != nil {
+		log.Warn(err)
+		return bytes.Buffer{}, err
+	}
+
+	dc.SetColor(color.White)
+	dc.DrawStringAnchored(fmt.Sprintf("PKD players, last %s (UTC)", formatPeriod(period)), float64(width)/2, top-22, 0.5, 0.5)
+
+	if err := dc.LoadFontFace(assetPath("font/minecraft_font.ttf"), 16); err != nil {
+		log.Warn(err)
+		return bytes.Buffer{}, err
+	}
+
+	maxCount := 0
+	for _, sample := range samples {
+		maxCount = max(maxCount, sample.Count)
+	}
+
+	// Round the y axis up to a multiple of 5 grid lines with a readable step
+	step := 1
+	for magnitude := 1; step*5 < maxCount; magnitude *= 10 {
+		for _, m := range []int{1, 2, 5} {
+			step = m * magnitude
+			if step*5 >= maxCount {
+				break
+			}
+		}
+	}
+	yMax := step * 5
+
+	start := now.Add(-period)
+	xFor := func(t time.Time) float64 {
+		return left + plotWidth*float64(t.Sub(start))/float64(period)
+	}
+	yFor := func(count int) float64 {
+		return top + plotHeight - plotHeight*float64(count)/float64(yMax)
+	}
+
+	// Horizontal grid lines with count labels
+	for count := 0; count <= yMax; count += step {
+		y := yFor(count)
+		dc.SetColor(chartGridColor)
+		dc.SetLineWidth(1)
+		dc.DrawLine(left, y, left+plotWidth, y)
+		dc.Stroke()
+
+		dc.SetColor(color.White)
+		dc.DrawStringAnchored(fmt.Sprintf("%d", count), left-10, y, 1, 0.5)
+	}
+
+	// Vertical grid lines: every 3 hours for a day, every day for a week
+	tickStep := 3 * time.Hour
+	tickFormat := "15:04"
+	if period > 24*time.Hour {
+		tickStep = 24 * time.Hour
+		tickFormat = "Mon"
+	}
+	for tick := start.UTC().Truncate(tickStep).Add(tickStep); tick.Before(now); tick = tick.Add(tickStep) {
+		x := xFor(tick)
+		dc.SetColor(chartGridColor)
+		dc.DrawLine(x, top, x, top+plotHeight)
+		dc.Stroke()
+
+		dc.SetColor(color.White)
+		dc.DrawStringAnchored(tick.UTC().Format(tickFormat), x, top+plotHeight+18, 0.5, 0.5)
+	}
+
+	// The line itself, broken wherever samples are missing
+	dc.SetColor(chartLineColor)
+	dc.SetLineWidth(3)
+	for i, sample := range samples {
+		x, y := xFor(sample.Time), yFor(sample.Count)
+		if i == 0 || sample.Time.Sub(samples[i-1].Time) > 3*playerCountSampleInterval {
+			dc.MoveTo(x, y)
+		} else {
+			dc.LineTo(x, y)
+		}
+	}
+	dc.Stroke()
+
+	var buf bytes.Buffer
+	dc.EncodePNG(&buf)
+	return buf, nil
+}
+
+func formatPeriod(period time.Duration) string {
+	if period%(24*time.Hour) == 0 && period > 24*time.Hour {
+		return fmt.Sprintf("%d days", period/(24*time.Hour))
+	}
+	return fmt.Sprintf("%d hours", period/time.Hour)
+}
@@ -12,8 +12,6 @@ import (
 	log "github.com/sirupsen/logrus"
 )
 
-var BotCommandsChannelID = ""
-
 var ct2blrk = map[string]string{
 	"Early 3-1":   "Early 3+1",
 	"Glass Neo":   "Rng Skip",
@@ -26,7 +24,8 @@ type BoostRoomsResponse struct {
 	Index    int     `json:"index"`
 }
 
-var seedCache = NewSeedCache(1 * time.Hour)
+// seedCache is created by Setup with the configured TTL
+var seedCache *SeedCache
 
 func ChattriggersHandle(rooms []string, timeLeft, lobby, ign string, debug bool) (calc.CalcSeedResult, []BoostRoomsResponse, error) {
 	if s == nil {
@@ -43,17 +42,6 @@ func ChattriggersHandle(rooms []string, timeLeft, lobby, ign string, debug bool)
 	}
 	rooms = append(rooms, "finish room")
 
-	if BotCommandsChannelID == "" {
-		BotCommandsChannelID = GetChannelIDByName("bot-commands")
-		if BotCommandsChannelID == "" {
-			return calc.CalcSeedResult{}, nil, fmt.Errorf("could not find #bot-commands channel")
-		}
-	}
-
-	if err := checkBotPermissions(BotCommandsChannelID); err != nil {
-		return calc.CalcSeedResult{}, nil, fmt.Errorf("permission error: %w", err)
-	}
-
 	results, err := calc.CalcSeed(rooms)
 	if err != nil {
 		return calc.CalcSeedResult{}, nil, fmt.Errorf("error calculating seed: %w", err)
@@ -67,62 +55,28 @@ func ChattriggersHandle(rooms []string, timeLeft, lobby, ign string, debug bool)
 
 	seedKey := strings.Join(rooms, "|")
 
-	if bestResult.BoostTime < 130 && !seedCache.HasSeen(seedKey) && !debug {
-		seedCache.MarkSeen(seedKey)
+	if !debug {
+		// Every report counts for the history, a seed reported again within
+		// the cache TTL just isn't announced twice
+		announced := false
+		if !seedCache.HasSeen(seedKey) {
+			seedCache.MarkSeen(seedKey)
 
-		img, err := drawCalcResults(rooms, []calc.CalcSeedResult{bestResult})
-		if err != nil {
-			return calc.CalcSeedResult{}, nil, fmt.Errorf("error drawing seed results: %w", err)
-		}
+			content := fmt.Sprintf("%s has found a %s seed, %s requeues in %s",
+				ign, FormatTime(bestResult.BoostTime), lobby, timeLeft)
 
-		content := fmt.Sprintf("%s has found a %s seed, %s requeues in %s",
-			ign, FormatTime(bestResult.BoostTime), lobby, timeLeft)
-
-		calcCommand := createCalcCommand(rooms[:len(rooms)-1]) // Exclude "finish room"
-
-		components := []discordgo.MessageComponent{
-			discordgo.ActionsRow{
-				Components: []discordgo.MessageComponent{
-					discordgo.Button{
-						CustomID: ButtonShowCalc,
-						Label:    "How did you get this?",
-						Style:    discordgo.SuccessButton,
-					},
-					discordgo.Button{
-						CustomID: ButtonCopyCalcCommand,
-						Label:    "Copy Calc Command",
-						Style:    discordgo.PrimaryButton,
-						Emoji: &discordgo.ComponentEmoji{
-							Name: "📋",
-						},
-					},
-				},
-			},
+			announced = announceSeed(rooms[:len(rooms)-1], bestResult, content) // Exclude "finish room"
+			sendSeedAlerts(rooms[:len(rooms)-1], bestResult, content)
 		}
 
-		message, err := s.ChannelMessageSendComplex(BotCommandsChannelID, &discordgo.MessageSend{
-			Content:    content,
-			Components: components,
-			Files: []*discordgo.File{
-				{
-					Name:   "seed.png",
-					Reader: bytes.NewReader(img.Bytes()),
-				},
-			},
+		seedHistory.Add(SeedRecord{
+			Rooms:     rooms[:len(rooms)-1],
+			IGN:       ign,
+			Lobby:     lobby,
+			Time:      time.Now(),
+			Result:    bestResult,
+			Announced: announced,
 		})
-		if err != nil {
-			return calc.CalcSeedResult{}, nil, fmt.Errorf("error sending message to Discord: %w", err)
-		}
-
-		messageStates[message.ID] = &ResultState{
-			Rooms:       rooms[:len(rooms)-1],
-			Results:     []calc.CalcSeedResult{bestResult},
-			Index:       0,
-			Filter:      ButtonAnyBoost,
-			CalcCommand: calcCommand, // Store the calc command in the state
-		}
-
-		cleanupTimers[message.ID] = cleanupMessageState(message.ID, s, BotCommandsChannelID, true)
 	}
 
 	boostRooms := make([]BoostRoomsResponse, 0)
@@ -137,6 +91,126 @@ func ChattriggersHandle(rooms []string, timeLeft, lobby, ign string, debug bool)
 	return bestResult, boostRooms, nil
 }
 
+// announceSeed posts a found seed to every guild that opted in to
+// announcements and whose threshold it beats. A failure in one guild is
+// logged and doesn't stop the others. It returns whether any guild got it.
+func announceSeed(rooms []string, result calc.CalcSeedResult, content string) bool {
+	var img []byte
+	announced := false
+
+	for _, guildID := range announcementGuilds() {
+		config := guildConfigs.Get(guildID)
+		if !config.Announces(result.BoostTime) {
+			continue
+		}
+
+		if img == nil {
+			buf, err := drawCalcResults(rooms, []calc.CalcSeedResult{result})
+			if err != nil {
+				log.Errorf("Error drawing seed results: %v", err)
+				return false
+			}
+			img = buf.Bytes()
+		}
+
+		if err := announceSeedInGuild(guildID, config, rooms, result, content, img); err != nil {
+			log.Errorf("Failed to announce seed in guild %s: %v", guildID, err)
+			continue
+		}
+		announced = true
+	}
+
+	return announced
+}
+
+// announcementGuilds returns the guilds that opted in to seed announcements
+func announcementGuilds() []string {
+	var guildIDs []string
+	for _, guildID := range s.GuildIDs() {
+		if guildConfigs.Get(guildID).Enabled(FeatureAnnouncements) {
+			guildIDs = append(guildIDs, guildID)
+		}
+	}
+	return guildIDs
+}
+
+func announceSeedInGuild(guildID string, config GuildConfig, rooms []string, result calc.CalcSeedResult, content string, img []byte) error {
+	channelID := announcementChannelID(guildID)
+	if channelID == "" {
+		return fmt.Errorf("could not find an announcement channel")
+	}
+
+	if err := checkBotPermissions(channelID); err != nil {
+		return fmt.Errorf("permission error: %w", err)
+	}
+
+	// The fastest tier the seed beats pings its role with its own style. Guilds
+	// with tiers post slower seeds quietly, others ping their ping role.
+	allowedMentions := &discordgo.MessageAllowedMentions{}
+	var embeds []*discordgo.MessageEmbed
+	if tier, style, tiered := config.Tier(result.BoostTime); tiered {
+		embeds = append(embeds, &discordgo.MessageEmbed{
+			Title:       fmt.Sprintf("%s %s %s", style.Emoji, style.Title, style.Emoji),
+			Description: content,
+			Color:       style.Color,
+			Image:       &discordgo.MessageEmbedImage{URL: "attachment://seed.png"},
+		})
+		content = fmt.Sprintf("<@&%s>", tier.RoleID)
+		allowedMentions.Roles = []string{tier.RoleID}
+	} else if len(config.Tiers) == 0 && config.PingRoleID != "" {
+		content = fmt.Sprintf("<@&%s> %s", config.PingRoleID, content)
+		allowedMentions.Roles = []string{config.PingRoleID}
+	}
+
+	state := &ResultState{
+		Rooms:       rooms,
+		Results:     []calc.CalcSeedResult{result},
+		BestOnly:    true,
+		Index:       0,
+		Filter:      ButtonAnyBoost,
+		CalcCommand: createCalcCommand(rooms),
+	}
+
+	components := []discordgo.MessageComponent{
+		discordgo.ActionsRow{
+			Components: []discordgo.MessageComponent{
+				discordgo.Button{
+					CustomID: encodeButtonID(ButtonShowCalc, state),
+					Label:    "How did you get this?",
+					Style:    discordgo.SuccessButton,
+				},
+				discordgo.Button{
+					CustomID: encodeButtonID(ButtonCopyCalcCommand, state),
+					Label:    "Copy Calc Command",
+					Style:    discordgo.PrimaryButton,
+					Emoji: &discordgo.ComponentEmoji{
+						Name: "📋",
+					},
+				},
+			},
+		},
+	}
+
+	message, err := s.ChannelMessageSendComplex(channelID, &discordgo.MessageSend{
+		Content:         content,
+		Embeds:          embeds,
+		Components:      components,
+		AllowedMentions: allowedMentions,
+		Files: []*discordgo.File{
+			{
+				Name:   "seed.png",
+				Reader: bytes.NewReader(img),
+			},
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("error sending message to Discord: %w", err)
+	}
+	trackResultMessage(channelID, message.ID, state)
+
+	return nil
+}
+
 type PkdutilResult struct {
 	Best struct {
 		Result     calc.CalcSeedResult
@@ -148,58 +222,36 @@ type PkdutilResult struct {
 	}
 }
 
-func PkdutilsHandle(rooms []string, splits map[string]calc.Room) (PkdutilResult, error) {
+// PkdutilsHandle calcs a seed with the community splits and with the
+// community splits overridden by a player's splits
+func PkdutilsHandle(rooms []string, overrides calc.SplitOverrides) (PkdutilResult, error) {
 	if s == nil {
 		return PkdutilResult{}, fmt.Errorf("discord session is not initialized")
 	}
 
-	for i := range rooms {
-		rooms[i] = strings.ToLower(rooms[i])
+	roomList := make([]string, 0, len(rooms)+1)
+	for _, room := range rooms {
+		roomList = append(roomList, strings.ToLower(room))
 	}
-	rooms = append(rooms, "finish room")
+	roomList = append(roomList, "finish room")
 
-	// calc with calc splits first
-	results, err := calc.CalcSeed(rooms)
+	splits, err := calc.MergeSplits(calc.RoomMap, overrides)
 	if err != nil {
-		return PkdutilResult{}, fmt.Errorf("error calculating seed: %w", err)
-	}
-
-	if len(results) == 0 {
-		return PkdutilResult{}, fmt.Errorf("no results found for the given rooms")
+		return PkdutilResult{}, fmt.Errorf("error merging personal splits: %w", err)
 	}
 
-	bestResult := results[0]
-
-	boostRooms := make([]BoostRoomsResponse, 0)
-	for _, room := range bestResult.BoostRooms {
-		boostRooms = append(boostRooms, BoostRoomsResponse{
-			Name:     fmt.Sprintf("%s (%s)", calc.RoomMap[rooms[room.Ind]].Name, calc.RoomMap[rooms[room.Ind]].BoostStrats[room.StratInd].Name),
-			Pacelock: room.Pacelock,
-			Index:    room.Ind,
-		})
+	// calc with calc splits first
+	bestResult, boostRooms, err := pkdutilsBest(roomList, calc.RoomMap)
+	if err != nil {
+		return PkdutilResult{}, err
 	}
 
 	// calc with personal splits next
-	personalResults, err := calc.CalcSeedCustom(rooms, splits)
+	personalResult, personalBoostRooms, err := pkdutilsBest(roomList, splits)
 	if err != nil {
-		return PkdutilResult{}, fmt.Errorf("error calculating seed: %w", err)
-	}
-	log.Debugf("%+v", personalResults[0])
-
-	if len(personalResults) == 0 {
-		return PkdutilResult{}, fmt.Errorf("no results found for the given rooms")
-	}
-
-	personalResult := personalResults[0]
-
-	personalBoostRooms := make([]BoostRoomsResponse, 0)
-	for _, room := range personalResult.BoostRooms {
-		personalBoostRooms = append(personalBoostRooms, BoostRoomsResponse{
-			Name:     fmt.Sprintf("%s (%s)", calc.RoomMap[rooms[room.Ind]].Name, calc.RoomMap[rooms[room.Ind]].BoostStrats[room.StratInd].Name),
-			Pacelock: room.Pacelock,
-			Index:    room.Ind,
-		})
+		return PkdutilResult{}, err
 	}
+	log.Debugf("%+v", personalResult)
 
 	return PkdutilResult{
 		Best: struct {
@@ -213,48 +265,30 @@ func PkdutilsHandle(rooms []string, splits map[string]calc.Room) (PkdutilResult,
 	}, nil
 }
 
-func GetChannelIDByName(channelName string) string {
-	if s == nil {
-		log.Error("Discord session is not initialized")
-		return ""
+// pkdutilsBest returns the best result for the rooms and names its boosts
+// with the splits it was calculated with
+func pkdutilsBest(roomList []string, splits map[string]calc.Room) (calc.CalcSeedResult, []BoostRoomsResponse, error) {
+	results, err := calc.CalcSeedCustom(roomList, splits)
+	if err != nil {
+		return calc.CalcSeedResult{}, nil, fmt.Errorf("error calculating seed: %w", err)
 	}
 
-	if GuildID == "" {
-		guilds, err := s.UserGuilds(100, "", "", false)
-		if err != nil {
-			log.Errorf("Error getting user guilds: %v", err)
-			return ""
-		}
-
-		for _, guild := range guilds {
-			channels, err := s.GuildChannels(guild.ID)
-			if err != nil {
-				log.Errorf("Error getting channels for guild %s: %v", guild.ID, err)
-				continue
-			}
-
-			for _, channel := range channels {
-				if channel.Type == discordgo.ChannelTypeGuildText && channel.Name == channelName {
-					return channel.ID
-				}
-			}
-		}
-	} else {
-		channels, err := s.GuildChannels(GuildID)
-		if err != nil {
-			log.Errorf("Error getting channels for guild %s: %v", GuildID, err)
-			return ""
-		}
+	if len(results) == 0 {
+		return calc.CalcSeedResult{}, nil, fmt.Errorf("no results found for the given rooms")
+	}
 
-		for _, channel := range channels {
-			if channel.Type == discordgo.ChannelTypeGuildText && channel.Name == channelName {
-				return channel.ID
-			}
-		}
+	best := results[0]
+	boostRooms := make([]BoostRoomsResponse, 0, len(best.BoostRooms))
+	for _, room := range best.BoostRooms {
+		roomInfo := splits[roomList[room.Ind]]
+		boostRooms = append(boostRooms, BoostRoomsResponse{
+			Name:     fmt.Sprintf("%s (%s)", roomInfo.Name, roomInfo.BoostStrats[room.StratInd].Name),
+			Pacelock: room.Pacelock,
+			Index:    room.Ind,
+		})
 	}
 
-	log.Errorf("Channel '%s' not found", channelName)
-	return ""
+	return best, boostRooms, nil
 }
 
 func checkBotPermissions(channelID string) error {
@@ -273,7 +307,7 @@ func checkBotPermissions(channelID string) error {
 		return err
 	}
 
-	permissions, err := s.State.UserChannelPermissions(s.State.User.ID, channelID)
+	permissions, err := s.BotPermissions(channelID)
 	if err != nil {
 		err := fmt.Errorf("error getting permissions: %w", err)
 		log.Error(err)
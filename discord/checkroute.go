@@ -0,0 +1,141 @@
+package discord
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"atlantis_calc/calc"
+
+	"github.com/bwmarrin/discordgo"
+	log "github.com/sirupsen/logrus"
+)
+
+var checkrouteCommand = &discordgo.ApplicationCommand{
+	Name:        "checkroute",
+	Description: "See how your route does against the best one",
+	Options: append(generateOptions(), &discordgo.ApplicationCommandOption{
+		Type:        discordgo.ApplicationCommandOptionString,
+		Name:        "route",
+		Description: "2 or 3 boosts separated by commas like 2a cp 1-2, 3b cp 1-2",
+		Required:    true,
+	}),
+}
+
+func checkrouteHandler(s Session, i *discordgo.InteractionCreate) {
+	logUserInteraction(i, "command", "checkroute")
+
+	respond := func(content string) {
+		err := s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+			Type: discordgo.InteractionResponseChannelMessageWithSource,
+			Data: &discordgo.InteractionResponseData{
+				Content: content,
+				Flags:   discordgo.MessageFlagsEphemeral,
+			},
+		})
+		if err != nil {
+			log.Errorf("Failed to respond to checkroute command: %v", err)
+		}
+	}
+
+	selected := make([]string, 0, 8)
+	routeText := ""
+	for _, option := range i.ApplicationCommandData().Options {
+		switch {
+		case strings.HasPrefix(option.Name, "room_"):
+			selected = append(selected, option.StringValue())
+		case option.Name == "route":
+			routeText = option.StringValue()
+		}
+	}
+
+	if valid, err := validateInput(selected); !valid {
+		respond(err.Error())
+		return
+	}
+
+	route, err := parseRoute(routeText, selected)
+	if err != nil {
+		respond(err.Error())
+		return
+	}
+
+	evaluation, err := calc.EvaluateRoute(selected, route, calc.RoomMap)
+	if err != nil {
+		respond(fmt.Sprintf("That route doesn't work: %v.", err))
+		return
+	}
+
+	img, err := drawCalcResults(append([]string{}, selected...), []calc.CalcSeedResult{evaluation.Result})
+	if err != nil {
+		log.Errorf("Error drawing route: %v", err)
+		respond("Go tell the developer he's an idiot 'cause something's broken idk")
+		return
+	}
+
+	err = s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{
+			Content: formatRouteEvaluation(selected, evaluation),
+			Files: []*discordgo.File{
+				{
+					Name:   "route.png",
+					Reader: bytes.NewReader(img.Bytes()),
+				},
+			},
+		},
+	})
+	if err != nil {
+		log.Errorf("Failed to send route: %v", err)
+	}
+}
+
+// parseRoute reads boosts separated by commas, each a room of the seed
+// followed by the strat like "2a cp 1-2" or "finish room: lol"
+func parseRoute(text string, rooms []string) ([]calc.RouteBoost, error) {
+	rooms = append(append([]string{}, rooms...), "finish room")
+
+	var route []calc.RouteBoost
+	for _, entry := range strings.Split(text, ",") {
+		entry = strings.ToLower(strings.TrimSpace(entry))
+		if entry == "" {
+			continue
+		}
+
+		// The longest room that starts the entry, so "finish room" isn't taken for a room "f"
+		room := ""
+		for _, r := range rooms {
+			if strings.HasPrefix(entry, r) && len(r) > len(room) {
+				room = r
+			}
+		}
+		if room == "" {
+			return nil, fmt.Errorf("\"%s\" doesn't start with a room of the seed.", entry)
+		}
+
+		strat := strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(entry[len(room):]), ":"))
+		if strat == "" {
+			return nil, fmt.Errorf("You didn't say which strat you boost %s with.", room)
+		}
+		route = append(route, calc.RouteBoost{Room: room, Strat: strat})
+	}
+	return route, nil
+}
+
+// formatRouteEvaluation sums up how a route compares to the best one
+func formatRouteEvaluation(rooms []string, evaluation calc.RouteEvaluation) string {
+	if evaluation.Rank == 1 {
+		return fmt.Sprintf("That's the best route, %s. Go get it.", FormatTime(evaluation.Result.BoostTime))
+	}
+
+	rooms = append(append([]string{}, rooms...), "finish room")
+	best := make([]string, len(evaluation.Best.BoostRooms))
+	for k, boost := range evaluation.Best.BoostRooms {
+		room := calc.RoomMap[rooms[boost.Ind]]
+		best[k] = fmt.Sprintf("%s (%s)", room.Name, room.BoostStrats[boost.StratInd].Name)
+	}
+
+	return fmt.Sprintf("Your route takes %s, #%d of %d routes and %.1fs slower than the best: %s in %s.",
+		FormatTime(evaluation.Result.BoostTime), evaluation.Rank, evaluation.Routes, evaluation.Delta,
+		strings.Join(best, ", "), FormatTime(evaluation.Best.BoostTime))
+}
@@ -0,0 +1,148 @@
+package discord
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/bwmarrin/discordgo"
+	log "github.com/sirupsen/logrus"
+)
+
+// commandRegistry is the part of the Discord API used to register commands
+type commandRegistry interface {
+	ApplicationCommands(appID, guildID string, options ...discordgo.RequestOption) ([]*discordgo.ApplicationCommand, error)
+	ApplicationCommandBulkOverwrite(appID string, guildID string, commands []*discordgo.ApplicationCommand, options ...discordgo.RequestOption) ([]*discordgo.ApplicationCommand, error)
+}
+
+// commandDiff lists the names of the commands that differ between Discord and us
+type commandDiff struct {
+	Added   []string
+	Changed []string
+	Removed []string
+}
+
+func (d commandDiff) empty() bool {
+	return len(d.Added) == 0 && len(d.Changed) == 0 && len(d.Removed) == 0
+}
+
+// registerCommands makes the commands registered for the application match
+// wanted, globally or in a single guild when guildID is set. Discord is only
+// called to overwrite them when something changed. It returns the registered
+// commands.
+func registerCommands(registry commandRegistry, appID, guildID string, wanted []*discordgo.ApplicationCommand) ([]*discordgo.ApplicationCommand, error) {
+	scope := "globally"
+	if guildID != "" {
+		scope = "in guild " + guildID
+	}
+
+	existing, err := registry.ApplicationCommands(appID, guildID)
+	if err != nil {
+		return nil, fmt.Errorf("error listing commands: %w", err)
+	}
+
+	diff := diffCommands(existing, wanted)
+	if diff.empty() {
+		log.Infof("Commands %s are up to date", scope)
+		return existing, nil
+	}
+
+	registered, err := registry.ApplicationCommandBulkOverwrite(appID, guildID, wanted)
+	if err != nil {
+		return nil, fmt.Errorf("error overwriting commands: %w", err)
+	}
+
+	for _, change := range []struct {
+		verb  string
+		names []string
+	}{{"Added", diff.Added}, {"Changed", diff.Changed}, {"Removed", diff.Removed}} {
+		if len(change.names) > 0 {
+			log.Infof("%s commands %s: %s", change.verb, scope, strings.Join(change.names, ", "))
+		}
+	}
+
+	return registered, nil
+}
+
+// clearCommands removes the commands registered for the application globally,
+// or in a single guild when guildID is set, so a scope we stopped using
+// doesn't show every command twice
+func clearCommands(registry commandRegistry, appID, guildID string) error {
+	scope := "globally"
+	if guildID != "" {
+		scope = "in guild " + guildID
+	}
+
+	existing, err := registry.ApplicationCommands(appID, guildID)
+	if err != nil {
+		return fmt.Errorf("error listing commands: %w", err)
+	}
+	if len(existing) == 0 {
+		return nil
+	}
+
+	if _, err := registry.ApplicationCommandBulkOverwrite(appID, guildID, []*discordgo.ApplicationCommand{}); err != nil {
+		return fmt.Errorf("error removing commands: %w", err)
+	}
+	log.Infof("Removed %d commands %s", len(existing), scope)
+
+	return nil
+}
+
+// diffCommands compares the commands registered on Discord with the wanted ones by name
+func diffCommands(existing, wanted []*discordgo.ApplicationCommand) commandDiff {
+	var diff commandDiff
+
+	registered := make(map[string]*discordgo.ApplicationCommand, len(existing))
+	for _, cmd := range existing {
+		registered[cmd.Name] = cmd
+	}
+
+	for _, cmd := range wanted {
+		current, exists := registered[cmd.Name]
+		switch {
+		case !exists:
+			diff.Added = append(diff.Added, cmd.Name)
+		case commandSignature(current) != commandSignature(cmd):
+			diff.Changed = append(diff.Changed, cmd.Name)
+		}
+		delete(registered, cmd.Name)
+	}
+
+	for name := range registered {
+		diff.Removed = append(diff.Removed, name)
+	}
+	slices.Sort(diff.Removed)
+
+	return diff
+}
+
+// commandSignature returns the parts of a command a user can see, leaving
+// out the IDs and versions Discord assigns
+func commandSignature(cmd *discordgo.ApplicationCommand) string {
+	commandType := cmd.Type
+	if commandType == 0 {
+		commandType = discordgo.ChatApplicationCommand
+	}
+
+	var permissions int64
+	if cmd.DefaultMemberPermissions != nil {
+		permissions = *cmd.DefaultMemberPermissions
+	}
+
+	signature, err := json.Marshal(struct {
+		Name        string
+		Description string
+		Type        discordgo.ApplicationCommandType
+		Permissions int64
+		Options     []*discordgo.ApplicationCommandOption
+	}{cmd.Name, cmd.Description, commandType, permissions, cmd.Options})
+	if err != nil {
+		// Can't happen for commands that came from JSON, compare by name only then
+		log.Errorf("Failed to compare command %s: %v", cmd.Name, err)
+		return ""
+	}
+
+	return string(signature)
+}
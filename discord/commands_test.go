@@ -0,0 +1,171 @@
+package discord
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"testing"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// fakeRegistry keeps the commands of every scope, "" being the global one
+type fakeRegistry struct {
+	commands   map[string][]*discordgo.ApplicationCommand
+	overwrites int
+}
+
+func (r *fakeRegistry) ApplicationCommands(appID, guildID string, options ...discordgo.RequestOption) ([]*discordgo.ApplicationCommand, error) {
+	return r.commands[guildID], nil
+}
+
+func (r *fakeRegistry) ApplicationCommandBulkOverwrite(appID string, guildID string, commands []*discordgo.ApplicationCommand, options ...discordgo.RequestOption) ([]*discordgo.ApplicationCommand, error) {
+	r.overwrites++
+	r.commands[guildID] = commands
+	return commands, nil
+}
+
+func TestRegisterCommandsInGuildClearsGlobal(t *testing.T) {
+	wanted := []*discordgo.ApplicationCommand{{Name: "calc", Description: "Choose 8 rooms"}}
+	registry := &fakeRegistry{commands: map[string][]*discordgo.ApplicationCommand{"": wanted}}
+
+	if _, err := registerCommands(registry, "app", "guild", wanted); err != nil {
+		t.Fatal(err)
+	}
+	if err := clearCommands(registry, "app", ""); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(registry.commands["guild"]) != 1 || len(registry.commands[""]) != 0 {
+		t.Errorf("expected the commands in the guild only, got %+v", registry.commands)
+	}
+
+	// Nothing left to clear
+	overwrites := registry.overwrites
+	if err := clearCommands(registry, "app", ""); err != nil {
+		t.Fatal(err)
+	}
+	if registry.overwrites != overwrites {
+		t.Errorf("cleared an empty scope")
+	}
+}
+
+// registeredCopy returns the commands like Discord lists them once they are
+// registered, with the IDs, version and type it fills in
+func registeredCopy(t *testing.T, wanted []*discordgo.ApplicationCommand) []*discordgo.ApplicationCommand {
+	t.Helper()
+
+	data, err := json.Marshal(wanted)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var registered []*discordgo.ApplicationCommand
+	if err := json.Unmarshal(data, &registered); err != nil {
+		t.Fatal(err)
+	}
+	for k, cmd := range registered {
+		cmd.ID = fmt.Sprint(1000 + k)
+		cmd.ApplicationID = "app"
+		cmd.Version = fmt.Sprint(2000 + k)
+		cmd.Type = discordgo.ChatApplicationCommand
+	}
+	return registered
+}
+
+func TestDiffCommands(t *testing.T) {
+	permissions := int64(discordgo.PermissionManageServer)
+	wanted := []*discordgo.ApplicationCommand{
+		{Name: "calc", Description: "Choose 8 rooms", Options: []*discordgo.ApplicationCommandOption{
+			{Type: discordgo.ApplicationCommandOptionString, Name: "room_1", Description: "First room", Required: true},
+		}},
+		{Name: "config", Description: "Set the bot up", DefaultMemberPermissions: &permissions},
+	}
+
+	tests := []struct {
+		name     string
+		existing func([]*discordgo.ApplicationCommand) []*discordgo.ApplicationCommand
+		diff     commandDiff
+	}{
+		{
+			name:     "up to date",
+			existing: func(cmds []*discordgo.ApplicationCommand) []*discordgo.ApplicationCommand { return cmds },
+		},
+		{
+			name:     "nothing registered",
+			existing: func([]*discordgo.ApplicationCommand) []*discordgo.ApplicationCommand { return nil },
+			diff:     commandDiff{Added: []string{"calc", "config"}},
+		},
+		{
+			name: "description changed",
+			existing: func(cmds []*discordgo.ApplicationCommand) []*discordgo.ApplicationCommand {
+				cmds[0].Description = "Choose 7 rooms"
+				return cmds
+			},
+			diff: commandDiff{Changed: []string{"calc"}},
+		},
+		{
+			name: "option changed",
+			existing: func(cmds []*discordgo.ApplicationCommand) []*discordgo.ApplicationCommand {
+				cmds[0].Options[0].Required = false
+				return cmds
+			},
+			diff: commandDiff{Changed: []string{"calc"}},
+		},
+		{
+			name: "permissions changed",
+			existing: func(cmds []*discordgo.ApplicationCommand) []*discordgo.ApplicationCommand {
+				cmds[1].DefaultMemberPermissions = nil
+				return cmds
+			},
+			diff: commandDiff{Changed: []string{"config"}},
+		},
+		{
+			name: "type left out",
+			existing: func(cmds []*discordgo.ApplicationCommand) []*discordgo.ApplicationCommand {
+				cmds[0].Type = 0
+				return cmds
+			},
+		},
+		{
+			name: "old commands",
+			existing: func(cmds []*discordgo.ApplicationCommand) []*discordgo.ApplicationCommand {
+				return append(cmds, &discordgo.ApplicationCommand{Name: "split"}, &discordgo.ApplicationCommand{Name: "boost"})
+			},
+			diff: commandDiff{Removed: []string{"boost", "split"}},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			diff := diffCommands(test.existing(registeredCopy(t, wanted)), wanted)
+			if !slices.Equal(diff.Added, test.diff.Added) || !slices.Equal(diff.Changed, test.diff.Changed) || !slices.Equal(diff.Removed, test.diff.Removed) {
+				t.Errorf("got %+v, want %+v", diff, test.diff)
+			}
+			if diff.empty() != (len(test.diff.Added)+len(test.diff.Changed)+len(test.diff.Removed) == 0) {
+				t.Errorf("empty() is %v for %+v", diff.empty(), diff)
+			}
+		})
+	}
+}
+
+func TestRegisterCommandsOverwritesOnlyChanges(t *testing.T) {
+	registry := &fakeRegistry{commands: map[string][]*discordgo.ApplicationCommand{"": registeredCopy(t, commands)}}
+
+	// The commands of the bot come back from Discord unchanged
+	if _, err := registerCommands(registry, "app", "", commands); err != nil {
+		t.Fatal(err)
+	}
+	if registry.overwrites != 0 {
+		t.Fatalf("overwrote commands that are up to date")
+	}
+
+	changed := registeredCopy(t, commands)
+	changed[0].Description += "!"
+	registry.commands[""] = changed
+	if _, err := registerCommands(registry, "app", "", commands); err != nil {
+		t.Fatal(err)
+	}
+	if registry.overwrites != 1 || registry.commands[""][0].Description != commands[0].Description {
+		t.Errorf("expected one overwrite with the wanted commands, got %d", registry.overwrites)
+	}
+}
@@ -0,0 +1,422 @@
+package discord
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"math"
+	"math/rand/v2"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"atlantis_calc/calc"
+
+	"github.com/bwmarrin/discordgo"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	dailyFile = "daily.json"
+	// dailyDateFormat is the UTC date a daily seed belongs to, also its key
+	dailyDateFormat = "2006-01-02"
+	// dailyKept is how many days of daily seeds are kept
+	dailyKept = 7
+	// dailyResultsShown is how many players the summary lists, to fit in an embed field
+	dailyResultsShown = 20
+)
+
+// DailySubmission is the best time a player got on a daily seed
+type DailySubmission struct {
+	Name string  `json:"name"`
+	Time float64 `json:"time"`
+}
+
+// DailyChallenge is the seed everyone plays on a day
+type DailyChallenge struct {
+	Date        string                      `json:"date"`
+	Rooms       []string                    `json:"rooms"`
+	Optimal     float64                     `json:"optimal"`
+	Submissions map[string]*DailySubmission `json:"submissions,omitempty"`
+	// Posted and Summarized are set once the seed and its summary went out
+	Posted     bool `json:"posted,omitempty"`
+	Summarized bool `json:"summarized,omitempty"`
+}
+
+// DailyResult is a player's time on a daily seed
+type DailyResult struct {
+	UserID string
+	Name   string
+	Time   float64
+}
+
+// Results ranks the submissions from the fastest, then by name
+func (c *DailyChallenge) Results() []DailyResult {
+	results := make([]DailyResult, 0, len(c.Submissions))
+	for userID, submission := range c.Submissions {
+		results = append(results, DailyResult{UserID: userID, Name: submission.Name, Time: submission.Time})
+	}
+
+	slices.SortFunc(results, func(a, b DailyResult) int {
+		if c := compareFloat(a.Time, b.Time); c != 0 {
+			return c
+		}
+		return strings.Compare(a.Name, b.Name)
+	})
+	return results
+}
+
+// clone copies the challenge so changing the copy leaves c alone
+func (c *DailyChallenge) clone() *DailyChallenge {
+	copied := *c
+	copied.Rooms = slices.Clone(c.Rooms)
+	copied.Submissions = make(map[string]*DailySubmission, len(c.Submissions))
+	for userID, submission := range c.Submissions {
+		s := *submission
+		copied.Submissions[userID] = &s
+	}
+	return &copied
+}
+
+// dailyDate returns the key of the daily seed of the day t is in
+func dailyDate(t time.Time) string {
+	return t.UTC().Format(dailyDateFormat)
+}
+
+// dailyRooms draws the rooms of the daily seed of a date, the same ones for
+// everyone
+func dailyRooms(date string) ([]string, error) {
+	day, err := time.Parse(dailyDateFormat, date)
+	if err != nil {
+		return nil, err
+	}
+	return calc.DrawSeed(rand.New(rand.NewPCG(uint64(day.Unix()), 0)), calc.RoomMap)
+}
+
+// newDailyChallenge draws the daily seed of a date and calcs it
+func newDailyChallenge(date string) (*DailyChallenge, error) {
+	rooms, err := dailyRooms(date)
+	if err != nil {
+		return nil, fmt.Errorf("error drawing daily seed of %s: %w", date, err)
+	}
+
+	results, err := calc.CalcSeed(slices.Clone(rooms))
+	if err != nil {
+		return nil, fmt.Errorf("error calcing daily seed of %s: %w", date, err)
+	}
+	return &DailyChallenge{Date: date, Rooms: rooms, Optimal: results[0].BoostTime}, nil
+}
+
+// DailyStore keeps the daily seeds of the last days on disk
+type DailyStore struct {
+	mutex      sync.RWMutex
+	challenges map[string]*DailyChallenge
+}
+
+// NewDailyStore loads the stored daily seeds
+func NewDailyStore() (*DailyStore, error) {
+	store := &DailyStore{challenges: make(map[string]*DailyChallenge)}
+	if err := loadJSON(dailyFile, &store.challenges); err != nil {
+		return nil, err
+	}
+	return store, nil
+}
+
+// Get returns a copy of the daily seed of a date, ok is false if it wasn't drawn yet
+func (ds *DailyStore) Get(date string) (*DailyChallenge, bool) {
+	ds.mutex.RLock()
+	defer ds.mutex.RUnlock()
+
+	challenge, exists := ds.challenges[date]
+	if !exists {
+		return nil, false
+	}
+	return challenge.clone(), true
+}
+
+// Update changes the daily seed of a date, drawing it first if needed, and
+// persists it. update may return an error to change nothing.
+func (ds *DailyStore) Update(date string, update func(c *DailyChallenge) error) (*DailyChallenge, error) {
+	ds.mutex.Lock()
+	defer ds.mutex.Unlock()
+
+	var challenge *DailyChallenge
+	if current, exists := ds.challenges[date]; exists {
+		challenge = current.clone()
+	} else {
+		drawn, err := newDailyChallenge(date)
+		if err != nil {
+			return nil, err
+		}
+		challenge = drawn
+	}
+
+	if err := update(challenge); err != nil {
+		return nil, err
+	}
+
+	// Only kept once saved, so a failed save leaves the store as it was
+	stored := maps.Clone(ds.challenges)
+	stored[date] = challenge
+	pruneDailies(stored)
+	if err := saveJSON(dailyFile, stored); err != nil {
+		return nil, fmt.Errorf("error saving daily seeds: %w", err)
+	}
+	ds.challenges = stored
+	return challenge.clone(), nil
+}
+
+// pruneDailies drops all but the latest dailyKept days
+func pruneDailies(challenges map[string]*DailyChallenge) {
+	dates := make([]string, 0, len(challenges))
+	for date := range challenges {
+		dates = append(dates, date)
+	}
+	slices.Sort(dates)
+	for _, date := range dates[:max(0, len(dates)-dailyKept)] {
+		delete(challenges, date)
+	}
+}
+
+var dailies *DailyStore
+
+var dailyCommand = &discordgo.ApplicationCommand{
+	Name:        "daily",
+	Description: "Play the seed of the day against everyone",
+	Options: []*discordgo.ApplicationCommandOption{
+		{
+			Type:        discordgo.ApplicationCommandOptionSubCommand,
+			Name:        "seed",
+			Description: "Show the seed of the day",
+		},
+		{
+			Type:        discordgo.ApplicationCommandOptionSubCommand,
+			Name:        "submit",
+			Description: "Submit your time on the seed of the day, your best one counts",
+			Options: []*discordgo.ApplicationCommandOption{
+				{
+					Type:        discordgo.ApplicationCommandOptionString,
+					Name:        "time",
+					Description: "Your time like 2:14.3",
+					Required:    true,
+				},
+			},
+		},
+	},
+}
+
+func dailyHandler(s Session, i *discordgo.InteractionCreate) {
+	logUserInteraction(i, "command", "daily")
+
+	respond := func(content string) {
+		err := s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+			Type: discordgo.InteractionResponseChannelMessageWithSource,
+			Data: &discordgo.InteractionResponseData{
+				Content: content,
+				Flags:   discordgo.MessageFlagsEphemeral,
+			},
+		})
+		if err != nil {
+			log.Errorf("Failed to respond to daily command: %v", err)
+		}
+	}
+
+	options := i.ApplicationCommandData().Options
+	if len(options) == 0 {
+		respond("You sent an incomplete command.")
+		return
+	}
+
+	date := dailyDate(time.Now())
+	userID := interactionUserID(i)
+
+	switch options[0].Name {
+	case "seed":
+		challenge, err := dailies.Update(date, func(*DailyChallenge) error { return nil })
+		if err != nil {
+			log.Errorf("Failed to get daily seed: %v", err)
+			respond("Go tell the developer he's an idiot 'cause something's broken idk")
+			return
+		}
+
+		content := ""
+		if submission, exists := challenge.Submissions[userID]; exists {
+			content = fmt.Sprintf("Your best so far is %s.", FormatTime(submission.Time))
+		}
+		err = s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+			Type: discordgo.InteractionResponseChannelMessageWithSource,
+			Data: &discordgo.InteractionResponseData{
+				Content: content,
+				Embeds:  []*discordgo.MessageEmbed{createDailyEmbed(challenge)},
+			},
+		})
+		if err != nil {
+			log.Errorf("Failed to show daily seed: %v", err)
+		}
+
+	case "submit":
+		var submitted float64
+		for _, opt := range options[0].Options {
+			if opt.Name == "time" {
+				t, err := ParseTime(opt.StringValue())
+				if err != nil {
+					respond(err.Error())
+					return
+				}
+				submitted = t
+			}
+		}
+		if submitted <= 0 || math.IsInf(submitted, 0) || math.IsNaN(submitted) {
+			respond("Your time has to be more than 0 seconds.")
+			return
+		}
+
+		errNotImproved := errors.New("not improved")
+		challenge, err := dailies.Update(date, func(c *DailyChallenge) error {
+			if best, exists := c.Submissions[userID]; exists && best.Time <= submitted {
+				return errNotImproved
+			}
+			if c.Submissions == nil {
+				c.Submissions = make(map[string]*DailySubmission)
+			}
+			c.Submissions[userID] = &DailySubmission{Name: duelistName(i, userID), Time: submitted}
+			return nil
+		})
+		if errors.Is(err, errNotImproved) {
+			challenge, _ := dailies.Get(date)
+			respond(fmt.Sprintf("You already have %s on today's seed, that one counts.",
+				FormatTime(challenge.Submissions[userID].Time)))
+			return
+		}
+		if err != nil {
+			log.Errorf("Failed to save daily submission of user %s: %v", userID, err)
+			respond("I couldn't save your time, go tell the developer.")
+			return
+		}
+
+		// The calc time stays a spoiler until the summary
+		respond(fmt.Sprintf("Submitted %s on today's seed, ||%s the calc's %s||. See how everyone did tomorrow.",
+			FormatTime(submitted), formatBehind(submitted-challenge.Optimal), FormatTime(challenge.Optimal)))
+
+	default:
+		respond(fmt.Sprintf("Unknown subcommand \"%s\".", options[0].Name))
+	}
+}
+
+// createDailyEmbed shows the daily seed with the calc time behind a spoiler
+func createDailyEmbed(challenge *DailyChallenge) *discordgo.MessageEmbed {
+	return &discordgo.MessageEmbed{
+		Title:       fmt.Sprintf("Daily seed of %s", challenge.Date),
+		Description: strings.Join(challenge.Rooms, ", "),
+		Color:       0x45D3B3,
+		Fields: []*discordgo.MessageEmbedField{
+			{Name: "Calc time", Value: fmt.Sprintf("||%s||", FormatTime(challenge.Optimal))},
+		},
+		Footer: &discordgo.MessageEmbedFooter{Text: "Submit your time with /daily submit, results tomorrow"},
+	}
+}
+
+// createDailySummaryEmbed shows how close everyone got to the calc on a daily seed
+func createDailySummaryEmbed(challenge *DailyChallenge) *discordgo.MessageEmbed {
+	var results strings.Builder
+	ranked := challenge.Results()
+	if len(ranked) == 0 {
+		results.WriteString("Nobody played it. Tough crowd.")
+	} else {
+		results.WriteString("```\n")
+		for k, result := range ranked[:min(len(ranked), dailyResultsShown)] {
+			results.WriteString(fmt.Sprintf("%2d. %-16s %7s %+6.1fs\n",
+				k+1, shortName(result.Name), FormatTime(result.Time), result.Time-challenge.Optimal))
+		}
+		results.WriteString("```")
+	}
+
+	return &discordgo.MessageEmbed{
+		Title:       fmt.Sprintf("Results of the daily seed of %s", challenge.Date),
+		Description: strings.Join(challenge.Rooms, ", "),
+		Color:       0x45D3B3,
+		Fields: []*discordgo.MessageEmbedField{
+			{Name: "Calc time", Value: FormatTime(challenge.Optimal)},
+			{Name: "Results", Value: results.String()},
+		},
+		Footer: &discordgo.MessageEmbedFooter{Text: "Time lost to the calc"},
+	}
+}
+
+// dailyGuilds returns the guilds that opted in to the daily seed
+func dailyGuilds() []string {
+	var guildIDs []string
+	for _, guildID := range s.GuildIDs() {
+		if guildConfigs.Get(guildID).Enabled(FeatureDaily) {
+			guildIDs = append(guildIDs, guildID)
+		}
+	}
+	return guildIDs
+}
+
+// postDaily sends an embed to the announcement channel of every guild that
+// opted in to the daily seed. A failure in one guild is logged and doesn't
+// stop the others.
+func postDaily(embed *discordgo.MessageEmbed) {
+	for _, guildID := range dailyGuilds() {
+		channelID := announcementChannelID(guildID)
+		if channelID == "" {
+			log.Errorf("Failed to post daily seed in guild %s: could not find an announcement channel", guildID)
+			continue
+		}
+		if err := checkBotPermissions(channelID); err != nil {
+			log.Errorf("Failed to post daily seed in guild %s: permission error: %v", guildID, err)
+			continue
+		}
+
+		_, err := s.ChannelMessageSendComplex(channelID, &discordgo.MessageSend{
+			Embeds: []*discordgo.MessageEmbed{embed},
+		})
+		if err != nil {
+			log.Errorf("Failed to post daily seed in guild %s: %v", guildID, err)
+		}
+	}
+}
+
+// postDailyChallenge posts the summary of yesterday's seed and today's seed
+// unless they already went out
+func postDailyChallenge(now time.Time) {
+	yesterday := dailyDate(now.AddDate(0, 0, -1))
+	if challenge, exists := dailies.Get(yesterday); exists && challenge.Posted && !challenge.Summarized {
+		postDaily(createDailySummaryEmbed(challenge))
+		if _, err := dailies.Update(yesterday, func(c *DailyChallenge) error { c.Summarized = true; return nil }); err != nil {
+			log.Errorf("Failed to save daily summary: %v", err)
+		}
+	}
+
+	challenge, err := dailies.Update(dailyDate(now), func(*DailyChallenge) error { return nil })
+	if err != nil {
+		log.Errorf("Failed to draw daily seed: %v", err)
+		return
+	}
+	if challenge.Posted {
+		return
+	}
+	postDaily(createDailyEmbed(challenge))
+	if _, err := dailies.Update(challenge.Date, func(c *DailyChallenge) error { c.Posted = true; return nil }); err != nil {
+		log.Errorf("Failed to save daily seed: %v", err)
+	}
+}
+
+// runDailyChallenges posts the daily seed every day at midnight UTC, and
+// right away if today's wasn't posted yet, until ctx is cancelled
+func runDailyChallenges(ctx context.Context) {
+	for {
+		now := time.Now()
+		postDailyChallenge(now)
+
+		midnight := now.UTC().Truncate(24 * time.Hour).Add(24 * time.Hour)
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(midnight.Sub(now)):
+		}
+	}
+}
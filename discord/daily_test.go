@@ -0,0 +1,135 @@
+package discord
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+func TestDailyRooms(t *testing.T) {
+	first, err := dailyRooms("2026-01-01")
+	if err != nil {
+		t.Fatal(err)
+	}
+	again, err := dailyRooms("2026-01-01")
+	if err != nil {
+		t.Fatal(err)
+	}
+	next, err := dailyRooms("2026-01-02")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(first) != 8 || !slices.Equal(first, again) {
+		t.Errorf("the same day drew %v and %v", first, again)
+	}
+	if slices.Equal(first, next) {
+		t.Errorf("two days drew the same rooms %v", first)
+	}
+	if _, err := dailyRooms("yesterday"); err == nil {
+		t.Error("drew rooms for a date that isn't one")
+	}
+}
+
+func TestDailyResults(t *testing.T) {
+	challenge := &DailyChallenge{Submissions: map[string]*DailySubmission{
+		"c": {Name: "carol", Time: 130},
+		"b": {Name: "bob", Time: 125},
+		"a": {Name: "alice", Time: 130},
+	}}
+
+	var names []string
+	for _, result := range challenge.Results() {
+		names = append(names, result.Name)
+	}
+	if want := []string{"bob", "alice", "carol"}; !slices.Equal(names, want) {
+		t.Errorf("got %v, want %v", names, want)
+	}
+}
+
+func TestDailyPostScenario(t *testing.T) {
+	fs := setupScenario(t, "playing", "not-playing")
+	fs.Channels = []*discordgo.Channel{
+		{ID: "playing-channel", GuildID: "playing", Name: defaultAnnouncementChannel, Type: discordgo.ChannelTypeGuildText},
+		{ID: "not-playing-channel", GuildID: "not-playing", Name: defaultAnnouncementChannel, Type: discordgo.ChannelTypeGuildText},
+	}
+	if _, err := guildConfigs.Update("playing", func(c *GuildConfig) { c.Features = map[string]bool{FeatureDaily: true} }); err != nil {
+		t.Fatal(err)
+	}
+
+	embedTitles := func() []string {
+		var titles []string
+		for _, call := range fs.Calls("ChannelMessageSendComplex") {
+			if call.ChannelID != "playing-channel" {
+				t.Errorf("posted in %s", call.ChannelID)
+			}
+			titles = append(titles, call.Send.Embeds[0].Title)
+		}
+		fs.Reset()
+		return titles
+	}
+
+	today := time.Date(2026, 1, 1, 0, 0, 5, 0, time.UTC)
+	postDailyChallenge(today)
+	if titles := embedTitles(); !slices.Equal(titles, []string{"Daily seed of 2026-01-01"}) {
+		t.Errorf("first run posted %v", titles)
+	}
+
+	// A restart on the same day posts nothing again
+	postDailyChallenge(today.Add(time.Hour))
+	if titles := embedTitles(); len(titles) != 0 {
+		t.Errorf("second run posted %v", titles)
+	}
+
+	if _, err := dailies.Update("2026-01-01", func(c *DailyChallenge) error {
+		c.Submissions = map[string]*DailySubmission{"user": {Name: "player", Time: 130}}
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	postDailyChallenge(today.AddDate(0, 0, 1))
+	want := []string{"Results of the daily seed of 2026-01-01", "Daily seed of 2026-01-02"}
+	if titles := embedTitles(); !slices.Equal(titles, want) {
+		t.Errorf("next day posted %v, want %v", titles, want)
+	}
+	if challenge, _ := dailies.Get("2026-01-01"); !challenge.Summarized {
+		t.Error("yesterday's seed wasn't marked summarized")
+	}
+
+	postDailyChallenge(today.AddDate(0, 0, 1).Add(time.Hour))
+	if titles := embedTitles(); len(titles) != 0 {
+		t.Errorf("the summary or seed went out twice: %v", titles)
+	}
+}
+
+func TestDailyUpdateKeepsFailedSaveOut(t *testing.T) {
+	useDataDir(t)
+	store, err := NewDailyStore()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.Update("2026-01-01", func(*DailyChallenge) error { return nil }); err != nil {
+		t.Fatal(err)
+	}
+
+	breakDataDir(t)
+	_, err = store.Update("2026-01-01", func(c *DailyChallenge) error {
+		c.Posted = true
+		return nil
+	})
+	if err == nil {
+		t.Fatal("expected the save to fail")
+	}
+	if challenge, _ := store.Get("2026-01-01"); challenge.Posted {
+		t.Error("the failed update was kept")
+	}
+	if _, err := store.Update("2026-01-02", func(*DailyChallenge) error { return nil }); err == nil {
+		t.Fatal("expected the save to fail")
+	}
+	if _, exists := store.Get("2026-01-02"); exists {
+		t.Error("the failed draw was kept")
+	}
+}
@@ -0,0 +1,273 @@
+package discord
+
+import (
+	"bytes"
+	"fmt"
+	"image/color"
+	"math"
+	"strings"
+
+	"atlantis_calc/calc"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/fogleman/gg"
+	log "github.com/sirupsen/logrus"
+)
+
+var (
+	aheadColor  = color.RGBA{155, 199, 0, 255}
+	behindColor = color.RGBA{230, 80, 80, 255}
+)
+
+var duelCommand = &discordgo.ApplicationCommand{
+	Name:        "duel",
+	Description: "Predict a duel between two players with their own splits",
+	Options: append(generateOptions(),
+		&discordgo.ApplicationCommandOption{
+			Type:        discordgo.ApplicationCommandOptionUser,
+			Name:        "opponent",
+			Description: "Who you duel",
+			Required:    true,
+		},
+		&discordgo.ApplicationCommandOption{
+			Type:        discordgo.ApplicationCommandOptionUser,
+			Name:        "player",
+			Description: "Duel someone else instead of you",
+		},
+	),
+}
+
+// duelist is a player in a /duel with their merged splits
+type duelist struct {
+	ID     string
+	Name   string
+	Splits map[string]calc.Room
+}
+
+func duelHandler(s Session, i *discordgo.InteractionCreate) {
+	logUserInteraction(i, "command", "duel")
+
+	respond := func(content string) {
+		err := s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+			Type: discordgo.InteractionResponseChannelMessageWithSource,
+			Data: &discordgo.InteractionResponseData{
+				Content:         content,
+				Flags:           discordgo.MessageFlagsEphemeral,
+				AllowedMentions: &discordgo.MessageAllowedMentions{},
+			},
+		})
+		if err != nil {
+			log.Errorf("Failed to respond to duel command: %v", err)
+		}
+	}
+
+	data := i.ApplicationCommandData()
+	selected := make([]string, 0, 8)
+	playerID := interactionUserID(i)
+	opponentID := ""
+	for _, option := range data.Options {
+		switch {
+		case strings.HasPrefix(option.Name, "room_"):
+			selected = append(selected, option.StringValue())
+		case option.Name == "player":
+			playerID, _ = option.Value.(string)
+		case option.Name == "opponent":
+			opponentID, _ = option.Value.(string)
+		}
+	}
+
+	if valid, err := validateInput(selected); !valid {
+		respond(err.Error())
+		return
+	}
+	if opponentID == "" || opponentID == playerID {
+		respond("A duel needs two different players, try someone who isn't you.")
+		return
+	}
+
+	players := make([]duelist, 0, 2)
+	for _, userID := range []string{playerID, opponentID} {
+		personal, exists := personalSplits.Get(userID)
+		if !exists {
+			respond(fmt.Sprintf("<@%s> didn't save any splits yet, they can do that with `/mysplits`.", userID))
+			return
+		}
+		splits, err := personal.Merged()
+		if err != nil {
+			respond(fmt.Sprintf("The splits of <@%s> don't fit the calc splits anymore, they can fix them with `/mysplits`.", userID))
+			return
+		}
+		players = append(players, duelist{ID: userID, Name: duelistName(i, userID), Splits: splits})
+	}
+
+	result, err := calc.Duel(selected, players[0].Splits, players[1].Splits)
+	if err != nil {
+		log.Errorf("Failed to calc duel: %v", err)
+		respond("Go tell the developer he's an idiot 'cause something's broken idk")
+		return
+	}
+
+	img, err := drawDuelResult(result, players[0], players[1])
+	if err != nil {
+		log.Errorf("Failed to draw duel: %v", err)
+		respond("Go tell the developer he's an idiot 'cause something's broken idk")
+		return
+	}
+
+	err = s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{
+			Content: fmt.Sprintf("<@%s> vs <@%s>: %s", players[0].ID, players[1].ID, duelVerdict(result, players[0], players[1])),
+			Files: []*discordgo.File{
+				{
+					Name:   "duel.png",
+					Reader: bytes.NewReader(img.Bytes()),
+				},
+			},
+			AllowedMentions: &discordgo.MessageAllowedMentions{},
+		},
+	})
+	if err != nil {
+		log.Errorf("Failed to send duel: %v", err)
+	}
+}
+
+// duelistName returns the name of a user in the command, from the resolved
+// users or the member who ran it
+func duelistName(i *discordgo.InteractionCreate, userID string) string {
+	if resolved := i.ApplicationCommandData().Resolved; resolved != nil {
+		if user, exists := resolved.Users[userID]; exists {
+			return user.Username
+		}
+	}
+	if i.Member != nil && i.Member.User != nil && i.Member.User.ID == userID {
+		return i.Member.User.Username
+	}
+	if i.User != nil && i.User.ID == userID {
+		return i.User.Username
+	}
+	return userID
+}
+
+// duelVerdict says who is favoured and how likely they are to win
+func duelVerdict(result calc.DuelResult, a, b duelist) string {
+	winner, chance := a, result.WinProbability
+	if chance < 0.5 {
+		winner, chance = b, 1-chance
+	}
+
+	if math.Round(chance*100) <= 50 {
+		return "it's a coin flip"
+	}
+	return fmt.Sprintf("%s wins %.0f%% of the time", winner.Name, chance*100)
+}
+
+// drawDuelResult renders the routes of both players side by side with the
+// running delta between them
+func drawDuelResult(result calc.DuelResult, a, b duelist) (bytes.Buffer, error) {
+	const (
+		rowHeight  = 40.0
+		cellHeight = 30.0
+		roomX      = 90.0
+		aX         = 315.0
+		deltaX     = 525.0
+		bX         = 735.0
+		roomWidth  = 150.0
+		routeWidth = 260.0
+		deltaWidth = 120.0
+	)
+
+	width := 880
+	height := 130 + int(rowHeight)*len(result.Rooms) + 90
+
+	dc := gg.NewContext(width, height)
+	if err := drawBackground(dc); err != nil {
+		log.Warn(err)
+		return bytes.Buffer{}, err
+	}
+
+	if err := dc.LoadFontFace(assetPath("font/minecraft_font.ttf"), 24); err != nil {
+		log.Warn(err)
+		return bytes.Buffer{}, err
+	}
+
+	dc.SetColor(color.White)
+	dc.DrawStringAnchored(a.Name, aX, 40, 0.5, 0.5)
+	dc.DrawStringAnchored("vs", deltaX, 40, 0.5, 0.5)
+	dc.DrawStringAnchored(b.Name, bX, 40, 0.5, 0.5)
+
+	if err := dc.LoadFontFace(assetPath("font/minecraft_font.ttf"), 18); err != nil {
+		log.Warn(err)
+		return bytes.Buffer{}, err
+	}
+
+	dc.DrawStringAnchored("Room", roomX, 85, 0.5, 0.5)
+	dc.DrawStringAnchored("Delta", deltaX, 85, 0.5, 0.5)
+
+	drawCell := func(x, y, cellWidth float64, fill color.Color, text string, textColor color.Color) {
+		dc.SetColor(fill)
+		dc.DrawRoundedRectangle(x-cellWidth/2, y-cellHeight/2, cellWidth, cellHeight, 10)
+		dc.Fill()
+
+		dc.SetColor(textColor)
+		dc.DrawStringAnchored(text, x, y, 0.5, 0.5)
+	}
+
+	// Route of one player: the strat and time of boosted rooms, the time of the rest
+	routeCells := func(player calc.DuelPlayer, splits map[string]calc.Room) ([]string, []color.Color) {
+		texts := make([]string, len(result.Rooms))
+		fills := make([]color.Color, len(result.Rooms))
+		for k, t := range player.RoomTimes {
+			texts[k] = FormatTime(t)
+			fills[k] = color.RGBA{0, 0, 0, 128}
+		}
+		for _, boost := range player.Result.BoostRooms {
+			strat := splits[result.Rooms[boost.Ind]].BoostStrats[boost.StratInd]
+			texts[boost.Ind] = fmt.Sprintf("%s %s", strat.Name, texts[boost.Ind])
+			fills[boost.Ind] = moveQualityColor(stratQuality(result.Rooms[boost.Ind], boost.StratInd))
+		}
+		return texts, fills
+	}
+	aTexts, aFills := routeCells(result.A, a.Splits)
+	bTexts, bFills := routeCells(result.B, b.Splits)
+
+	y := 130.0
+	for k, room := range result.Rooms {
+		drawCell(roomX, y, roomWidth, color.RGBA{0, 0, 0, 128}, strings.ToUpper(room[:1])+room[1:], color.White)
+		drawCell(aX, y, routeWidth, aFills[k], aTexts[k], color.White)
+		drawCell(bX, y, routeWidth, bFills[k], bTexts[k], color.White)
+
+		// The delta is shown from the first player's side
+		delta := result.Delta[k]
+		deltaColor := color.Color(color.White)
+		switch {
+		case delta < -0.05:
+			deltaColor = aheadColor
+		case delta > 0.05:
+			deltaColor = behindColor
+		}
+		drawCell(deltaX, y, deltaWidth, color.RGBA{0, 0, 0, 128}, fmt.Sprintf("%+.1f", delta), deltaColor)
+
+		y += rowHeight
+	}
+
+	if err := dc.LoadFontFace(assetPath("font/minecraft_font.ttf"), 24); err != nil {
+		log.Warn(err)
+		return bytes.Buffer{}, err
+	}
+
+	y += 10
+	dc.SetColor(color.White)
+	dc.DrawStringAnchored(FormatTime(result.A.Result.BoostTime), aX, y, 0.5, 0.5)
+	dc.DrawStringAnchored(FormatTime(result.B.Result.BoostTime), bX, y, 0.5, 0.5)
+	dc.DrawStringAnchored("Total", deltaX, y, 0.5, 0.5)
+
+	y += 40
+	dc.SetColor(chartLineColor)
+	verdict := duelVerdict(result, a, b)
+	dc.DrawStringAnchored(strings.ToUpper(verdict[:1])+verdict[1:], float64(width)/2, y, 0.5, 0.5)
+
+	var buf bytes.Buffer
+	dc.EncodePNG(&buf)
+	return buf, nil
+}
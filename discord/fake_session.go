@@ -0,0 +1,395 @@
+package discord
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strconv"
+	"sync"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// FakeCall is a call made on a FakeSession. Only the fields that make sense
+// for the method are set.
+type FakeCall struct {
+	Method      string
+	ChannelID   string
+	MessageID   string
+	Response    *discordgo.InteractionResponse
+	WebhookEdit *discordgo.WebhookEdit
+	Send        *discordgo.MessageSend
+	Edit        *discordgo.MessageEdit
+}
+
+// FakeSession is a Session that keeps messages in memory and records every
+// call, for running handler scenarios without connecting to Discord
+type FakeSession struct {
+	mutex sync.Mutex
+
+	User   *discordgo.User
+	Guilds []string
+	// Channels are looked up by ID and listed per guild
+	Channels []*discordgo.Channel
+	// Permissions of the bot per channel ID. Channels without an entry get
+	// every permission.
+	Permissions map[string]int64
+
+	// Messages holds every message sent or edited, by message ID
+	Messages map[string]*discordgo.Message
+	calls    []FakeCall
+	nextID   int
+
+	// files holds the data of every attachment by URL path. server serves
+	// them so handlers can download attachments like from Discord.
+	files  map[string][]byte
+	server *httptest.Server
+}
+
+// NewFakeSession creates a fake session for a bot in the given guilds
+func NewFakeSession(guildIDs ...string) *FakeSession {
+	return &FakeSession{
+		User:        &discordgo.User{ID: "bot", Username: "atlantis_calc", Bot: true},
+		Guilds:      guildIDs,
+		Permissions: make(map[string]int64),
+		Messages:    make(map[string]*discordgo.Message),
+		files:       make(map[string][]byte),
+	}
+}
+
+// Close stops serving the attachments of the messages
+func (fs *FakeSession) Close() {
+	fs.mutex.Lock()
+	defer fs.mutex.Unlock()
+
+	if fs.server != nil {
+		fs.server.Close()
+		fs.server = nil
+	}
+}
+
+// File returns the data of an attachment
+func (fs *FakeSession) File(attachment *discordgo.MessageAttachment) ([]byte, bool) {
+	fs.mutex.Lock()
+	defer fs.mutex.Unlock()
+
+	for path, data := range fs.files {
+		if fs.server != nil && fs.server.URL+path == attachment.URL {
+			return data, true
+		}
+	}
+	return nil, false
+}
+
+// Calls returns the recorded calls, only those of the given methods if any are given
+func (fs *FakeSession) Calls(methods ...string) []FakeCall {
+	fs.mutex.Lock()
+	defer fs.mutex.Unlock()
+
+	var calls []FakeCall
+	for _, call := range fs.calls {
+		if len(methods) == 0 || slices.Contains(methods, call.Method) {
+			calls = append(calls, call)
+		}
+	}
+	return calls
+}
+
+// Reset forgets the recorded calls
+func (fs *FakeSession) Reset() {
+	fs.mutex.Lock()
+	defer fs.mutex.Unlock()
+
+	fs.calls = nil
+}
+
+// record stores a call. The caller must hold the lock.
+func (fs *FakeSession) record(call FakeCall) {
+	fs.calls = append(fs.calls, call)
+}
+
+// newMessage stores a new message. The caller must hold the lock.
+func (fs *FakeSession) newMessage(channelID string) *discordgo.Message {
+	fs.nextID++
+	message := &discordgo.Message{
+		ID:        strconv.Itoa(fs.nextID),
+		ChannelID: channelID,
+		Author:    fs.User,
+	}
+	fs.Messages[message.ID] = message
+	return message
+}
+
+// attach keeps only the attachments of a message listed in kept, unless kept
+// is nil, and adds the files as new attachments. The caller must hold the lock.
+func (fs *FakeSession) attach(message *discordgo.Message, kept *[]*discordgo.MessageAttachment, files []*discordgo.File) error {
+	if kept != nil {
+		attachments := make([]*discordgo.MessageAttachment, 0, len(*kept))
+		for _, attachment := range message.Attachments {
+			if slices.ContainsFunc(*kept, func(k *discordgo.MessageAttachment) bool { return k.ID == attachment.ID }) {
+				attachments = append(attachments, attachment)
+			}
+		}
+		message.Attachments = attachments
+	}
+
+	if len(files) > 0 && fs.server == nil {
+		fs.server = httptest.NewServer(http.HandlerFunc(fs.serveFile))
+	}
+	for _, file := range files {
+		data, err := io.ReadAll(file.Reader)
+		if err != nil {
+			return fmt.Errorf("error reading file %s: %w", file.Name, err)
+		}
+
+		fs.nextID++
+		id := strconv.Itoa(fs.nextID)
+		path := fmt.Sprintf("/attachments/%s/%s", id, file.Name)
+		fs.files[path] = data
+		message.Attachments = append(message.Attachments, &discordgo.MessageAttachment{
+			ID:          id,
+			URL:         fs.server.URL + path,
+			Filename:    file.Name,
+			ContentType: file.ContentType,
+			Size:        len(data),
+		})
+	}
+	return nil
+}
+
+func (fs *FakeSession) serveFile(w http.ResponseWriter, r *http.Request) {
+	fs.mutex.Lock()
+	data, exists := fs.files[r.URL.Path]
+	fs.mutex.Unlock()
+
+	if !exists {
+		http.NotFound(w, r)
+		return
+	}
+	w.Write(data)
+}
+
+// interactionMessageID is the ID under which the response to an interaction is stored
+func interactionMessageID(interaction *discordgo.Interaction) string {
+	return "interaction-" + interaction.ID
+}
+
+func (fs *FakeSession) InteractionRespond(interaction *discordgo.Interaction, resp *discordgo.InteractionResponse, options ...discordgo.RequestOption) error {
+	fs.mutex.Lock()
+	defer fs.mutex.Unlock()
+
+	fs.record(FakeCall{Method: "InteractionRespond", ChannelID: interaction.ChannelID, Response: resp})
+
+	// Component updates edit the message the component is on
+	if resp.Type == discordgo.InteractionResponseUpdateMessage && interaction.Message != nil {
+		if message, exists := fs.Messages[interaction.Message.ID]; exists && resp.Data != nil {
+			message.Content = resp.Data.Content
+			message.Embeds = resp.Data.Embeds
+			message.Components = resp.Data.Components
+			return fs.attach(message, resp.Data.Attachments, resp.Data.Files)
+		}
+		return nil
+	}
+
+	message := &discordgo.Message{
+		ID:        interactionMessageID(interaction),
+		ChannelID: interaction.ChannelID,
+		Author:    fs.User,
+	}
+	fs.Messages[message.ID] = message
+	if resp.Data != nil {
+		message.Content = resp.Data.Content
+		message.Embeds = resp.Data.Embeds
+		message.Components = resp.Data.Components
+		return fs.attach(message, nil, resp.Data.Files)
+	}
+
+	return nil
+}
+
+func (fs *FakeSession) InteractionResponse(interaction *discordgo.Interaction, options ...discordgo.RequestOption) (*discordgo.Message, error) {
+	fs.mutex.Lock()
+	defer fs.mutex.Unlock()
+
+	fs.record(FakeCall{Method: "InteractionResponse", ChannelID: interaction.ChannelID})
+
+	message, exists := fs.Messages[interactionMessageID(interaction)]
+	if !exists {
+		return nil, fmt.Errorf("interaction %s was not responded to", interaction.ID)
+	}
+
+	copied := *message
+	return &copied, nil
+}
+
+func (fs *FakeSession) InteractionResponseEdit(interaction *discordgo.Interaction, newresp *discordgo.WebhookEdit, options ...discordgo.RequestOption) (*discordgo.Message, error) {
+	fs.mutex.Lock()
+	defer fs.mutex.Unlock()
+
+	fs.record(FakeCall{Method: "InteractionResponseEdit", ChannelID: interaction.ChannelID, WebhookEdit: newresp})
+
+	messageID := interactionMessageID(interaction)
+	if interaction.Message != nil {
+		messageID = interaction.Message.ID
+	}
+
+	message, exists := fs.Messages[messageID]
+	if !exists {
+		return nil, fmt.Errorf("interaction %s was not responded to", interaction.ID)
+	}
+
+	if newresp.Content != nil {
+		message.Content = *newresp.Content
+	}
+	if newresp.Embeds != nil {
+		message.Embeds = *newresp.Embeds
+	}
+	if newresp.Components != nil {
+		message.Components = *newresp.Components
+	}
+	if err := fs.attach(message, newresp.Attachments, newresp.Files); err != nil {
+		return nil, err
+	}
+
+	copied := *message
+	return &copied, nil
+}
+
+func (fs *FakeSession) ChannelMessage(channelID, messageID string, options ...discordgo.RequestOption) (*discordgo.Message, error) {
+	fs.mutex.Lock()
+	defer fs.mutex.Unlock()
+
+	fs.record(FakeCall{Method: "ChannelMessage", ChannelID: channelID, MessageID: messageID})
+
+	message, exists := fs.Messages[messageID]
+	if !exists || message.ChannelID != channelID {
+		return nil, fmt.Errorf("unknown message %s in channel %s", messageID, channelID)
+	}
+
+	copied := *message
+	return &copied, nil
+}
+
+func (fs *FakeSession) ChannelMessageSend(channelID string, content string, options ...discordgo.RequestOption) (*discordgo.Message, error) {
+	return fs.ChannelMessageSendComplex(channelID, &discordgo.MessageSend{Content: content}, options...)
+}
+
+func (fs *FakeSession) ChannelMessageSendComplex(channelID string, data *discordgo.MessageSend, options ...discordgo.RequestOption) (*discordgo.Message, error) {
+	fs.mutex.Lock()
+	defer fs.mutex.Unlock()
+
+	fs.record(FakeCall{Method: "ChannelMessageSendComplex", ChannelID: channelID, Send: data})
+
+	message := fs.newMessage(channelID)
+	message.Content = data.Content
+	message.Embeds = data.Embeds
+	message.Components = data.Components
+	if err := fs.attach(message, nil, data.Files); err != nil {
+		return nil, err
+	}
+
+	copied := *message
+	return &copied, nil
+}
+
+func (fs *FakeSession) ChannelMessageEdit(channelID, messageID, content string, options ...discordgo.RequestOption) (*discordgo.Message, error) {
+	return fs.ChannelMessageEditComplex(discordgo.NewMessageEdit(channelID, messageID).SetContent(content), options...)
+}
+
+func (fs *FakeSession) ChannelMessageEditComplex(m *discordgo.MessageEdit, options ...discordgo.RequestOption) (*discordgo.Message, error) {
+	fs.mutex.Lock()
+	defer fs.mutex.Unlock()
+
+	fs.record(FakeCall{Method: "ChannelMessageEditComplex", ChannelID: m.Channel, MessageID: m.ID, Edit: m})
+
+	message, exists := fs.Messages[m.ID]
+	if !exists || message.ChannelID != m.Channel {
+		return nil, fmt.Errorf("unknown message %s in channel %s", m.ID, m.Channel)
+	}
+
+	if m.Content != nil {
+		message.Content = *m.Content
+	}
+	if m.Embeds != nil {
+		message.Embeds = *m.Embeds
+	}
+	if m.Components != nil {
+		message.Components = *m.Components
+	}
+	if err := fs.attach(message, m.Attachments, m.Files); err != nil {
+		return nil, err
+	}
+
+	copied := *message
+	return &copied, nil
+}
+
+func (fs *FakeSession) Channel(channelID string, options ...discordgo.RequestOption) (*discordgo.Channel, error) {
+	fs.mutex.Lock()
+	defer fs.mutex.Unlock()
+
+	for _, channel := range fs.Channels {
+		if channel.ID == channelID {
+			return channel, nil
+		}
+	}
+	return nil, fmt.Errorf("unknown channel %s", channelID)
+}
+
+func (fs *FakeSession) GuildChannels(guildID string, options ...discordgo.RequestOption) ([]*discordgo.Channel, error) {
+	fs.mutex.Lock()
+	defer fs.mutex.Unlock()
+
+	var channels []*discordgo.Channel
+	for _, channel := range fs.Channels {
+		if channel.GuildID == guildID {
+			channels = append(channels, channel)
+		}
+	}
+	return channels, nil
+}
+
+func (fs *FakeSession) UserChannelCreate(recipientID string, options ...discordgo.RequestOption) (*discordgo.Channel, error) {
+	fs.mutex.Lock()
+	defer fs.mutex.Unlock()
+
+	channelID := "dm-" + recipientID
+	fs.record(FakeCall{Method: "UserChannelCreate", ChannelID: channelID})
+
+	for _, channel := range fs.Channels {
+		if channel.ID == channelID {
+			return channel, nil
+		}
+	}
+
+	channel := &discordgo.Channel{
+		ID:         channelID,
+		Type:       discordgo.ChannelTypeDM,
+		Recipients: []*discordgo.User{{ID: recipientID}},
+	}
+	fs.Channels = append(fs.Channels, channel)
+	return channel, nil
+}
+
+func (fs *FakeSession) BotUser() *discordgo.User {
+	return fs.User
+}
+
+func (fs *FakeSession) GuildIDs() []string {
+	fs.mutex.Lock()
+	defer fs.mutex.Unlock()
+
+	return append([]string{}, fs.Guilds...)
+}
+
+func (fs *FakeSession) BotPermissions(channelID string) (int64, error) {
+	fs.mutex.Lock()
+	defer fs.mutex.Unlock()
+
+	if permissions, exists := fs.Permissions[channelID]; exists {
+		return permissions, nil
+	}
+	return discordgo.PermissionAll, nil
+}
@@ -0,0 +1,488 @@
+package discord
+
+import (
+	"fmt"
+	"maps"
+	"math"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/bwmarrin/discordgo"
+	log "github.com/sirupsen/logrus"
+)
+
+const guildConfigFile = "guild_config.json"
+
+// Features that can be switched on and off per guild
+const (
+	FeatureAnnouncements = "announcements"
+	FeaturePlayerCount   = "playercount"
+	FeatureDaily         = "daily"
+)
+
+// Seed announcements and the daily seed are opt-in, every other feature is
+// on unless turned off
+var defaultFeatures = map[string]bool{
+	FeatureAnnouncements: false,
+	FeaturePlayerCount:   true,
+	FeatureDaily:         false,
+}
+
+// defaultAnnouncementChannel is used when a guild didn't configure a channel
+const defaultAnnouncementChannel = "bot-commands"
+
+// defaultAnnouncementThreshold is the boost time under which seeds get
+// announced in guilds that didn't set one
+var defaultAnnouncementThreshold = 130.0
+
+// maxPingTiers is how many ping tiers a guild can have
+const maxPingTiers = 5
+
+// PingTier pings a role for seeds faster than its threshold
+type PingTier struct {
+	Threshold float64 `json:"threshold"`
+	RoleID    string  `json:"role_id"`
+}
+
+// tierStyle is how an announcement of a tier looks, from the slowest tier up
+type tierStyle struct {
+	Emoji string
+	Title string
+	Color int
+}
+
+var tierStyles = []tierStyle{
+	{"🔥", "Great seed", 0x45D3B3},
+	{"⚡", "Insane seed", 0x0079D3},
+	{"👑", "God seed", 0xF1C40F},
+}
+
+// styleOfTier returns the style of the tier at index k of count tiers. The
+// fastest tier always gets the top style, the slowest style repeats when
+// there are more tiers than styles.
+func styleOfTier(k, count int) tierStyle {
+	fromFastest := count - 1 - k
+	return tierStyles[max(len(tierStyles)-1-fromFastest, 0)]
+}
+
+// GuildConfig holds the settings of a single guild
+type GuildConfig struct {
+	AnnouncementChannelID string  `json:"announcement_channel_id,omitempty"`
+	Threshold             float64 `json:"threshold,omitempty"`
+	PingRoleID            string  `json:"ping_role_id,omitempty"`
+	// Tiers are sorted from the slowest threshold to the fastest
+	Tiers    []PingTier      `json:"tiers,omitempty"`
+	Features map[string]bool `json:"features,omitempty"`
+}
+
+// AnnouncementThreshold returns the boost time under which seeds get announced
+func (c GuildConfig) AnnouncementThreshold() float64 {
+	if !(c.Threshold > 0) || math.IsInf(c.Threshold, 0) {
+		return defaultAnnouncementThreshold
+	}
+	return c.Threshold
+}
+
+// Tier returns the fastest tier a boost time beats and its style, ok is
+// false if it beats none
+func (c GuildConfig) Tier(boostTime float64) (PingTier, tierStyle, bool) {
+	for k := len(c.Tiers) - 1; k >= 0; k-- {
+		if boostTime < c.Tiers[k].Threshold {
+			return c.Tiers[k], styleOfTier(k, len(c.Tiers)), true
+		}
+	}
+	return PingTier{}, tierStyle{}, false
+}
+
+// Announces reports whether a seed with the boost time is posted in the
+// guild, which it is when it beats the threshold or any tier
+func (c GuildConfig) Announces(boostTime float64) bool {
+	_, _, tiered := c.Tier(boostTime)
+	return tiered || boostTime < c.AnnouncementThreshold()
+}
+
+// setTier adds a tier, replaces the one with the same threshold, or removes
+// it when roleID is empty, keeping the tiers sorted
+func (c *GuildConfig) setTier(threshold float64, roleID string) {
+	c.Tiers = slices.DeleteFunc(slices.Clone(c.Tiers), func(t PingTier) bool { return t.Threshold == threshold })
+	if roleID != "" {
+		c.Tiers = append(c.Tiers, PingTier{Threshold: threshold, RoleID: roleID})
+	}
+	slices.SortFunc(c.Tiers, func(a, b PingTier) int { return compareFloat(b.Threshold, a.Threshold) })
+}
+
+// Enabled reports whether a feature is switched on, falling back to its default
+func (c GuildConfig) Enabled(feature string) bool {
+	if enabled, exists := c.Features[feature]; exists {
+		return enabled
+	}
+	return defaultFeatures[feature]
+}
+
+// GuildConfigStore keeps the configuration of every guild on disk
+type GuildConfigStore struct {
+	mutex   sync.RWMutex
+	configs map[string]GuildConfig
+}
+
+// NewGuildConfigStore loads the stored guild configurations
+func NewGuildConfigStore() (*GuildConfigStore, error) {
+	store := &GuildConfigStore{configs: make(map[string]GuildConfig)}
+	if err := loadJSON(guildConfigFile, &store.configs); err != nil {
+		return nil, err
+	}
+	return store, nil
+}
+
+// Get returns the configuration of a guild. Guilds that never changed their
+// configuration get the defaults.
+func (gs *GuildConfigStore) Get(guildID string) GuildConfig {
+	gs.mutex.RLock()
+	defer gs.mutex.RUnlock()
+
+	return gs.configs[guildID]
+}
+
+// Update changes the configuration of a guild and persists it. The change is
+// only kept if it is saved.
+func (gs *GuildConfigStore) Update(guildID string, update func(*GuildConfig)) (GuildConfig, error) {
+	gs.mutex.Lock()
+	defer gs.mutex.Unlock()
+
+	config := gs.configs[guildID]
+	features := make(map[string]bool, len(config.Features))
+	for feature, enabled := range config.Features {
+		features[feature] = enabled
+	}
+	config.Features = features
+
+	update(&config)
+
+	stored := maps.Clone(gs.configs)
+	stored[guildID] = config
+	if err := saveJSON(guildConfigFile, stored); err != nil {
+		return GuildConfig{}, err
+	}
+	gs.configs = stored
+	return config, nil
+}
+
+// AnyEnabled reports whether a feature is on in any guild that changed its
+// configuration, or by default
+func (gs *GuildConfigStore) AnyEnabled(feature string) bool {
+	gs.mutex.RLock()
+	defer gs.mutex.RUnlock()
+
+	if defaultFeatures[feature] {
+		return true
+	}
+	for _, config := range gs.configs {
+		if config.Enabled(feature) {
+			return true
+		}
+	}
+	return false
+}
+
+// SetFeatureDefault switches a feature on or off for a guild unless the
+// guild already chose a setting for it
+func (gs *GuildConfigStore) SetFeatureDefault(guildID, feature string, enabled bool) error {
+	if _, exists := gs.Get(guildID).Features[feature]; exists {
+		return nil
+	}
+
+	_, err := gs.Update(guildID, func(c *GuildConfig) {
+		if _, exists := c.Features[feature]; !exists {
+			c.Features[feature] = enabled
+		}
+	})
+	return err
+}
+
+var guildConfigs *GuildConfigStore
+
+// announcementChannelID returns the channel where found seeds are posted in a guild
+func announcementChannelID(guildID string) string {
+	config := guildConfigs.Get(guildID)
+	if config.AnnouncementChannelID != "" {
+		return config.AnnouncementChannelID
+	}
+
+	return guildChannelIDByName(guildID, defaultAnnouncementChannel)
+}
+
+// guildChannelIDByName returns the ID of the text channel with the given name in a guild
+func guildChannelIDByName(guildID, channelName string) string {
+	if s == nil {
+		log.Error("Discord session is not initialized")
+		return ""
+	}
+
+	channels, err := s.GuildChannels(guildID)
+	if err != nil {
+		log.Errorf("Error getting channels for guild %s: %v", guildID, err)
+		return ""
+	}
+
+	for _, channel := range channels {
+		if channel.Type == discordgo.ChannelTypeGuildText && channel.Name == channelName {
+			return channel.ID
+		}
+	}
+
+	return ""
+}
+
+var configCommand = &discordgo.ApplicationCommand{
+	Name:                     "config",
+	Description:              "Change the bot settings for this server",
+	DefaultMemberPermissions: func() *int64 { p := int64(discordgo.PermissionManageServer); return &p }(),
+	Options: []*discordgo.ApplicationCommandOption{
+		{
+			Type:        discordgo.ApplicationCommandOptionSubCommand,
+			Name:        "show",
+			Description: "Show the current settings",
+		},
+		{
+			Type:        discordgo.ApplicationCommandOptionSubCommand,
+			Name:        "channel",
+			Description: "Set the channel where found seeds are announced",
+			Options: []*discordgo.ApplicationCommandOption{
+				{
+					Type:         discordgo.ApplicationCommandOptionChannel,
+					Name:         "channel",
+					Description:  "Announcement channel, leave empty to use #" + defaultAnnouncementChannel,
+					ChannelTypes: []discordgo.ChannelType{discordgo.ChannelTypeGuildText},
+				},
+			},
+		},
+		{
+			Type:        discordgo.ApplicationCommandOptionSubCommand,
+			Name:        "threshold",
+			Description: "Only announce seeds faster than this",
+			Options: []*discordgo.ApplicationCommandOption{
+				{
+					Type:        discordgo.ApplicationCommandOptionString,
+					Name:        "time",
+					Description: "Boost time like 2:10",
+					Required:    true,
+				},
+			},
+		},
+		{
+			Type:        discordgo.ApplicationCommandOptionSubCommand,
+			Name:        "pingrole",
+			Description: "Set the role pinged when a seed is announced",
+			Options: []*discordgo.ApplicationCommandOption{
+				{
+					Type:        discordgo.ApplicationCommandOptionRole,
+					Name:        "role",
+					Description: "Role to ping, leave empty to ping nobody",
+				},
+			},
+		},
+		{
+			Type:        discordgo.ApplicationCommandOptionSubCommand,
+			Name:        "tier",
+			Description: "Ping a role only for seeds faster than a time, the fastest tier wins",
+			Options: []*discordgo.ApplicationCommandOption{
+				{
+					Type:        discordgo.ApplicationCommandOptionString,
+					Name:        "time",
+					Description: "Boost time like 2:05",
+					Required:    true,
+				},
+				{
+					Type:        discordgo.ApplicationCommandOptionRole,
+					Name:        "role",
+					Description: "Role to ping, leave empty to remove the tier",
+				},
+			},
+		},
+		{
+			Type:        discordgo.ApplicationCommandOptionSubCommand,
+			Name:        "feature",
+			Description: "Turn a feature on or off",
+			Options: []*discordgo.ApplicationCommandOption{
+				{
+					Type:        discordgo.ApplicationCommandOptionString,
+					Name:        "name",
+					Description: "The feature",
+					Required:    true,
+					Choices:     featureChoices(),
+				},
+				{
+					Type:        discordgo.ApplicationCommandOptionBoolean,
+					Name:        "enabled",
+					Description: "Whether the feature is on",
+					Required:    true,
+				},
+			},
+		},
+	},
+}
+
+func featureChoices() []*discordgo.ApplicationCommandOptionChoice {
+	features := make([]string, 0, len(defaultFeatures))
+	for feature := range defaultFeatures {
+		features = append(features, feature)
+	}
+	sort.Strings(features)
+
+	choices := make([]*discordgo.ApplicationCommandOptionChoice, 0, len(features))
+	for _, feature := range features {
+		choices = append(choices, &discordgo.ApplicationCommandOptionChoice{
+			Name:  feature,
+			Value: feature,
+		})
+	}
+	return choices
+}
+
+func configHandler(s Session, i *discordgo.InteractionCreate) {
+	logUserInteraction(i, "command", "config")
+
+	respond := func(content string) {
+		err := s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+			Type: discordgo.InteractionResponseChannelMessageWithSource,
+			Data: &discordgo.InteractionResponseData{
+				Content:         content,
+				Flags:           discordgo.MessageFlagsEphemeral,
+				AllowedMentions: &discordgo.MessageAllowedMentions{},
+			},
+		})
+		if err != nil {
+			log.Errorf("Failed to respond to config command: %v", err)
+		}
+	}
+
+	if i.GuildID == "" || i.Member == nil {
+		respond("This command only works in a server.")
+		return
+	}
+
+	if i.Member.Permissions&discordgo.PermissionManageServer == 0 {
+		respond("You need the Manage Server permission to change my settings.")
+		return
+	}
+
+	options := i.ApplicationCommandData().Options
+	if len(options) == 0 {
+		respond("You sent an incomplete command.")
+		return
+	}
+
+	subcommand := options[0]
+	values := make(map[string]*discordgo.ApplicationCommandInteractionDataOption)
+	for _, opt := range subcommand.Options {
+		values[opt.Name] = opt
+	}
+
+	var update func(*GuildConfig)
+	switch subcommand.Name {
+	case "show":
+		respond(formatGuildConfig(guildConfigs.Get(i.GuildID)))
+		return
+	case "channel":
+		channelID := ""
+		if opt, exists := values["channel"]; exists {
+			channelID = opt.ChannelValue(nil).ID
+			if err := checkBotPermissions(channelID); err != nil {
+				respond(fmt.Sprintf("I can't post in <#%s>: %v", channelID, err))
+				return
+			}
+		}
+		update = func(c *GuildConfig) { c.AnnouncementChannelID = channelID }
+	case "threshold":
+		threshold, err := ParseTime(values["time"].StringValue())
+		if err != nil {
+			respond(err.Error())
+			return
+		}
+		update = func(c *GuildConfig) { c.Threshold = threshold }
+	case "pingrole":
+		roleID := ""
+		if opt, exists := values["role"]; exists {
+			roleID = opt.RoleValue(nil, "").ID
+		}
+		update = func(c *GuildConfig) { c.PingRoleID = roleID }
+	case "tier":
+		threshold, err := ParseTime(values["time"].StringValue())
+		if err != nil {
+			respond(err.Error())
+			return
+		}
+		roleID := ""
+		if opt, exists := values["role"]; exists {
+			roleID = opt.RoleValue(nil, "").ID
+		}
+		current := guildConfigs.Get(i.GuildID)
+		if roleID != "" && len(current.Tiers) >= maxPingTiers &&
+			!slices.ContainsFunc(current.Tiers, func(t PingTier) bool { return t.Threshold == threshold }) {
+			respond(fmt.Sprintf("You can only have %d tiers, remove one first.", maxPingTiers))
+			return
+		}
+		update = func(c *GuildConfig) { c.setTier(threshold, roleID) }
+	case "feature":
+		feature := values["name"].StringValue()
+		if _, exists := defaultFeatures[feature]; !exists {
+			respond(fmt.Sprintf("I don't know a feature called \"%s\".", feature))
+			return
+		}
+		enabled := values["enabled"].BoolValue()
+		update = func(c *GuildConfig) { c.Features[feature] = enabled }
+	default:
+		respond(fmt.Sprintf("Unknown subcommand \"%s\".", subcommand.Name))
+		return
+	}
+
+	config, err := guildConfigs.Update(i.GuildID, update)
+	if err != nil {
+		log.Errorf("Failed to save config for guild %s: %v", i.GuildID, err)
+		respond("I couldn't save the settings, go tell the developer.")
+		return
+	}
+
+	respond("Settings updated.\n" + formatGuildConfig(config))
+}
+
+func formatGuildConfig(config GuildConfig) string {
+	var description strings.Builder
+
+	channel := fmt.Sprintf("#%s (default)", defaultAnnouncementChannel)
+	if config.AnnouncementChannelID != "" {
+		channel = fmt.Sprintf("<#%s>", config.AnnouncementChannelID)
+	}
+	description.WriteString(fmt.Sprintf("**Announcement channel:** %s\n", channel))
+	description.WriteString(fmt.Sprintf("**Threshold:** %s\n", FormatTime(config.AnnouncementThreshold())))
+
+	role := "nobody"
+	if config.PingRoleID != "" {
+		role = fmt.Sprintf("<@&%s>", config.PingRoleID)
+	}
+	if len(config.Tiers) > 0 {
+		role += " (the tiers ping instead)"
+	}
+	description.WriteString(fmt.Sprintf("**Ping role:** %s\n", role))
+
+	if len(config.Tiers) > 0 {
+		description.WriteString("**Tiers:**")
+		for k, tier := range config.Tiers {
+			description.WriteString(fmt.Sprintf(" %s under %s <@&%s>", styleOfTier(k, len(config.Tiers)).Emoji, FormatTime(tier.Threshold), tier.RoleID))
+		}
+		description.WriteString("\n")
+	}
+
+	description.WriteString("**Features:**")
+	for _, choice := range featureChoices() {
+		state := "off"
+		if config.Enabled(choice.Name) {
+			state = "on"
+		}
+		description.WriteString(fmt.Sprintf(" %s: %s", choice.Name, state))
+	}
+
+	return description.String()
+}
@@ -0,0 +1,160 @@
+package discord
+
+import (
+	"fmt"
+	"math"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+func TestAnnouncementThreshold(t *testing.T) {
+	tests := []struct {
+		threshold float64
+		want      float64
+	}{
+		{0, defaultAnnouncementThreshold},
+		{-5, defaultAnnouncementThreshold},
+		{math.NaN(), defaultAnnouncementThreshold},
+		{math.Inf(1), defaultAnnouncementThreshold},
+		{125, 125},
+	}
+
+	for _, test := range tests {
+		if got := (GuildConfig{Threshold: test.threshold}).AnnouncementThreshold(); got != test.want {
+			t.Errorf("threshold %v: got %v, want %v", test.threshold, got, test.want)
+		}
+	}
+}
+
+func TestGuildConfigUpdate(t *testing.T) {
+	useDataDir(t)
+	store, err := NewGuildConfigStore()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if store.AnyEnabled(FeatureAnnouncements) {
+		t.Error("announcements are on without any guild opting in")
+	}
+	if _, err := store.Update("guild", func(c *GuildConfig) { c.Features[FeatureAnnouncements] = true }); err != nil {
+		t.Fatal(err)
+	}
+	if !store.AnyEnabled(FeatureAnnouncements) {
+		t.Error("announcements are off after a guild opted in")
+	}
+
+	breakDataDir(t)
+	if _, err := store.Update("guild", func(c *GuildConfig) { c.Threshold = 100 }); err == nil {
+		t.Fatal("expected the save to fail")
+	}
+	if _, err := store.Update("other", func(c *GuildConfig) { c.Threshold = 100 }); err == nil {
+		t.Fatal("expected the save to fail")
+	}
+	if store.Get("guild").Threshold != 0 || store.Get("other").Threshold != 0 {
+		t.Error("the failed update was kept")
+	}
+}
+
+func TestTierStyles(t *testing.T) {
+	great, insane, god := tierStyles[0], tierStyles[1], tierStyles[2]
+
+	tests := []struct {
+		name       string
+		thresholds []float64
+		boostTime  float64
+		style      tierStyle
+		tiered     bool
+	}{
+		{"one tier", []float64{120}, 110, god, true},
+		{"not beaten", []float64{120}, 125, tierStyle{}, false},
+		{"two tiers, slow one", []float64{130, 120}, 125, insane, true},
+		{"two tiers, fast one", []float64{130, 120}, 110, god, true},
+		{"five tiers, slowest", []float64{150, 140, 130, 120, 110}, 145, great, true},
+		{"five tiers, third", []float64{150, 140, 130, 120, 110}, 125, great, true},
+		{"five tiers, fastest", []float64{150, 140, 130, 120, 110}, 100, god, true},
+	}
+
+	for _, test := range tests {
+		var config GuildConfig
+		for k, threshold := range test.thresholds {
+			config.setTier(threshold, fmt.Sprint("role-", k))
+		}
+
+		tier, style, tiered := config.Tier(test.boostTime)
+		if tiered != test.tiered || style != test.style {
+			t.Errorf("%s: got %+v %+v %v, want %+v", test.name, tier, style, tiered, test.style)
+		}
+		if tiered && !(test.boostTime < tier.Threshold) {
+			t.Errorf("%s: %.0f doesn't beat tier %+v", test.name, test.boostTime, tier)
+		}
+	}
+}
+
+func TestAnnounces(t *testing.T) {
+	config := GuildConfig{Threshold: 120}
+	config.setTier(110, "role")
+
+	for boostTime, want := range map[float64]bool{100: true, 115: true, 120: false, 130: false} {
+		if got := config.Announces(boostTime); got != want {
+			t.Errorf("%.0f: got %v, want %v", boostTime, got, want)
+		}
+	}
+
+	// A tier slower than the threshold announces too
+	config.setTier(125, "slow-role")
+	if !config.Announces(122) {
+		t.Error("a seed beating only a tier wasn't announced")
+	}
+}
+
+func TestSetTier(t *testing.T) {
+	var config GuildConfig
+	config.setTier(120, "a")
+	config.setTier(130, "b")
+	config.setTier(110, "c")
+	config.setTier(120, "d")
+
+	want := []PingTier{{130, "b"}, {120, "d"}, {110, "c"}}
+	if !slices.Equal(config.Tiers, want) {
+		t.Errorf("got %+v, want %+v", config.Tiers, want)
+	}
+
+	config.setTier(120, "")
+	config.setTier(100, "")
+	if want := []PingTier{{130, "b"}, {110, "c"}}; !slices.Equal(config.Tiers, want) {
+		t.Errorf("after removing: got %+v, want %+v", config.Tiers, want)
+	}
+}
+
+func TestTierCapScenario(t *testing.T) {
+	fs := setupScenario(t, "guild")
+
+	tier := func(id, time, roleID string) string {
+		options := []*discordgo.ApplicationCommandInteractionDataOption{stringOption("time", time)}
+		if roleID != "" {
+			options = append(options, &discordgo.ApplicationCommandInteractionDataOption{Name: "role", Type: discordgo.ApplicationCommandOptionRole, Value: roleID})
+		}
+		i := subcommandInteraction(id, "guild", "config", "tier", true, options...)
+		HandleInteraction(fs, i)
+		return responseContent(t, fs, i)
+	}
+
+	for k := 0; k < maxPingTiers; k++ {
+		tier(fmt.Sprint(k), fmt.Sprintf("2:%02d", 10*k), fmt.Sprint("role-", k))
+	}
+	if content := tier("full", "1:55", "role-new"); !strings.Contains(content, "remove one first") {
+		t.Errorf("a tier over the cap got %q", content)
+	}
+	tier("replace", "2:00", "role-replaced")
+	tier("remove", "2:10", "")
+	tier("add", "1:55", "role-new")
+
+	tiers := guildConfigs.Get("guild").Tiers
+	want := []PingTier{{160, "role-4"}, {150, "role-3"}, {140, "role-2"}, {120, "role-replaced"}, {115, "role-new"}}
+	if !slices.Equal(tiers, want) {
+		t.Errorf("got %+v, want %+v", tiers, want)
+	}
+}
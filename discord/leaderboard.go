@@ -0,0 +1,259 @@
+package discord
+
+import (
+	"bytes"
+	"fmt"
+	"image/color"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/fogleman/gg"
+	log "github.com/sirupsen/logrus"
+)
+
+// leaderboardSize is how many players the leaderboard shows
+const leaderboardSize = 10
+
+// Leaderboard metrics
+const (
+	MetricBest     = "best"
+	MetricSub      = "sub"
+	MetricActivity = "activity"
+)
+
+// LeaderboardEntry sums up the seeds a player found in a period
+type LeaderboardEntry struct {
+	IGN string
+	// Best is the boost time of their fastest seed
+	Best float64
+	// Sub is how many of their seeds beat the threshold
+	Sub int
+	// Seeds is how many seeds they found
+	Seeds int
+}
+
+// buildLeaderboard ranks the players of the seeds by a metric. Names are
+// matched ignoring case and shown as they were last reported. On a tie the
+// player who found a seed first ranks higher.
+func buildLeaderboard(records []SeedRecord, metric string, threshold float64) []LeaderboardEntry {
+	entries := make(map[string]*LeaderboardEntry)
+	// records are newest first, go oldest first so order is by first seed
+	var order []string
+	for k := len(records) - 1; k >= 0; k-- {
+		record := records[k]
+		key := strings.ToLower(record.IGN)
+
+		entry, exists := entries[key]
+		if !exists {
+			entry = &LeaderboardEntry{Best: record.Result.BoostTime}
+			entries[key] = entry
+			order = append(order, key)
+		}
+		entry.IGN = record.IGN
+		entry.Seeds++
+		entry.Best = min(entry.Best, record.Result.BoostTime)
+		if record.Result.BoostTime < threshold {
+			entry.Sub++
+		}
+	}
+
+	ranked := make([]LeaderboardEntry, 0, len(order))
+	for _, key := range order {
+		entry := *entries[key]
+		if metric == MetricSub && entry.Sub == 0 {
+			continue
+		}
+		ranked = append(ranked, entry)
+	}
+
+	slices.SortStableFunc(ranked, func(a, b LeaderboardEntry) int {
+		switch metric {
+		case MetricSub:
+			return b.Sub - a.Sub
+		case MetricActivity:
+			return b.Seeds - a.Seeds
+		default:
+			return compareFloat(a.Best, b.Best)
+		}
+	})
+
+	return ranked
+}
+
+// leaderboardValue is what an entry scores in a metric
+func leaderboardValue(entry LeaderboardEntry, metric string) string {
+	switch metric {
+	case MetricSub:
+		return fmt.Sprintf("%d", entry.Sub)
+	case MetricActivity:
+		return fmt.Sprintf("%d", entry.Seeds)
+	default:
+		return FormatTime(entry.Best)
+	}
+}
+
+func leaderboardTitle(metric, period string, threshold float64) string {
+	var title string
+	switch metric {
+	case MetricSub:
+		title = fmt.Sprintf("Most sub %s seeds", FormatTime(threshold))
+	case MetricActivity:
+		title = "Most seeds found"
+	default:
+		title = "Best seed found"
+	}
+
+	if period == "all" {
+		return title + ", all time"
+	}
+	return fmt.Sprintf("%s this %s", title, period)
+}
+
+var leaderboardCommand = &discordgo.ApplicationCommand{
+	Name:        "leaderboard",
+	Description: "Rank players by the seeds they found",
+	Options: []*discordgo.ApplicationCommandOption{
+		{
+			Type:        discordgo.ApplicationCommandOptionString,
+			Name:        "metric",
+			Description: "What to rank by, the best seed if empty",
+			Choices: []*discordgo.ApplicationCommandOptionChoice{
+				{Name: "Best seed", Value: MetricBest},
+				{Name: "Seeds under the announcement threshold", Value: MetricSub},
+				{Name: "Seeds found", Value: MetricActivity},
+			},
+		},
+		{
+			Type:        discordgo.ApplicationCommandOptionString,
+			Name:        "period",
+			Description: "How far back to look, a week if empty",
+			Choices: []*discordgo.ApplicationCommandOptionChoice{
+				{Name: "Day", Value: "day"},
+				{Name: "Week", Value: "week"},
+				{Name: "Month", Value: "month"},
+				{Name: "All time", Value: "all"},
+			},
+		},
+	},
+}
+
+func leaderboardHandler(s Session, i *discordgo.InteractionCreate) {
+	logUserInteraction(i, "command", "leaderboard")
+
+	metric, period := MetricBest, "week"
+	for _, opt := range i.ApplicationCommandData().Options {
+		switch opt.Name {
+		case "metric":
+			metric = opt.StringValue()
+		case "period":
+			period = opt.StringValue()
+		}
+	}
+
+	// Sub threshold seeds count against the threshold of the guild asking
+	threshold := defaultAnnouncementThreshold
+	if i.GuildID != "" {
+		threshold = guildConfigs.Get(i.GuildID).AnnouncementThreshold()
+	}
+
+	since := time.Time{}
+	if duration, exists := seedPeriods[period]; exists {
+		since = time.Now().Add(-duration)
+	}
+	records := seedHistory.Find(func(r SeedRecord) bool { return r.Time.After(since) })
+	entries := buildLeaderboard(records, metric, threshold)
+
+	if len(entries) == 0 {
+		err := s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+			Type: discordgo.InteractionResponseChannelMessageWithSource,
+			Data: &discordgo.InteractionResponseData{
+				Content: "Nobody made it on this leaderboard yet. Your chance to be first.",
+			},
+		})
+		if err != nil {
+			log.Errorf("Failed to respond to leaderboard command: %v", err)
+		}
+		return
+	}
+
+	img, err := drawLeaderboard(leaderboardTitle(metric, period, threshold), entries[:min(len(entries), leaderboardSize)], metric)
+	if err != nil {
+		log.Error(err)
+		s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+			Type: discordgo.InteractionResponseChannelMessageWithSource,
+			Data: &discordgo.InteractionResponseData{
+				Content: "Go tell the developer he's an idiot 'cause something's broken idk",
+			},
+		})
+		return
+	}
+
+	err = s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{
+			Files: []*discordgo.File{
+				{
+					Name:   "leaderboard.png",
+					Reader: bytes.NewReader(img.Bytes()),
+				},
+			},
+		},
+	})
+	if err != nil {
+		log.Errorf("Failed to send leaderboard: %v", err)
+	}
+}
+
+// drawLeaderboard renders the ranked entries like the calc results, the
+// podium highlighted in the move quality colors
+func drawLeaderboard(title string, entries []LeaderboardEntry, metric string) (bytes.Buffer, error) {
+	const (
+		rowHeight  = 40.0
+		rectWidth  = 520.0
+		rectHeight = 30.0
+	)
+
+	width := 775
+	height := 100 + int(rowHeight)*len(entries) + 20
+
+	dc := gg.NewContext(width, height)
+	if err := drawBackground(dc); err != nil {
+		log.Warn(err)
+		return bytes.Buffer{}, err
+	}
+
+	if err := dc.LoadFontFace(assetPath("font/minecraft_font.ttf"), 24); err != nil {
+		log.Warn(err)
+		return bytes.Buffer{}, err
+	}
+
+	dc.SetColor(color.White)
+	dc.DrawStringAnchored(title, float64(width)/2, 40, 0.5, 0.5)
+
+	podium := []color.Color{brilliantMoveColor, greatMoveColor, bestMoveColor}
+
+	rectX := float64(width)/2 - rectWidth/2
+	y := 100.0
+	for k, entry := range entries {
+		fill := color.Color(color.RGBA{0, 0, 0, 128})
+		if k < len(podium) {
+			fill = podium[k]
+		}
+		dc.SetColor(fill)
+		dc.DrawRoundedRectangle(rectX, y-rectHeight/2, rectWidth, rectHeight, 10)
+		dc.Fill()
+
+		dc.SetColor(color.White)
+		dc.DrawStringAnchored(fmt.Sprintf("%d.", k+1), rectX+20, y, 0, 0.5)
+		dc.DrawStringAnchored(entry.IGN, rectX+80, y, 0, 0.5)
+		dc.DrawStringAnchored(leaderboardValue(entry, metric), rectX+rectWidth-20, y, 1, 0.5)
+
+		y += rowHeight
+	}
+
+	var buf bytes.Buffer
+	dc.EncodePNG(&buf)
+	return buf, nil
+}
@@ -0,0 +1,58 @@
+package discord
+
+import (
+	"slices"
+	"testing"
+
+	"atlantis_calc/calc"
+)
+
+func TestBuildLeaderboard(t *testing.T) {
+	seed := func(ign string, boostTime float64) SeedRecord {
+		return SeedRecord{IGN: ign, Result: calc.CalcSeedResult{BoostTime: boostTime}}
+	}
+	// Newest first, like SeedHistory.Find returns them
+	records := []SeedRecord{
+		seed("Carol", 140),
+		seed("alice", 118),
+		seed("bob", 125),
+		seed("carol", 130),
+		seed("Bob", 135),
+		seed("Alice", 131),
+		seed("dave", 118),
+	}
+
+	tests := []struct {
+		metric string
+		igns   []string
+		values []int
+	}{
+		// alice and dave tie on 118, dave found a seed first
+		{MetricBest, []string{"dave", "alice", "bob", "Carol"}, nil},
+		// bob and carol have no sub seed
+		{MetricSub, []string{"dave", "alice"}, []int{1, 1}},
+		{MetricActivity, []string{"alice", "bob", "Carol", "dave"}, []int{2, 2, 2, 1}},
+	}
+
+	for _, test := range tests {
+		var igns []string
+		var values []int
+		for _, entry := range buildLeaderboard(records, test.metric, 120) {
+			igns = append(igns, entry.IGN)
+			switch test.metric {
+			case MetricSub:
+				values = append(values, entry.Sub)
+			case MetricActivity:
+				values = append(values, entry.Seeds)
+			}
+		}
+		if !slices.Equal(igns, test.igns) || !slices.Equal(values, test.values) {
+			t.Errorf("%s: got %v %v, want %v %v", test.metric, igns, values, test.igns, test.values)
+		}
+	}
+
+	best := buildLeaderboard(records, MetricBest, 120)
+	if alice := best[1]; alice.Best != 118 || alice.Seeds != 2 || alice.Sub != 1 {
+		t.Errorf("alice's seeds weren't merged: %+v", alice)
+	}
+}
@@ -0,0 +1,188 @@
+package discord
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	messageStatesFile = "message_store.json"
+	// legacyMessageStatesFile is where the states were kept before the state store
+	legacyMessageStatesFile = "message_states.json"
+)
+
+// legacyResultState is a result message as legacyMessageStatesFile kept it
+type legacyResultState struct {
+	ChannelID    string    `json:"channel_id"`
+	Rooms        []string  `json:"rooms"`
+	BestOnly     bool      `json:"best_only,omitempty"`
+	Index        int       `json:"index"`
+	Filter       string    `json:"filter"`
+	CalcCommand  string    `json:"calc_command,omitempty"`
+	KeepShowCalc bool      `json:"keep_show_calc,omitempty"`
+	ExpiresAt    time.Time `json:"expires_at"`
+	// ShowCalcExpiresAt is set once only the ShowCalc button is left
+	ShowCalcExpiresAt time.Time `json:"show_calc_expires_at,omitempty"`
+}
+
+// legacyMessageStates is the content of legacyMessageStatesFile
+type legacyMessageStates struct {
+	States           map[string]*legacyResultState `json:"states"`
+	ShowCalcMessages map[string]string             `json:"show_calc_messages"`
+}
+
+// convert turns the legacy states into store states. Messages whose buttons
+// expired while the bot was offline expire as soon as the store loads them.
+func (l legacyMessageStates) convert() map[string]MessageState {
+	states := make(map[string]MessageState, len(l.States))
+	for messageID, legacy := range l.States {
+		state := MessageState{
+			ChannelID: legacy.ChannelID,
+			Result: &ResultState{
+				Rooms:        legacy.Rooms,
+				BestOnly:     legacy.BestOnly,
+				Index:        legacy.Index,
+				Filter:       legacy.Filter,
+				CalcCommand:  legacy.CalcCommand,
+				KeepShowCalc: legacy.KeepShowCalc,
+			},
+			ShowCalcMessageID: l.ShowCalcMessages[messageID],
+			ExpiresAt:         legacy.ExpiresAt,
+		}
+		if !legacy.ShowCalcExpiresAt.IsZero() {
+			state.ShowCalcOnly = true
+			state.ExpiresAt = legacy.ShowCalcExpiresAt
+		}
+		states[messageID] = state
+	}
+	return states
+}
+
+// migrateMessageStates moves the states of legacyMessageStatesFile to
+// messageStatesFile once, so the buttons of messages sent before the upgrade
+// keep working. It does nothing once the legacy file is gone.
+func migrateMessageStates() error {
+	path := filepath.Join(DataDir, legacyMessageStatesFile)
+	if _, err := os.Stat(path); errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+
+	var legacy legacyMessageStates
+	if err := loadJSON(legacyMessageStatesFile, &legacy); err != nil {
+		return err
+	}
+
+	// States saved by the store since the upgrade win over the legacy ones
+	states := legacy.convert()
+	if err := loadJSON(messageStatesFile, &states); err != nil {
+		return err
+	}
+	if err := saveJSON(messageStatesFile, states); err != nil {
+		return err
+	}
+
+	log.Infof("Migrated %d message states from %s", len(legacy.States), legacyMessageStatesFile)
+	return os.Remove(path)
+}
+
+// messageStore holds the state of the messages the bot sent. Buttons of newer
+// result messages carry their own state, so for them it only remembers the
+// calculation message sent by ShowCalc. Messages whose state doesn't fit in
+// their custom IDs, and older ones, keep their whole state here until their
+// buttons expire.
+var messageStore *MessageStateStore
+
+// stateTTL returns how long the state of a message is kept after one of its
+// buttons is clicked
+func stateTTL(state MessageState) time.Duration {
+	switch {
+	case state.Quiz != nil:
+		return buttonDuration
+	case state.Result == nil:
+		return showCalcMessageTTL
+	case state.ShowCalcOnly:
+		return longButtonDuration
+	default:
+		return buttonDuration
+	}
+}
+
+// expireMessage removes the buttons of a message once its server-side state
+// expires. Messages that keep the ShowCalc button get it for another
+// longButtonDuration first.
+func expireMessage(messageID string, state MessageState) {
+	if state.Result == nil {
+		// Stateless buttons never expire
+		return
+	}
+
+	if state.Result.KeepShowCalc && !state.ShowCalcOnly {
+		// Keep only the ShowCalc button
+		components := []discordgo.MessageComponent{
+			discordgo.ActionsRow{
+				Components: []discordgo.MessageComponent{
+					discordgo.Button{
+						CustomID: ButtonShowCalc,
+						Label:    "How did you get this?",
+						Style:    discordgo.SuccessButton,
+					},
+				},
+			},
+		}
+
+		if err := replaceMessageComponents(s, state.ChannelID, messageID, components); err != nil {
+			log.Errorf("Failed to update buttons: %v", err)
+		}
+
+		messageStore.Put(messageID, MessageState{
+			ChannelID:    state.ChannelID,
+			Result:       state.Result,
+			ShowCalcOnly: true,
+		}, longButtonDuration)
+		return
+	}
+
+	// Keep the last image but remove the buttons
+	if err := replaceMessageComponents(s, state.ChannelID, messageID, []discordgo.MessageComponent{}); err != nil {
+		log.Errorf("Failed to remove buttons: %v", err)
+	}
+}
+
+// trackResultMessage records a result message in messageStore, the one place
+// calcSeedHandler, buttonHandler and ChattriggersHandle keep message state.
+// Its whole state is kept when it doesn't fit in the custom IDs of its
+// buttons, otherwise only its channel, for ShowCalc to find its calculation
+// message later.
+func trackResultMessage(channelID, messageID string, state *ResultState) {
+	if fitsButtonID(state) {
+		messageStore.Update(messageID, func(m *MessageState) {
+			m.ChannelID = channelID
+			m.Result = nil
+		}, showCalcMessageTTL)
+		return
+	}
+
+	state.KeepShowCalc = true
+	messageStore.Update(messageID, func(m *MessageState) {
+		m.ChannelID = channelID
+		m.Result = state
+	}, buttonDuration)
+}
+
+// finalizeMessage removes the buttons of a message whose state would be lost,
+// so they don't outlive the bot
+func finalizeMessage(messageID string, state MessageState) {
+	if state.Result == nil {
+		return
+	}
+
+	if err := replaceMessageComponents(s, state.ChannelID, messageID, []discordgo.MessageComponent{}); err != nil {
+		log.Errorf("Failed to remove buttons of message %s: %v", messageID, err)
+	}
+}
@@ -0,0 +1,533 @@
+package discord
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"maps"
+	"net/http"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"atlantis_calc/calc"
+
+	"github.com/bwmarrin/discordgo"
+	log "github.com/sirupsen/logrus"
+)
+
+const personalSplitsFile = "personal_splits.json"
+
+// boostlessStrat is the strat name used to set the boostless time of a room
+const boostlessStrat = "boostless"
+
+// maxImportSize is the largest splits file /mysplits import accepts
+const maxImportSize = 1 << 20
+
+// importClient downloads the splits files users import
+var importClient = &http.Client{Timeout: 10 * time.Second}
+
+// PersonalSplits holds the splits a user set, only for the rooms they set
+type PersonalSplits struct {
+	Rooms calc.SplitOverrides `json:"rooms"`
+}
+
+// Merged returns the community splits with the user's splits laid over them
+func (p PersonalSplits) Merged() (map[string]calc.Room, error) {
+	return calc.MergeSplits(calc.RoomMap, p.Rooms)
+}
+
+// clone copies the splits so changing the copy leaves p alone
+func (p PersonalSplits) clone() PersonalSplits {
+	rooms := make(calc.SplitOverrides, len(p.Rooms))
+	for name, room := range p.Rooms {
+		room.Strats = maps.Clone(room.Strats)
+		rooms[name] = room
+	}
+	return PersonalSplits{Rooms: rooms}
+}
+
+// legacyStratNames maps the strat names of rooms whose strats were renamed to
+// their names now, in the order of the strats. Both 4c strats used to be
+// called "cp 1-2", so splits saved for it changed both.
+var legacyStratNames = map[string]map[string][]string{
+	"3f": {"cp 1-2 ": {"cp 1-2"}},
+	"4c": {"cp 1-2": {"cp 1-2 (Late)", "cp 1-2 (Early)"}},
+}
+
+// migrateStratNames moves the splits saved under old strat names to the
+// names now, keeping splits already saved under the new names. It reports
+// whether anything moved.
+func (p PersonalSplits) migrateStratNames() bool {
+	migrated := false
+	for roomName, room := range p.Rooms {
+		for legacy, names := range legacyStratNames[roomName] {
+			times, exists := room.Strats[legacy]
+			if !exists {
+				continue
+			}
+			delete(room.Strats, legacy)
+			for _, name := range names {
+				if _, exists := room.Strats[name]; !exists {
+					room.Strats[name] = times
+				}
+			}
+			migrated = true
+		}
+	}
+	return migrated
+}
+
+// PersonalSplitsStore keeps the personal splits of every user on disk
+type PersonalSplitsStore struct {
+	mutex  sync.RWMutex
+	splits map[string]PersonalSplits
+}
+
+// NewPersonalSplitsStore loads the stored personal splits
+func NewPersonalSplitsStore() (*PersonalSplitsStore, error) {
+	store := &PersonalSplitsStore{splits: make(map[string]PersonalSplits)}
+	if err := loadJSON(personalSplitsFile, &store.splits); err != nil {
+		return nil, err
+	}
+
+	migrated := 0
+	for _, splits := range store.splits {
+		if splits.migrateStratNames() {
+			migrated++
+		}
+	}
+	if migrated > 0 {
+		if err := saveJSON(personalSplitsFile, store.splits); err != nil {
+			return nil, fmt.Errorf("error saving renamed strats: %w", err)
+		}
+		log.Infof("Moved the personal splits of %d users to the renamed strats", migrated)
+	}
+
+	return store, nil
+}
+
+// Get returns the personal splits of a user, ok is false if they never set any
+func (ps *PersonalSplitsStore) Get(userID string) (PersonalSplits, bool) {
+	ps.mutex.RLock()
+	defer ps.mutex.RUnlock()
+
+	splits, exists := ps.splits[userID]
+	return splits, exists && len(splits.Rooms) > 0
+}
+
+// Update changes the personal splits of a user and persists them. The change
+// is only kept if it is saved.
+func (ps *PersonalSplitsStore) Update(userID string, update func(*PersonalSplits)) (PersonalSplits, error) {
+	ps.mutex.Lock()
+	defer ps.mutex.Unlock()
+
+	// Copy so the update doesn't change maps handed out by Get
+	splits := ps.splits[userID].clone()
+	update(&splits)
+
+	updated := maps.Clone(ps.splits)
+	updated[userID] = splits
+	if err := saveJSON(personalSplitsFile, updated); err != nil {
+		return PersonalSplits{}, err
+	}
+	ps.splits = updated
+	return splits, nil
+}
+
+var personalSplits *PersonalSplitsStore
+
+// interactionUserID returns the ID of the user who triggered an interaction
+func interactionUserID(i *discordgo.InteractionCreate) string {
+	if i.Member != nil && i.Member.User != nil {
+		return i.Member.User.ID
+	}
+	if i.User != nil {
+		return i.User.ID
+	}
+	return ""
+}
+
+var mysplitsCommand = &discordgo.ApplicationCommand{
+	Name:        "mysplits",
+	Description: "Save your own splits to calc seeds with",
+	Options: []*discordgo.ApplicationCommandOption{
+		{
+			Type:        discordgo.ApplicationCommandOptionSubCommand,
+			Name:        "set",
+			Description: "Set your time for a room or strat",
+			Options: []*discordgo.ApplicationCommandOption{
+				{
+					Type:         discordgo.ApplicationCommandOptionString,
+					Name:         "room",
+					Description:  "The room",
+					Required:     true,
+					Autocomplete: true,
+				},
+				{
+					Type:         discordgo.ApplicationCommandOptionString,
+					Name:         "strat",
+					Description:  "The boost strat, or boostless",
+					Required:     true,
+					Autocomplete: true,
+				},
+				{
+					Type:        discordgo.ApplicationCommandOptionString,
+					Name:        "time",
+					Description: "Your time for the whole room like 12.4",
+					Required:    true,
+				},
+				{
+					Type:        discordgo.ApplicationCommandOptionString,
+					Name:        "boost_at",
+					Description: "When you boost in the room, keeps the community time if empty",
+				},
+			},
+		},
+		{
+			Type:        discordgo.ApplicationCommandOptionSubCommand,
+			Name:        "import",
+			Description: "Replace your splits with a splits JSON file",
+			Options: []*discordgo.ApplicationCommandOption{
+				{
+					Type:        discordgo.ApplicationCommandOptionAttachment,
+					Name:        "file",
+					Description: "Splits file in the same format as the calc splits",
+					Required:    true,
+				},
+			},
+		},
+		{
+			Type:        discordgo.ApplicationCommandOptionSubCommand,
+			Name:        "show",
+			Description: "Show the splits you set",
+		},
+	},
+}
+
+func mysplitsHandler(s Session, i *discordgo.InteractionCreate) {
+	logUserInteraction(i, "command", "mysplits")
+
+	respond := func(content string) {
+		err := s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+			Type: discordgo.InteractionResponseChannelMessageWithSource,
+			Data: &discordgo.InteractionResponseData{
+				Content: content,
+				Flags:   discordgo.MessageFlagsEphemeral,
+			},
+		})
+		if err != nil {
+			log.Errorf("Failed to respond to mysplits command: %v", err)
+		}
+	}
+
+	userID := interactionUserID(i)
+	options := i.ApplicationCommandData().Options
+	if userID == "" || len(options) == 0 {
+		respond("You sent an incomplete command.")
+		return
+	}
+
+	subcommand := options[0]
+	values := make(map[string]*discordgo.ApplicationCommandInteractionDataOption)
+	for _, opt := range subcommand.Options {
+		values[opt.Name] = opt
+	}
+
+	switch subcommand.Name {
+	case "set":
+		room := strings.ToLower(values["room"].StringValue())
+		strat := values["strat"].StringValue()
+		boostAt := ""
+		if opt, exists := values["boost_at"]; exists {
+			boostAt = opt.StringValue()
+		}
+
+		update, err := personalSplitUpdate(room, strat, values["time"].StringValue(), boostAt)
+		if err != nil {
+			respond(err.Error())
+			return
+		}
+
+		// A new time can clash with the community boost time it keeps
+		current, _ := personalSplits.Get(userID)
+		preview := current.clone()
+		update(&preview)
+		if _, err := preview.Merged(); err != nil {
+			respond(fmt.Sprintf("Those splits don't add up: %v", err))
+			return
+		}
+
+		if _, err := personalSplits.Update(userID, update); err != nil {
+			log.Errorf("Failed to save splits of user %s: %v", userID, err)
+			respond("I couldn't save your splits, go tell the developer.")
+			return
+		}
+		respond(fmt.Sprintf("Saved your %s split for %s. Use `/calc use_mine:true` to calc with your splits.", strat, room))
+
+	case "import":
+		var attachment *discordgo.MessageAttachment
+		if resolved := i.ApplicationCommandData().Resolved; resolved != nil && values["file"] != nil {
+			attachmentID, _ := values["file"].Value.(string)
+			attachment = resolved.Attachments[attachmentID]
+		}
+		imported, skipped, err := importPersonalSplits(attachment)
+		if err != nil {
+			respond(err.Error())
+			return
+		}
+		if _, err := imported.Merged(); err != nil {
+			respond(fmt.Sprintf("Those splits don't add up: %v", err))
+			return
+		}
+
+		if _, err := personalSplits.Update(userID, func(p *PersonalSplits) { *p = imported }); err != nil {
+			log.Errorf("Failed to save splits of user %s: %v", userID, err)
+			respond("I couldn't save your splits, go tell the developer.")
+			return
+		}
+
+		content := fmt.Sprintf("Imported your splits for %d rooms.", len(imported.Rooms))
+		if len(skipped) > 0 {
+			content += fmt.Sprintf(" I don't know these so I skipped them: %s", strings.Join(skipped, ", "))
+		}
+		respond(content)
+
+	case "show":
+		splits, exists := personalSplits.Get(userID)
+		if !exists {
+			respond("You didn't set any splits yet, use `/mysplits set` or `/mysplits import`.")
+			return
+		}
+
+		err := s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+			Type: discordgo.InteractionResponseChannelMessageWithSource,
+			Data: &discordgo.InteractionResponseData{
+				Embeds: []*discordgo.MessageEmbed{createPersonalSplitsEmbed(splits)},
+				Flags:  discordgo.MessageFlagsEphemeral,
+			},
+		})
+		if err != nil {
+			log.Errorf("Failed to show splits: %v", err)
+		}
+
+	default:
+		respond(fmt.Sprintf("Unknown subcommand \"%s\".", subcommand.Name))
+	}
+}
+
+// personalSplitUpdate checks a split set with /mysplits set and returns the
+// update that stores it
+func personalSplitUpdate(roomName, stratName, timeText, boostAtText string) (func(*PersonalSplits), error) {
+	room, exists := calc.RoomMap[roomName]
+	if !exists || roomName == "finish room" {
+		return nil, fmt.Errorf("I don't know a room called \"%s\".", roomName)
+	}
+
+	splitTime, err := ParseTime(timeText)
+	if err != nil {
+		return nil, err
+	}
+	if splitTime <= 0 {
+		return nil, fmt.Errorf("Your time has to be more than 0 seconds.")
+	}
+
+	if stratName == boostlessStrat {
+		return func(p *PersonalSplits) {
+			splits := p.Rooms[roomName]
+			splits.BoostlessTime = splitTime
+			p.Rooms[roomName] = splits
+		}, nil
+	}
+
+	if !slices.ContainsFunc(room.BoostStrats, func(b calc.BoostRoom) bool { return b.Name == stratName }) {
+		return nil, fmt.Errorf("%s doesn't have a strat called \"%s\".", roomName, stratName)
+	}
+
+	var boostAt float64
+	if boostAtText != "" {
+		boostAt, err = ParseTime(boostAtText)
+		if err != nil {
+			return nil, err
+		}
+		if boostAt <= 0 || boostAt >= splitTime {
+			return nil, fmt.Errorf("You have to boost during the room, between 0 and %s.", FormatTime(splitTime))
+		}
+	}
+
+	return func(p *PersonalSplits) {
+		splits := p.Rooms[roomName]
+		if splits.Strats == nil {
+			splits.Strats = make(map[string]calc.StratOverride)
+		}
+		splits.Strats[stratName] = calc.StratOverride{Time: splitTime, BoostTime: boostAt}
+		p.Rooms[roomName] = splits
+	}, nil
+}
+
+// importPersonalSplits reads a splits file shaped like calc.RoomMap. Rooms
+// and strats the calc doesn't know are skipped and returned.
+func importPersonalSplits(attachment *discordgo.MessageAttachment) (PersonalSplits, []string, error) {
+	if attachment == nil {
+		return PersonalSplits{}, nil, fmt.Errorf("Please attach a splits file.")
+	}
+	if attachment.Size > maxImportSize {
+		return PersonalSplits{}, nil, fmt.Errorf("That file is too big to be splits.")
+	}
+
+	resp, err := importClient.Get(attachment.URL)
+	if err != nil {
+		log.Errorf("Failed to download splits file: %v", err)
+		return PersonalSplits{}, nil, fmt.Errorf("I couldn't download your file, try again.")
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		log.Errorf("Failed to download splits file: %s", resp.Status)
+		return PersonalSplits{}, nil, fmt.Errorf("I couldn't download your file, try again.")
+	}
+
+	// The size Discord reports isn't trusted, read one byte past the limit to
+	// tell a file that is too big from one that fits exactly
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxImportSize+1))
+	if err != nil {
+		log.Errorf("Failed to download splits file: %v", err)
+		return PersonalSplits{}, nil, fmt.Errorf("I couldn't download your file, try again.")
+	}
+	if len(data) > maxImportSize {
+		return PersonalSplits{}, nil, fmt.Errorf("That file is too big to be splits.")
+	}
+
+	var rooms map[string]calc.Room
+	if err := json.Unmarshal(data, &rooms); err != nil {
+		return PersonalSplits{}, nil, fmt.Errorf("That doesn't look like a splits file: %v", err)
+	}
+
+	imported := PersonalSplits{Rooms: make(calc.SplitOverrides)}
+	var skipped []string
+	for name, room := range rooms {
+		name = strings.ToLower(name)
+		community, exists := calc.RoomMap[name]
+		if !exists || name == "finish room" {
+			skipped = append(skipped, name)
+			continue
+		}
+
+		splits := calc.RoomOverride{Strats: make(map[string]calc.StratOverride)}
+		if room.BoostlessTime > 0 {
+			splits.BoostlessTime = room.BoostlessTime
+		}
+		// Files exported before strats were renamed have them in order
+		legacySeen := make(map[string]int)
+		for _, strat := range room.BoostStrats {
+			if names := legacyStratNames[name][strat.Name]; len(names) > 0 {
+				legacy := strat.Name
+				strat.Name = names[min(legacySeen[legacy], len(names)-1)]
+				legacySeen[legacy]++
+			}
+			known := slices.ContainsFunc(community.BoostStrats, func(b calc.BoostRoom) bool { return b.Name == strat.Name })
+			if !known || strat.Time <= 0 || strat.BoostTime < 0 || strat.BoostTime >= strat.Time {
+				skipped = append(skipped, fmt.Sprintf("%s (%s)", name, strat.Name))
+				continue
+			}
+			splits.Strats[strat.Name] = calc.StratOverride{Time: strat.Time, BoostTime: strat.BoostTime}
+		}
+
+		imported.Rooms[name] = splits
+	}
+	sort.Strings(skipped)
+
+	return imported, skipped, nil
+}
+
+func createPersonalSplitsEmbed(splits PersonalSplits) *discordgo.MessageEmbed {
+	names := make([]string, 0, len(splits.Rooms))
+	for name := range splits.Rooms {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var description strings.Builder
+	description.WriteString("Your time, with the community time in brackets\n```\n")
+	for _, name := range names {
+		personal := splits.Rooms[name]
+		community := calc.RoomMap[name]
+
+		if personal.BoostlessTime > 0 {
+			description.WriteString(fmt.Sprintf("%-4s %-20s %6.2f (%6.2f)\n",
+				name, boostlessStrat, personal.BoostlessTime, community.BoostlessTime))
+		}
+		for _, strat := range community.BoostStrats {
+			if times, exists := personal.Strats[strat.Name]; exists {
+				description.WriteString(fmt.Sprintf("%-4s %-20s %6.2f (%6.2f)\n",
+					name, strat.Name, times.Time, strat.Time))
+			}
+		}
+	}
+	description.WriteString("```")
+
+	return &discordgo.MessageEmbed{
+		Title:       "Your Splits",
+		Description: description.String(),
+		Color:       0x45D3B3,
+	}
+}
+
+// mysplitsAutocomplete suggests rooms and the strats of the chosen room
+func mysplitsAutocomplete(s Session, i *discordgo.InteractionCreate) {
+	options := i.ApplicationCommandData().Options
+	if len(options) == 0 {
+		return
+	}
+
+	var room string
+	var focused *discordgo.ApplicationCommandInteractionDataOption
+	for _, opt := range options[0].Options {
+		if opt.Name == "room" {
+			room = strings.ToLower(opt.StringValue())
+		}
+		if opt.Focused {
+			focused = opt
+		}
+	}
+	if focused == nil {
+		return
+	}
+
+	var candidates []string
+	switch focused.Name {
+	case "room":
+		candidates = slices.Clone(roomOptions)
+	case "strat":
+		candidates = []string{boostlessStrat}
+		for _, strat := range calc.RoomMap[room].BoostStrats {
+			candidates = append(candidates, strat.Name)
+		}
+	}
+
+	search := strings.ToLower(focused.StringValue())
+	choices := make([]*discordgo.ApplicationCommandOptionChoice, 0, 25)
+	for _, candidate := range candidates {
+		if search != "" && !strings.Contains(strings.ToLower(candidate), search) {
+			continue
+		}
+		choices = append(choices, &discordgo.ApplicationCommandOptionChoice{
+			Name:  candidate,
+			Value: candidate,
+		})
+		if len(choices) == 25 {
+			break
+		}
+	}
+
+	err := s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionApplicationCommandAutocompleteResult,
+		Data: &discordgo.InteractionResponseData{
+			Choices: choices,
+		},
+	})
+	if err != nil {
+		log.Errorf("Autocomplete response failed: %v", err)
+	}
+}
@@ -0,0 +1,164 @@
+package discord
+
+import (
+	"encoding/json"
+	"maps"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"atlantis_calc/calc"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+func TestImportPersonalSplits(t *testing.T) {
+	files := map[string]string{
+		"/splits.json": `{"1a": {"BoostlessTime": 13.1}, "nowhere": {"BoostlessTime": 10}}`,
+		"/big.json":    `{"1a": {"BoostlessTime": 13.1}}` + strings.Repeat(" ", maxImportSize),
+		// Exported before the 4c strats were told apart and the 3f one trimmed
+		"/old.json": `{"4c": {"BoostStrats": [{"Name": "cp 1-2", "Time": 11.5, "BoostTime": 9.5}, {"Name": "cp 1-2", "Time": 12.1, "BoostTime": 2.1}]},
+			"3f": {"BoostStrats": [{"Name": "cp 1-2 ", "Time": 19.5, "BoostTime": 8}]}}`,
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		file, exists := files[r.URL.Path]
+		if !exists {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte(file))
+	}))
+	defer server.Close()
+
+	imported, skipped, err := importPersonalSplits(&discordgo.MessageAttachment{URL: server.URL + "/splits.json"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if imported.Rooms["1a"].BoostlessTime != 13.1 || len(skipped) != 1 || skipped[0] != "nowhere" {
+		t.Errorf("got %+v, skipped %v", imported, skipped)
+	}
+
+	imported, skipped, err = importPersonalSplits(&discordgo.MessageAttachment{URL: server.URL + "/old.json"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]map[string]calc.StratOverride{
+		"4c": {"cp 1-2 (Late)": {Time: 11.5, BoostTime: 9.5}, "cp 1-2 (Early)": {Time: 12.1, BoostTime: 2.1}},
+		"3f": {"cp 1-2": {Time: 19.5, BoostTime: 8}},
+	}
+	for room, strats := range want {
+		if !maps.Equal(imported.Rooms[room].Strats, strats) {
+			t.Errorf("got %s strats %+v, want %+v", room, imported.Rooms[room].Strats, strats)
+		}
+	}
+	if len(skipped) != 0 {
+		t.Errorf("skipped %v", skipped)
+	}
+
+	for _, attachment := range []*discordgo.MessageAttachment{
+		nil,
+		{URL: server.URL + "/big.json"},
+		{URL: server.URL + "/missing.json"},
+	} {
+		if _, _, err := importPersonalSplits(attachment); err == nil {
+			t.Errorf("imported %+v", attachment)
+		}
+	}
+}
+
+func TestPersonalSplitsUpdateKeepsFailedSaveOut(t *testing.T) {
+	useDataDir(t)
+	store, err := NewPersonalSplitsStore()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	setBoostless := func(time float64) func(*PersonalSplits) {
+		return func(p *PersonalSplits) {
+			if p.Rooms == nil {
+				p.Rooms = make(calc.SplitOverrides)
+			}
+			p.Rooms["1a"] = calc.RoomOverride{BoostlessTime: time}
+		}
+	}
+	if _, err := store.Update("user", setBoostless(13.1)); err != nil {
+		t.Fatal(err)
+	}
+
+	breakDataDir(t)
+	if _, err := store.Update("user", setBoostless(12)); err == nil {
+		t.Fatal("expected the save to fail")
+	}
+	if _, err := store.Update("other", setBoostless(12)); err == nil {
+		t.Fatal("expected the save to fail")
+	}
+
+	if splits, _ := store.Get("user"); splits.Rooms["1a"].BoostlessTime != 13.1 {
+		t.Errorf("the failed update was kept: %+v", splits)
+	}
+	if _, exists := store.Get("other"); exists {
+		t.Error("the failed update of another user was kept")
+	}
+}
+
+func TestPersonalSplitsMigratesRenamedStrats(t *testing.T) {
+	dir := useDataDir(t)
+	stored := `{
+		"old": {"rooms": {"4c": {"strats": {"cp 1-2": {"time": 11.5}}}, "3f": {"strats": {"cp 1-2 ": {"time": 19.5, "boost_time": 8}}}}},
+		"both": {"rooms": {"4c": {"strats": {"cp 1-2": {"time": 11.5}, "cp 1-2 (Early)": {"time": 12.1}}}}},
+		"new": {"rooms": {"4c": {"strats": {"cp 1-2 (Late)": {"time": 11.4}}}}}
+	}`
+	if err := os.WriteFile(filepath.Join(dir, personalSplitsFile), []byte(stored), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[string]map[string]map[string]calc.StratOverride{
+		// Both 4c strats used the old name, so both keep the time
+		"old": {
+			"4c": {"cp 1-2 (Late)": {Time: 11.5}, "cp 1-2 (Early)": {Time: 11.5}},
+			"3f": {"cp 1-2": {Time: 19.5, BoostTime: 8}},
+		},
+		"both": {"4c": {"cp 1-2 (Late)": {Time: 11.5}, "cp 1-2 (Early)": {Time: 12.1}}},
+		"new":  {"4c": {"cp 1-2 (Late)": {Time: 11.4}}},
+	}
+
+	check := func(store *PersonalSplitsStore) {
+		t.Helper()
+
+		for userID, rooms := range want {
+			splits, _ := store.Get(userID)
+			for room, strats := range rooms {
+				if !maps.Equal(splits.Rooms[room].Strats, strats) {
+					t.Errorf("%s got %s strats %+v, want %+v", userID, room, splits.Rooms[room].Strats, strats)
+				}
+			}
+			if _, err := splits.Merged(); err != nil {
+				t.Errorf("the splits of %s don't merge: %v", userID, err)
+			}
+		}
+	}
+
+	store, err := NewPersonalSplitsStore()
+	if err != nil {
+		t.Fatal(err)
+	}
+	check(store)
+
+	// The migration was saved
+	data, err := os.ReadFile(filepath.Join(dir, personalSplitsFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var saved map[string]PersonalSplits
+	if err := json.Unmarshal(data, &saved); err != nil {
+		t.Fatal(err)
+	}
+	for userID, splits := range saved {
+		if splits.migrateStratNames() {
+			t.Errorf("the old strat names of %s are still stored", userID)
+		}
+	}
+}
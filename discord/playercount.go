@@ -0,0 +1,201 @@
+package discord
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	log "github.com/sirupsen/logrus"
+)
+
+var playerCountStats *CachedStatsProvider
+
+// newStatsProvider picks the stats provider selected by STATS_PROVIDER
+func newStatsProvider(kind, apiKey string) (StatsProvider, error) {
+	switch kind {
+	case "", "hypixel":
+		if apiKey == "" {
+			return nil, fmt.Errorf("HYPIXEL_API_KEY is not set")
+		}
+		return NewHypixelStatsProvider(apiKey), nil
+	case "fake":
+		return &FakeStatsProvider{Counts: []int{42, 57, 51, 64}}, nil
+	default:
+		return nil, fmt.Errorf("unknown stats provider %q", kind)
+	}
+}
+
+func playerCountHandler(s Session, i *discordgo.InteractionCreate) {
+	logUserInteraction(i, "command", "playercount")
+
+	if i.GuildID != "" && !guildConfigs.Get(i.GuildID).Enabled(FeaturePlayerCount) {
+		s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+			Type: discordgo.InteractionResponseChannelMessageWithSource,
+			Data: &discordgo.InteractionResponseData{
+				Content: "Player counts are turned off in this server.",
+				Flags:   discordgo.MessageFlagsEphemeral,
+			},
+		})
+		return
+	}
+
+	if playerCountStats == nil {
+		s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+			Type: discordgo.InteractionResponseChannelMessageWithSource,
+			Data: &discordgo.InteractionResponseData{
+				Content: "Player counts aren't set up on this bot.",
+				Flags:   discordgo.MessageFlagsEphemeral,
+			},
+		})
+		return
+	}
+
+	err := s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseDeferredChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{},
+	})
+	if err != nil {
+		log.Errorf("Failed to defer response: %v", err)
+		return
+	}
+
+	options := i.ApplicationCommandData().Options
+	if len(options) > 0 && options[0].Name == "period" {
+		playerCountChartResponse(s, i, options[0].StringValue())
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+
+	current, previous, err := playerCountStats.Sample(ctx)
+	if err != nil {
+		log.Errorf("Failed to fetch player count: %v", err)
+		content := "Couldn't get the player count right now, try again later."
+		s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
+			Content: &content,
+		})
+		return
+	}
+
+	embed := createPlayerCountEmbed(current, previous)
+
+	_, err = s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
+		Embeds: &[]*discordgo.MessageEmbed{embed},
+	})
+	if err != nil {
+		log.Errorf("Failed to edit response with player count: %v", err)
+	}
+}
+
+func createPlayerCountEmbed(current, previous PlayerCountSample) *discordgo.MessageEmbed {
+	trend := "No earlier sample yet"
+	if !previous.Time.IsZero() {
+		diff := current.Count - previous.Count
+		ago := current.Time.Sub(previous.Time).Round(time.Second)
+
+		switch {
+		case diff > 0:
+			trend = fmt.Sprintf("📈 +%d since %s ago", diff, ago)
+		case diff < 0:
+			trend = fmt.Sprintf("📉 %d since %s ago", diff, ago)
+		default:
+			trend = fmt.Sprintf("➖ No change since %s ago", ago)
+		}
+	}
+
+	return &discordgo.MessageEmbed{
+		Title: "PKD Player Count",
+		Color: 0x45D3B3,
+		Fields: []*discordgo.MessageEmbedField{
+			{
+				Name:   "Players",
+				Value:  fmt.Sprintf("%d", current.Count),
+				Inline: true,
+			},
+			{
+				Name:   "Trend",
+				Value:  trend,
+				Inline: true,
+			},
+		},
+		Timestamp: current.Time.Format(time.RFC3339),
+	}
+}
+
+var playerCountPeriods = map[string]time.Duration{
+	"24h": 24 * time.Hour,
+	"7d":  7 * 24 * time.Hour,
+}
+
+// playerCountChartResponse edits the deferred response with a chart of the
+// player count history over the named period
+func playerCountChartResponse(s Session, i *discordgo.InteractionCreate, periodName string) {
+	period, exists := playerCountPeriods[periodName]
+	if !exists {
+		content := fmt.Sprintf("I don't know a period called \"%s\".", periodName)
+		s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
+			Content: &content,
+		})
+		return
+	}
+
+	now := time.Now()
+	var samples []PlayerCountSample
+	if playerCountHistory != nil {
+		samples = playerCountHistory.Since(now.Add(-period))
+	}
+
+	if len(samples) < 2 {
+		content := "I haven't collected enough player counts for a chart yet, check back later."
+		s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
+			Content: &content,
+		})
+		return
+	}
+
+	img, err := drawPlayerCountChart(samples, period, now)
+	if err != nil {
+		log.Errorf("Failed to draw player count chart: %v", err)
+		content := "Go tell the developer he's an idiot 'cause something's broken idk"
+		s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
+			Content: &content,
+		})
+		return
+	}
+
+	peak, low := samples[0], samples[0]
+	for _, sample := range samples {
+		if sample.Count > peak.Count {
+			peak = sample
+		}
+		if sample.Count < low.Count {
+			low = sample
+		}
+	}
+
+	embed := &discordgo.MessageEmbed{
+		Title: fmt.Sprintf("PKD Player Count (%s)", periodName),
+		Color: 0x45D3B3,
+		Description: fmt.Sprintf("Peak: **%d** at <t:%d:f>\nLow: **%d** at <t:%d:f>",
+			peak.Count, peak.Time.Unix(), low.Count, low.Time.Unix()),
+		Image: &discordgo.MessageEmbedImage{
+			URL: "attachment://playercount.png",
+		},
+	}
+
+	_, err = s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
+		Embeds: &[]*discordgo.MessageEmbed{embed},
+		Files: []*discordgo.File{
+			{
+				Name:   "playercount.png",
+				Reader: bytes.NewReader(img.Bytes()),
+			},
+		},
+	})
+	if err != nil {
+		log.Errorf("Failed to edit response with player count chart: %v", err)
+	}
+}
@@ -0,0 +1,103 @@
+package discord
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	playerCountHistoryFile = "playercount_history.json"
+	playerCountRetention   = 8 * 24 * time.Hour
+)
+
+// playerCountSampleInterval is how often the player count is sampled
+var playerCountSampleInterval = 10 * time.Minute
+
+var playerCountHistory *PlayerCountHistory
+
+// PlayerCountHistory keeps player count samples on disk for charting
+type PlayerCountHistory struct {
+	mutex     sync.RWMutex
+	samples   []PlayerCountSample
+	retention time.Duration
+}
+
+// NewPlayerCountHistory loads the stored samples, dropping the ones older than retention
+func NewPlayerCountHistory(retention time.Duration) (*PlayerCountHistory, error) {
+	history := &PlayerCountHistory{retention: retention}
+
+	if err := loadJSON(playerCountHistoryFile, &history.samples); err != nil {
+		return nil, err
+	}
+	history.prune(time.Now())
+
+	return history, nil
+}
+
+// prune removes samples older than the retention. The caller must hold the lock.
+func (h *PlayerCountHistory) prune(now time.Time) {
+	cutoff := now.Add(-h.retention)
+	keep := 0
+	for keep < len(h.samples) && h.samples[keep].Time.Before(cutoff) {
+		keep++
+	}
+	h.samples = h.samples[keep:]
+}
+
+// Add records a sample and persists the history
+func (h *PlayerCountHistory) Add(sample PlayerCountSample) error {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	// The stats provider is cached, so the same sample can come back twice
+	if len(h.samples) > 0 && h.samples[len(h.samples)-1].Time.Equal(sample.Time) {
+		return nil
+	}
+
+	h.samples = append(h.samples, sample)
+	h.prune(sample.Time)
+
+	return saveJSON(playerCountHistoryFile, h.samples)
+}
+
+// Since returns the samples taken after t, oldest first
+func (h *PlayerCountHistory) Since(t time.Time) []PlayerCountSample {
+	h.mutex.RLock()
+	defer h.mutex.RUnlock()
+
+	res := make([]PlayerCountSample, 0, len(h.samples))
+	for _, sample := range h.samples {
+		if sample.Time.After(t) {
+			res = append(res, sample)
+		}
+	}
+	return res
+}
+
+// samplePlayerCounts polls the stats provider every interval and records the
+// counts in the history until ctx is cancelled
+func samplePlayerCounts(ctx context.Context, stats *CachedStatsProvider, history *PlayerCountHistory, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		sampleCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+		current, _, err := stats.Sample(sampleCtx)
+		cancel()
+
+		if err != nil {
+			log.Warnf("Failed to sample player count: %v", err)
+		} else if err := history.Add(current); err != nil {
+			log.Errorf("Failed to store player count sample: %v", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,547 @@
+package discord
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+
+	"atlantis_calc/calc"
+
+	"github.com/bwmarrin/discordgo"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	quizStatsFile = "quiz_stats.json"
+	// quizComponentPrefix starts the custom IDs of the quiz menus and buttons
+	quizComponentPrefix = "quiz"
+	// quizTolerance is how much slower than the best route an answer can be and still count
+	quizTolerance = 0.05
+)
+
+// QuizStats is how well a user did in the quiz
+type QuizStats struct {
+	Played     int `json:"played"`
+	Correct    int `json:"correct"`
+	Streak     int `json:"streak"`
+	BestStreak int `json:"best_streak"`
+}
+
+// Accuracy is the share of quizzes answered with the best route
+func (q QuizStats) Accuracy() float64 {
+	if q.Played == 0 {
+		return 0
+	}
+	return float64(q.Correct) / float64(q.Played)
+}
+
+// QuizStatsStore keeps the quiz stats of every user on disk
+type QuizStatsStore struct {
+	mutex sync.RWMutex
+	stats map[string]QuizStats
+}
+
+// NewQuizStatsStore loads the stored quiz stats
+func NewQuizStatsStore() (*QuizStatsStore, error) {
+	store := &QuizStatsStore{stats: make(map[string]QuizStats)}
+	if err := loadJSON(quizStatsFile, &store.stats); err != nil {
+		return nil, err
+	}
+	return store, nil
+}
+
+// Get returns the stats of a user
+func (qs *QuizStatsStore) Get(userID string) QuizStats {
+	qs.mutex.RLock()
+	defer qs.mutex.RUnlock()
+
+	return qs.stats[userID]
+}
+
+// Record counts an answer of a user and persists the stats
+func (qs *QuizStatsStore) Record(userID string, correct bool) (QuizStats, error) {
+	qs.mutex.Lock()
+	defer qs.mutex.Unlock()
+
+	stats := qs.stats[userID]
+	stats.Played++
+	if correct {
+		stats.Correct++
+		stats.Streak++
+		stats.BestStreak = max(stats.BestStreak, stats.Streak)
+	} else {
+		stats.Streak = 0
+	}
+	qs.stats[userID] = stats
+
+	return stats, saveJSON(quizStatsFile, qs.stats)
+}
+
+var quizStats *QuizStatsStore
+
+// quizState is a quiz and the answer picked so far, kept in the custom IDs of
+// its components as "quiz|action|rooms|picks". Quizzes that don't fit there
+// get "quiz|action" and keep their state in messageStore.
+type quizState struct {
+	Rooms []string `json:"rooms"`
+	// Picks are the boosted rooms by index, the finish room included, with
+	// a StratInd of -1 until a strat is picked
+	Picks []calc.CalcResultBoost `json:"picks,omitempty"`
+}
+
+// quizLongestAction is the longest action of a quiz component, a strat menu
+const quizLongestAction = "strat-8"
+
+func (q quizState) customID(action string) string {
+	if !q.fits() {
+		return quizComponentPrefix + "|" + action
+	}
+	return q.formatID(action)
+}
+
+func (q quizState) formatID(action string) string {
+	picks := make([]string, len(q.Picks))
+	for k, pick := range q.Picks {
+		picks[k] = fmt.Sprintf("%d.%d", pick.Ind, pick.StratInd)
+	}
+	return strings.Join([]string{quizComponentPrefix, action, strings.Join(q.Rooms, ","), strings.Join(picks, ",")}, "|")
+}
+
+// fits reports whether the state can be encoded in the custom IDs of the
+// quiz components, like fitsButtonID
+func (q quizState) fits() bool {
+	for _, room := range q.Rooms {
+		if strings.ContainsAny(room, ",|") {
+			return false
+		}
+	}
+	return len(q.formatID(quizLongestAction)) <= customIDMaxLength
+}
+
+// decodeQuizID unpacks a quiz custom ID. encoded is false if the state is
+// kept in messageStore instead.
+func decodeQuizID(customID string) (action string, state quizState, encoded bool, err error) {
+	parts := strings.Split(customID, "|")
+	if len(parts) == 2 && parts[0] == quizComponentPrefix {
+		return parts[1], quizState{}, false, nil
+	}
+	if len(parts) != 4 || parts[0] != quizComponentPrefix {
+		return "", quizState{}, false, fmt.Errorf("invalid quiz ID %q", customID)
+	}
+
+	state = quizState{Rooms: strings.Split(parts[2], ",")}
+	if len(state.Rooms) != 8 {
+		return "", quizState{}, false, fmt.Errorf("invalid rooms in quiz ID %q", customID)
+	}
+	if parts[3] != "" {
+		for _, pick := range strings.Split(parts[3], ",") {
+			ind, stratInd, _ := strings.Cut(pick, ".")
+			i, err := strconv.Atoi(ind)
+			if err != nil {
+				return "", quizState{}, false, fmt.Errorf("invalid pick in quiz ID %q: %w", customID, err)
+			}
+			s, err := strconv.Atoi(stratInd)
+			if err != nil {
+				return "", quizState{}, false, fmt.Errorf("invalid pick in quiz ID %q: %w", customID, err)
+			}
+			state.Picks = append(state.Picks, calc.CalcResultBoost{Ind: i, StratInd: s})
+		}
+	}
+	return parts[1], state, true, nil
+}
+
+// trackQuizMessage keeps the state of a quiz in messageStore when it doesn't
+// fit in the custom IDs of its components
+func trackQuizMessage(channelID, messageID string, state quizState) {
+	if state.fits() {
+		return
+	}
+
+	messageStore.Update(messageID, func(m *MessageState) {
+		m.ChannelID = channelID
+		m.Quiz = &state
+	}, buttonDuration)
+}
+
+// room returns the name of the room at an index, the finish room after the 8 others
+func (q quizState) room(ind int) string {
+	if ind == len(q.Rooms) {
+		return "finish room"
+	}
+	return q.Rooms[ind]
+}
+
+// complete reports whether every picked room has a strat
+func (q quizState) complete() bool {
+	return len(q.Picks) > 0 && !slices.ContainsFunc(q.Picks, func(p calc.CalcResultBoost) bool { return p.StratInd < 0 })
+}
+
+// QuizScore is how an answer compares to the routes of the calc
+type QuizScore struct {
+	Answer calc.CalcSeedResult
+	Best   calc.CalcSeedResult
+	// Rank of the answer among all routes, 1 is the best
+	Rank   int
+	Routes int
+	Lost   float64
+	// ExtraPacelock is how much longer the answer waits for pacelock than
+	// the best route
+	ExtraPacelock float64
+}
+
+// Correct reports whether the answer is as fast as the best route
+func (q QuizScore) Correct() bool {
+	return q.Lost <= quizTolerance
+}
+
+// scoreQuizAnswer ranks the picked route among all routes of the seed
+func scoreQuizAnswer(state quizState) (QuizScore, error) {
+	route := make([]calc.RouteBoost, len(state.Picks))
+	for k, pick := range state.Picks {
+		room := calc.RoomMap[state.room(pick.Ind)]
+		if pick.StratInd < 0 || pick.StratInd >= len(room.BoostStrats) {
+			return QuizScore{}, fmt.Errorf("room %q has no strat %d", room.Name, pick.StratInd)
+		}
+		route[k] = calc.RouteBoost{Room: room.Name, Strat: room.BoostStrats[pick.StratInd].Name}
+	}
+
+	evaluation, err := calc.EvaluateRoute(state.Rooms, route, calc.RoomMap)
+	if err != nil {
+		return QuizScore{}, err
+	}
+
+	score := QuizScore{
+		Answer: evaluation.Result,
+		Best:   evaluation.Best,
+		Rank:   evaluation.Rank,
+		Routes: evaluation.Routes,
+		Lost:   evaluation.Delta,
+	}
+	score.ExtraPacelock = totalPacelock(score.Answer) - totalPacelock(score.Best)
+	return score, nil
+}
+
+// formatQuizRoute writes a route as its rooms and strats
+func formatQuizRoute(state quizState, result calc.CalcSeedResult) string {
+	boosts := make([]string, len(result.BoostRooms))
+	for k, boost := range result.BoostRooms {
+		room := calc.RoomMap[state.room(boost.Ind)]
+		boosts[k] = fmt.Sprintf("%s (%s)", room.Name, room.BoostStrats[boost.StratInd].Name)
+	}
+
+	text := fmt.Sprintf("%s in %s", strings.Join(boosts, ", "), FormatTime(result.BoostTime))
+	if pacelock := totalPacelock(result); pacelock > 0 {
+		text += fmt.Sprintf(", %.1fs pacelock", pacelock)
+	}
+	return text
+}
+
+// totalPacelock is how long a route waits for pacelock
+func totalPacelock(result calc.CalcSeedResult) float64 {
+	pacelock := 0.0
+	for _, boost := range result.BoostRooms {
+		pacelock += boost.Pacelock
+	}
+	return pacelock
+}
+
+var quizCommand = &discordgo.ApplicationCommand{
+	Name:        "quiz",
+	Description: "Practice finding the best route of a random seed",
+}
+
+func quizHandler(s Session, i *discordgo.InteractionCreate) {
+	logUserInteraction(i, "command", "quiz")
+
+	state, err := newQuiz()
+	if err != nil {
+		log.Errorf("Failed to draw quiz seed: %v", err)
+		s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+			Type: discordgo.InteractionResponseChannelMessageWithSource,
+			Data: &discordgo.InteractionResponseData{
+				Content: "Go tell the developer he's an idiot 'cause something's broken idk",
+				Flags:   discordgo.MessageFlagsEphemeral,
+			},
+		})
+		return
+	}
+
+	// The quiz is only shown to the user so nobody else answers it
+	err = s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{
+			Embeds:     []*discordgo.MessageEmbed{createQuizEmbed(state)},
+			Components: quizComponents(state),
+			Flags:      discordgo.MessageFlagsEphemeral,
+		},
+	})
+	if err != nil {
+		log.Errorf("Failed to respond to quiz command: %v", err)
+		return
+	}
+
+	if !state.fits() {
+		message, err := s.InteractionResponse(i.Interaction)
+		if err != nil {
+			log.Errorf("Failed to get the quiz message, its menus won't work: %v", err)
+			return
+		}
+		trackQuizMessage(message.ChannelID, message.ID, state)
+	}
+}
+
+func newQuiz() (quizState, error) {
+	rooms, err := calc.DrawSeed(rand.New(rand.NewPCG(rand.Uint64(), rand.Uint64())), calc.RoomMap)
+	if err != nil {
+		return quizState{}, err
+	}
+	return quizState{Rooms: rooms}, nil
+}
+
+// quizComponentHandler handles the menus and buttons of a quiz
+func quizComponentHandler(s Session, i *discordgo.InteractionCreate) {
+	data := i.MessageComponentData()
+	logUserInteraction(i, "button click", data.CustomID)
+
+	update := func(state quizState, embed *discordgo.MessageEmbed, components []discordgo.MessageComponent) {
+		trackQuizMessage(i.ChannelID, i.Message.ID, state)
+		err := s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+			Type: discordgo.InteractionResponseUpdateMessage,
+			Data: &discordgo.InteractionResponseData{
+				Embeds:     []*discordgo.MessageEmbed{embed},
+				Components: components,
+			},
+		})
+		if err != nil {
+			log.Errorf("Failed to update quiz: %v", err)
+		}
+	}
+	broken := func(err error) {
+		log.Error(err)
+		s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+			Type: discordgo.InteractionResponseChannelMessageWithSource,
+			Data: &discordgo.InteractionResponseData{
+				Content: "HA. Buttons not working.",
+				Flags:   discordgo.MessageFlagsEphemeral,
+			},
+		})
+	}
+
+	action, state, encoded, err := decodeQuizID(data.CustomID)
+	if err != nil {
+		broken(err)
+		return
+	}
+	if !encoded {
+		entry, tracked := messageStore.Get(i.Message.ID)
+		if !tracked || entry.Quiz == nil {
+			s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+				Type: discordgo.InteractionResponseChannelMessageWithSource,
+				Data: &discordgo.InteractionResponseData{
+					Content: "This quiz has expired. Run /quiz again.",
+					Flags:   discordgo.MessageFlagsEphemeral,
+				},
+			})
+			return
+		}
+		state = *entry.Quiz
+	}
+
+	switch {
+	case action == "rooms":
+		// Rooms that stay picked keep their strat
+		picks := make([]calc.CalcResultBoost, 0, len(data.Values))
+		for _, value := range data.Values {
+			ind, err := strconv.Atoi(value)
+			if err != nil || ind < 0 || ind > len(state.Rooms) {
+				broken(fmt.Errorf("invalid quiz room %q", value))
+				return
+			}
+			pick := calc.CalcResultBoost{Ind: ind, StratInd: -1}
+			if k := slices.IndexFunc(state.Picks, func(p calc.CalcResultBoost) bool { return p.Ind == ind }); k >= 0 {
+				pick = state.Picks[k]
+			}
+			picks = append(picks, pick)
+		}
+		slices.SortFunc(picks, func(a, b calc.CalcResultBoost) int { return a.Ind - b.Ind })
+		state.Picks = picks
+		update(state, createQuizEmbed(state), quizComponents(state))
+
+	case strings.HasPrefix(action, "strat-"):
+		ind, _ := strconv.Atoi(strings.TrimPrefix(action, "strat-"))
+		k := slices.IndexFunc(state.Picks, func(p calc.CalcResultBoost) bool { return p.Ind == ind })
+		stratInd, err := strconv.Atoi(strings.Join(data.Values, ""))
+		if k < 0 || err != nil || stratInd < 0 || stratInd >= len(calc.RoomMap[state.room(ind)].BoostStrats) {
+			broken(fmt.Errorf("invalid quiz strat %v in %q", data.Values, data.CustomID))
+			return
+		}
+		state.Picks[k].StratInd = stratInd
+		update(state, createQuizEmbed(state), quizComponents(state))
+
+	case action == "submit":
+		if !state.complete() {
+			broken(fmt.Errorf("quiz submitted without a full route %q", data.CustomID))
+			return
+		}
+		score, err := scoreQuizAnswer(state)
+		if err != nil {
+			broken(fmt.Errorf("failed to score quiz seed %v: %w", state.Rooms, err))
+			return
+		}
+
+		userID := interactionUserID(i)
+		stats, err := quizStats.Record(userID, score.Correct())
+		if err != nil {
+			log.Errorf("Failed to save quiz stats of user %s: %v", userID, err)
+		}
+		update(quizState{Rooms: state.Rooms}, createQuizResultEmbed(state, score, stats), quizNextComponents(state))
+
+	case action == "next":
+		next, err := newQuiz()
+		if err != nil {
+			broken(fmt.Errorf("failed to draw quiz seed: %w", err))
+			return
+		}
+		update(next, createQuizEmbed(next), quizComponents(next))
+
+	default:
+		broken(fmt.Errorf("unknown quiz action %q", action))
+	}
+}
+
+func createQuizEmbed(state quizState) *discordgo.MessageEmbed {
+	var rooms strings.Builder
+	for k, room := range state.Rooms {
+		rooms.WriteString(fmt.Sprintf("**%d.** %s\n", k+1, room))
+	}
+	rooms.WriteString("\nPick 2 or 3 rooms to boost, then a strat for each.")
+
+	return &discordgo.MessageEmbed{
+		Title:       "What's the best route?",
+		Description: rooms.String(),
+		Color:       0x45D3B3,
+	}
+}
+
+func createQuizResultEmbed(state quizState, score QuizScore, stats QuizStats) *discordgo.MessageEmbed {
+	title := fmt.Sprintf("#%d of %d routes, %.1fs slower than the best", score.Rank, score.Routes, score.Lost)
+	if score.Correct() {
+		title = "Nailed it, that's the best route!"
+	}
+
+	fields := []*discordgo.MessageEmbedField{
+		{Name: "Your route", Value: formatQuizRoute(state, score.Answer)},
+	}
+	if !score.Correct() {
+		fields = append(fields, &discordgo.MessageEmbedField{Name: "Best route", Value: formatQuizRoute(state, score.Best)})
+	}
+	if !score.Correct() && score.ExtraPacelock > quizTolerance {
+		fields = append(fields, &discordgo.MessageEmbedField{
+			Name:  "Pacelock",
+			Value: fmt.Sprintf("You waited %.1fs longer for pacelock than the best route, space your boosts out more.", score.ExtraPacelock),
+		})
+	}
+
+	return &discordgo.MessageEmbed{
+		Title:       title,
+		Description: strings.Join(state.Rooms, ", "),
+		Color:       0x45D3B3,
+		Fields:      fields,
+		Footer: &discordgo.MessageEmbedFooter{
+			Text: fmt.Sprintf("Streak %d (best %d), %.0f%% right over %d quizzes",
+				stats.Streak, stats.BestStreak, stats.Accuracy()*100, stats.Played),
+		},
+	}
+}
+
+// quizComponents are a menu to pick the boosted rooms, one to pick the strat
+// of each of them and the submit button
+func quizComponents(state quizState) []discordgo.MessageComponent {
+	picked := func(ind int) bool {
+		return slices.ContainsFunc(state.Picks, func(p calc.CalcResultBoost) bool { return p.Ind == ind })
+	}
+
+	roomChoices := make([]discordgo.SelectMenuOption, 0, len(state.Rooms)+1)
+	for ind := 0; ind <= len(state.Rooms); ind++ {
+		label := "Finish room"
+		if ind < len(state.Rooms) {
+			label = fmt.Sprintf("%d. %s", ind+1, state.Rooms[ind])
+		}
+		roomChoices = append(roomChoices, discordgo.SelectMenuOption{
+			Label:   label,
+			Value:   strconv.Itoa(ind),
+			Default: picked(ind),
+		})
+	}
+
+	minRooms := 2
+	components := []discordgo.MessageComponent{
+		discordgo.ActionsRow{
+			Components: []discordgo.MessageComponent{
+				discordgo.SelectMenu{
+					MenuType:    discordgo.StringSelectMenu,
+					CustomID:    state.customID("rooms"),
+					Placeholder: "Rooms to boost",
+					MinValues:   &minRooms,
+					MaxValues:   3,
+					Options:     roomChoices,
+				},
+			},
+		},
+	}
+
+	for _, pick := range state.Picks {
+		room := calc.RoomMap[state.room(pick.Ind)]
+		stratOptions := make([]discordgo.SelectMenuOption, 0, len(room.BoostStrats))
+		for k, strat := range room.BoostStrats {
+			stratOptions = append(stratOptions, discordgo.SelectMenuOption{
+				Label:       strat.Name,
+				Value:       strconv.Itoa(k),
+				Description: fmt.Sprintf("%.2fs, boost at %.2fs", strat.Time, strat.BoostTime),
+				Default:     k == pick.StratInd,
+			})
+		}
+		components = append(components, discordgo.ActionsRow{
+			Components: []discordgo.MessageComponent{
+				discordgo.SelectMenu{
+					MenuType:    discordgo.StringSelectMenu,
+					CustomID:    state.customID(fmt.Sprintf("strat-%d", pick.Ind)),
+					Placeholder: fmt.Sprintf("Strat in %s", room.Name),
+					Options:     stratOptions,
+				},
+			},
+		})
+	}
+
+	components = append(components, discordgo.ActionsRow{
+		Components: []discordgo.MessageComponent{
+			discordgo.Button{
+				Label:    "Submit",
+				CustomID: state.customID("submit"),
+				Style:    discordgo.SuccessButton,
+				Disabled: !state.complete(),
+			},
+		},
+	})
+	return components
+}
+
+func quizNextComponents(state quizState) []discordgo.MessageComponent {
+	return []discordgo.MessageComponent{
+		discordgo.ActionsRow{
+			Components: []discordgo.MessageComponent{
+				discordgo.Button{
+					Label:    "Next seed",
+					CustomID: quizState{Rooms: state.Rooms}.customID("next"),
+					Style:    discordgo.PrimaryButton,
+					Emoji: &discordgo.ComponentEmoji{
+						Name: "➡️",
+					},
+				},
+			},
+		},
+	}
+}
@@ -0,0 +1,140 @@
+package discord
+
+import (
+	"math/rand/v2"
+	"slices"
+	"strings"
+	"testing"
+
+	"atlantis_calc/calc"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+func TestQuizIDRoundTrip(t *testing.T) {
+	rooms := []string{"1a", "2b", "3c", "4e", "5a", "1c", "2f", "3g"}
+	states := []quizState{
+		{Rooms: rooms},
+		{Rooms: rooms, Picks: []calc.CalcResultBoost{{Ind: 0, StratInd: -1}}},
+		{Rooms: rooms, Picks: []calc.CalcResultBoost{{Ind: 1, StratInd: 0}, {Ind: 4, StratInd: 2}, {Ind: 8, StratInd: 0}}},
+	}
+
+	for _, state := range states {
+		if !state.fits() {
+			t.Fatalf("%+v doesn't fit", state)
+		}
+		customID := state.customID(quizLongestAction)
+		if len(customID) > customIDMaxLength {
+			t.Errorf("%q is %d characters long", customID, len(customID))
+		}
+
+		action, decoded, encoded, err := decodeQuizID(customID)
+		if err != nil || !encoded || action != quizLongestAction {
+			t.Fatalf("decoding %q: action %q, encoded %v, err %v", customID, action, encoded, err)
+		}
+		if !slices.Equal(decoded.Rooms, state.Rooms) || !slices.Equal(decoded.Picks, state.Picks) {
+			t.Errorf("%q decoded to %+v, want %+v", customID, decoded, state)
+		}
+	}
+}
+
+func TestQuizIDFallsBackWhenTooLong(t *testing.T) {
+	longRooms := []string{"early 3+1 skip", "overhead 4b", "rng skip", "underbridge", "four towers", "castle wall", "sandpit", "quad ladder"}
+	commaRooms := []string{"1a,2b", "3c", "4e", "5a", "1c", "2f", "3g", "4a"}
+
+	for _, rooms := range [][]string{longRooms, commaRooms} {
+		state := quizState{Rooms: rooms}
+		if state.fits() {
+			t.Errorf("%v fits", rooms)
+		}
+		customID := state.customID("submit")
+		if customID != quizComponentPrefix+"|submit" {
+			t.Errorf("got %q, want the bare action", customID)
+		}
+		action, _, encoded, err := decodeQuizID(customID)
+		if err != nil || encoded || action != "submit" {
+			t.Errorf("decoding %q: action %q, encoded %v, err %v", customID, action, encoded, err)
+		}
+	}
+
+	for _, customID := range []string{"quiz", "other|submit", "quiz|submit|1a,2b|", "quiz|submit|1a,2b,3c,4e,5a,1c,2f,3g|x.1"} {
+		if _, _, _, err := decodeQuizID(customID); err == nil {
+			t.Errorf("decoded %q", customID)
+		}
+	}
+}
+
+// pacelockedSeed finds a seed whose best route waits for pacelock
+func pacelockedSeed(t *testing.T) ([]string, []calc.CalcSeedResult) {
+	t.Helper()
+
+	for seed := uint64(0); seed < 200; seed++ {
+		rooms, err := calc.DrawSeed(rand.New(rand.NewPCG(seed, 0)), calc.RoomMap)
+		if err != nil {
+			t.Fatal(err)
+		}
+		results, err := calc.CalcSeed(slices.Clone(rooms))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if totalPacelock(results[0]) > 0 {
+			return rooms, results
+		}
+	}
+	t.Fatal("no seed has a pacelocked best route")
+	return nil, nil
+}
+
+// picksOf answers a quiz with a route of the calc
+func picksOf(result calc.CalcSeedResult) []calc.CalcResultBoost {
+	picks := make([]calc.CalcResultBoost, len(result.BoostRooms))
+	for k, boost := range result.BoostRooms {
+		picks[k] = calc.CalcResultBoost{Ind: boost.Ind, StratInd: boost.StratInd}
+	}
+	return picks
+}
+
+func TestScoreQuizAnswer(t *testing.T) {
+	rooms, results := pacelockedSeed(t)
+
+	best, err := scoreQuizAnswer(quizState{Rooms: rooms, Picks: picksOf(results[0])})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !best.Correct() || best.Rank != 1 || best.ExtraPacelock != 0 || best.Routes != len(results) {
+		t.Errorf("best route scored %+v", best)
+	}
+	embed := createQuizResultEmbed(quizState{Rooms: rooms}, best, QuizStats{})
+	for _, field := range embed.Fields {
+		if field.Name == "Pacelock" {
+			t.Errorf("the best route was told about pacelock: %q", field.Value)
+		}
+	}
+
+	worst, err := scoreQuizAnswer(quizState{Rooms: rooms, Picks: picksOf(results[len(results)-1])})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if worst.Correct() || worst.Rank == 1 || worst.Lost <= 0 {
+		t.Errorf("worst route scored %+v", worst)
+	}
+	if want := totalPacelock(worst.Answer) - totalPacelock(results[0]); worst.ExtraPacelock != want {
+		t.Errorf("got %.2fs extra pacelock, want %.2fs", worst.ExtraPacelock, want)
+	}
+
+	if _, err := scoreQuizAnswer(quizState{Rooms: rooms, Picks: []calc.CalcResultBoost{{Ind: 0, StratInd: 9}, {Ind: 1, StratInd: 0}}}); err == nil {
+		t.Error("scored a strat the room doesn't have")
+	}
+}
+
+func TestQuizResultEmbedPacelock(t *testing.T) {
+	rooms := []string{"1a", "2b", "3c", "4e", "5a", "1c", "2f", "3g"}
+	state := quizState{Rooms: rooms}
+	route := calc.CalcSeedResult{BoostTime: 130, BoostRooms: []calc.CalcResultBoost{{Ind: 0}, {Ind: 1, Pacelock: 3}}}
+
+	score := QuizScore{Answer: route, Best: route, Rank: 2, Routes: 10, Lost: 3, ExtraPacelock: 3}
+	embed := createQuizResultEmbed(state, score, QuizStats{})
+	if !slices.ContainsFunc(embed.Fields, func(f *discordgo.MessageEmbedField) bool { return strings.Contains(f.Value, "3.0s longer") }) {
+		t.Errorf("a slow answer wasn't told about its extra pacelock: %+v", embed.Fields)
+	}
+}
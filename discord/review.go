@@ -0,0 +1,272 @@
+package discord
+
+import (
+	"bytes"
+	"fmt"
+	"image/color"
+	"strings"
+
+	"atlantis_calc/calc"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/fogleman/gg"
+	log "github.com/sirupsen/logrus"
+)
+
+// gradeColors highlight the decisions of a review like a chess game review
+var gradeColors = map[calc.ReviewGrade]color.Color{
+	calc.GradeBest:       color.NRGBA{155, 199, 0, 200},
+	calc.GradeGood:       color.NRGBA{120, 160, 110, 200},
+	calc.GradeInaccuracy: color.NRGBA{230, 180, 50, 200},
+	calc.GradeMistake:    color.NRGBA{230, 120, 40, 200},
+	calc.GradeBlunder:    color.NRGBA{200, 50, 50, 200},
+}
+
+var reviewCommand = &discordgo.ApplicationCommand{
+	Name:        "review",
+	Description: "Review the decisions of a run like a chess game",
+	Options: append(generateOptions(),
+		&discordgo.ApplicationCommandOption{
+			Type:        discordgo.ApplicationCommandOptionString,
+			Name:        "route",
+			Description: "The 2 or 3 boosts you did separated by commas like 2a cp 1-2, 3b cp 1-2",
+			Required:    true,
+		},
+		&discordgo.ApplicationCommandOption{
+			Type:        discordgo.ApplicationCommandOptionString,
+			Name:        "splits",
+			Description: "Your time in each room and the finish room separated by commas like 13.2, 15.0, ...",
+			Required:    true,
+		},
+	),
+}
+
+func reviewHandler(s Session, i *discordgo.InteractionCreate) {
+	logUserInteraction(i, "command", "review")
+
+	respond := func(content string) {
+		err := s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+			Type: discordgo.InteractionResponseChannelMessageWithSource,
+			Data: &discordgo.InteractionResponseData{
+				Content: content,
+				Flags:   discordgo.MessageFlagsEphemeral,
+			},
+		})
+		if err != nil {
+			log.Errorf("Failed to respond to review command: %v", err)
+		}
+	}
+
+	selected := make([]string, 0, 8)
+	routeText, splitsText := "", ""
+	for _, option := range i.ApplicationCommandData().Options {
+		switch {
+		case strings.HasPrefix(option.Name, "room_"):
+			selected = append(selected, option.StringValue())
+		case option.Name == "route":
+			routeText = option.StringValue()
+		case option.Name == "splits":
+			splitsText = option.StringValue()
+		}
+	}
+
+	if valid, err := validateInput(selected); !valid {
+		respond(err.Error())
+		return
+	}
+
+	route, err := parseRoute(routeText, selected)
+	if err != nil {
+		respond(err.Error())
+		return
+	}
+
+	splitTimes, err := parseSplitTimes(splitsText)
+	if err != nil {
+		respond(err.Error())
+		return
+	}
+	if len(splitTimes) != len(selected)+1 {
+		respond(fmt.Sprintf("I need %d split times, one per room and the finish room, you gave %d.", len(selected)+1, len(splitTimes)))
+		return
+	}
+
+	review, err := calc.ReviewRun(selected, route, splitTimes, calc.RoomMap)
+	if err != nil {
+		respond(fmt.Sprintf("That route doesn't work: %v.", err))
+		return
+	}
+
+	img, err := drawReview(review)
+	if err != nil {
+		log.Errorf("Failed to draw review: %v", err)
+		respond("Go tell the developer he's an idiot 'cause something's broken idk")
+		return
+	}
+
+	err = s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{
+			Content: formatReview(review),
+			Files: []*discordgo.File{
+				{
+					Name:   "review.png",
+					Reader: bytes.NewReader(img.Bytes()),
+				},
+			},
+		},
+	})
+	if err != nil {
+		log.Errorf("Failed to send review: %v", err)
+	}
+}
+
+// parseSplitTimes reads times separated by commas
+func parseSplitTimes(text string) ([]float64, error) {
+	var times []float64
+	for _, part := range strings.Split(text, ",") {
+		if strings.TrimSpace(part) == "" {
+			continue
+		}
+		t, err := ParseTime(part)
+		if err != nil {
+			return nil, err
+		}
+		times = append(times, t)
+	}
+	return times, nil
+}
+
+// reviewDecision names the decision of a move, the strat or no boost
+func reviewDecision(room string, stratInd int) string {
+	if stratInd < 0 {
+		return "no boost"
+	}
+	return calc.RoomMap[room].BoostStrats[stratInd].Name
+}
+
+// formatReview lists the decisions that lost time and what was better
+func formatReview(review calc.Review) string {
+	var content strings.Builder
+	content.WriteString(fmt.Sprintf("Accuracy **%.0f%%**, %s on a %s route (best %s).",
+		review.Accuracy, FormatTime(review.Actual), FormatTime(review.Route.Result.BoostTime), FormatTime(review.Route.Best.BoostTime)))
+
+	mistakes := 0
+	for k, move := range review.Moves {
+		if move.Grade == calc.GradeBest {
+			continue
+		}
+		room := review.Rooms[k]
+		content.WriteString(fmt.Sprintf("\n**%s**: %s (%s), %.1fs lost, %s was better",
+			room, move.Grade, reviewDecision(room, move.StratInd), move.Lost, reviewDecision(room, move.BestStratInd)))
+		mistakes++
+	}
+	if mistakes == 0 {
+		content.WriteString("\nNo mistakes, every decision was the best one.")
+	}
+	return content.String()
+}
+
+// drawReview renders every room of the run with its decision highlighted by
+// its grade, the run time and how it compares to the splits
+func drawReview(review calc.Review) (bytes.Buffer, error) {
+	const (
+		rowHeight  = 40.0
+		cellHeight = 30.0
+		roomX      = 200.0
+		gradeX     = 470.0
+		actualX    = 620.0
+		deltaX     = 750.0
+		roomWidth  = 340.0
+		gradeWidth = 170.0
+		timeWidth  = 110.0
+	)
+
+	width := 880
+	height := 130 + int(rowHeight)*len(review.Rooms) + 50
+
+	dc := gg.NewContext(width, height)
+	if err := drawBackground(dc); err != nil {
+		log.Warn(err)
+		return bytes.Buffer{}, err
+	}
+
+	if err := dc.LoadFontFace(assetPath("font/minecraft_font.ttf"), 24); err != nil {
+		log.Warn(err)
+		return bytes.Buffer{}, err
+	}
+
+	dc.SetColor(color.White)
+	dc.DrawStringAnchored(fmt.Sprintf("Game review, %.0f%% accuracy", review.Accuracy), float64(width)/2, 40, 0.5, 0.5)
+
+	if err := dc.LoadFontFace(assetPath("font/minecraft_font.ttf"), 18); err != nil {
+		log.Warn(err)
+		return bytes.Buffer{}, err
+	}
+
+	dc.DrawStringAnchored("Decision", roomX, 85, 0.5, 0.5)
+	dc.DrawStringAnchored("Grade", gradeX, 85, 0.5, 0.5)
+	dc.DrawStringAnchored("Time", actualX, 85, 0.5, 0.5)
+	dc.DrawStringAnchored("Splits", deltaX, 85, 0.5, 0.5)
+
+	drawCell := func(x, y, cellWidth float64, fill color.Color, text string, textColor color.Color) {
+		dc.SetColor(fill)
+		dc.DrawRoundedRectangle(x-cellWidth/2, y-cellHeight/2, cellWidth, cellHeight, 10)
+		dc.Fill()
+
+		dc.SetColor(textColor)
+		dc.DrawStringAnchored(text, x, y, 0.5, 0.5)
+	}
+
+	// Rooms without a choice and rooms that were just run through stay plain
+	// unless skipping a boost there lost time
+	plain := color.RGBA{0, 0, 0, 128}
+	y := 130.0
+	for k, move := range review.Moves {
+		room := review.Rooms[k]
+		text := strings.ToUpper(room[:1]) + room[1:]
+		fill := color.Color(plain)
+		if move.Decision && (move.StratInd >= 0 || move.Grade != calc.GradeBest) {
+			fill = gradeColors[move.Grade]
+		}
+		if move.StratInd >= 0 {
+			text = fmt.Sprintf("%s (%s)", text, reviewDecision(room, move.StratInd))
+		}
+		drawCell(roomX, y, roomWidth, fill, text, color.White)
+
+		grade := move.Grade.String()
+		if !move.Decision {
+			grade = "forced"
+		} else if move.Grade != calc.GradeBest {
+			grade = fmt.Sprintf("%s -%.1f", grade, move.Lost)
+		}
+		drawCell(gradeX, y, gradeWidth, fill, grade, color.White)
+		drawCell(actualX, y, timeWidth, plain, fmt.Sprintf("%.1f", move.Actual), color.White)
+
+		delta := move.Actual - move.Expected
+		deltaColor := color.Color(color.White)
+		switch {
+		case delta < -0.05:
+			deltaColor = aheadColor
+		case delta > 0.05:
+			deltaColor = behindColor
+		}
+		drawCell(deltaX, y, timeWidth, plain, fmt.Sprintf("%+.1f", delta), deltaColor)
+
+		y += rowHeight
+	}
+
+	if err := dc.LoadFontFace(assetPath("font/minecraft_font.ttf"), 24); err != nil {
+		log.Warn(err)
+		return bytes.Buffer{}, err
+	}
+
+	y += 10
+	dc.SetColor(chartLineColor)
+	dc.DrawStringAnchored(fmt.Sprintf("%s, route %s, best %s", FormatTime(review.Actual),
+		FormatTime(review.Route.Result.BoostTime), FormatTime(review.Route.Best.BoostTime)), float64(width)/2, y, 0.5, 0.5)
+
+	var buf bytes.Buffer
+	dc.EncodePNG(&buf)
+	return buf, nil
+}
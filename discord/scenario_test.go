@@ -0,0 +1,215 @@
+package discord
+
+import (
+	"slices"
+	"strings"
+	"testing"
+
+	"atlantis_calc/config"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+var scenarioRooms = []string{"1a", "2b", "3c", "4e", "5a", "1c", "2f", "3g"}
+
+// setupScenario sets the handlers up against a fake session for the guilds,
+// with an empty data directory
+func setupScenario(t *testing.T, guildIDs ...string) *FakeSession {
+	t.Helper()
+
+	c := config.Default()
+	c.DataDir = t.TempDir()
+	c.AssetDir = ".."
+
+	fs := NewFakeSession(guildIDs...)
+	if err := Setup(fs, &c); err != nil {
+		t.Fatalf("setup failed: %v", err)
+	}
+	t.Cleanup(func() {
+		messageStore.Close(nil)
+		seedHistory.Close()
+		fs.Close()
+	})
+	return fs
+}
+
+func scenarioUser() *discordgo.Member {
+	return &discordgo.Member{User: &discordgo.User{ID: "user", Username: "player"}}
+}
+
+func commandInteraction(id, channelID, name string, options ...*discordgo.ApplicationCommandInteractionDataOption) *discordgo.InteractionCreate {
+	return &discordgo.InteractionCreate{Interaction: &discordgo.Interaction{
+		ID:        id,
+		Type:      discordgo.InteractionApplicationCommand,
+		ChannelID: channelID,
+		Member:    scenarioUser(),
+		Data:      discordgo.ApplicationCommandInteractionData{Name: name, Options: options},
+	}}
+}
+
+func componentInteraction(id string, message *discordgo.Message, customID string) *discordgo.InteractionCreate {
+	return &discordgo.InteractionCreate{Interaction: &discordgo.Interaction{
+		ID:        id,
+		Type:      discordgo.InteractionMessageComponent,
+		ChannelID: message.ChannelID,
+		Member:    scenarioUser(),
+		Message:   message,
+		Data:      discordgo.MessageComponentInteractionData{CustomID: customID, ComponentType: discordgo.ButtonComponent},
+	}}
+}
+
+func calcRoomOptions(rooms []string) []*discordgo.ApplicationCommandInteractionDataOption {
+	options := make([]*discordgo.ApplicationCommandInteractionDataOption, len(rooms))
+	for k, room := range rooms {
+		options[k] = &discordgo.ApplicationCommandInteractionDataOption{
+			Name:  "room_" + string(rune('1'+k)),
+			Type:  discordgo.ApplicationCommandOptionString,
+			Value: room,
+		}
+	}
+	return options
+}
+
+// findButton returns the custom ID of the first button of a message whose ID starts with prefix
+func findButton(t *testing.T, message *discordgo.Message, prefix string) string {
+	t.Helper()
+
+	for _, row := range message.Components {
+		actions, ok := row.(discordgo.ActionsRow)
+		if !ok {
+			continue
+		}
+		for _, component := range actions.Components {
+			if button, ok := component.(discordgo.Button); ok && strings.HasPrefix(button.CustomID, prefix) {
+				return button.CustomID
+			}
+		}
+	}
+	t.Fatalf("message %s has no button starting with %q", message.ID, prefix)
+	return ""
+}
+
+func TestCalcScenario(t *testing.T) {
+	fs := setupScenario(t, "guild")
+
+	HandleInteraction(fs, commandInteraction("1", "channel", "calc", calcRoomOptions(scenarioRooms)...))
+
+	message, exists := fs.Messages["interaction-1"]
+	if !exists {
+		t.Fatalf("/calc didn't respond, calls: %+v", fs.Calls())
+	}
+	if len(message.Attachments) != 1 || message.Attachments[0].Filename != "result.png" {
+		t.Fatalf("expected the result image, got %+v", message.Attachments)
+	}
+	if _, tracked := messageStore.Get(message.ID); !tracked {
+		t.Errorf("the calc message isn't in the state store")
+	}
+
+	next := findButton(t, message, ButtonNext+"|")
+	fs.Reset()
+	HandleInteraction(fs, componentInteraction("2", message, next))
+
+	edits := fs.Calls("ChannelMessageEditComplex")
+	if len(edits) != 1 {
+		t.Fatalf("expected the message to be edited once, calls: %+v", fs.Calls())
+	}
+	if len(edits[0].Edit.Files) != 1 {
+		t.Errorf("expected a new image, got %d files", len(edits[0].Edit.Files))
+	}
+
+	message = fs.Messages[message.ID]
+	findButton(t, message, ButtonPrevious+"|any|1|")
+	if len(message.Attachments) != 1 {
+		t.Errorf("expected the new image to replace the old one, got %+v", message.Attachments)
+	}
+}
+
+func TestExpireMessageScenario(t *testing.T) {
+	fs := setupScenario(t, "guild")
+
+	HandleInteraction(fs, commandInteraction("1", "channel", "calc", calcRoomOptions(scenarioRooms)...))
+	message := fs.Messages["interaction-1"]
+
+	state := &ResultState{Rooms: scenarioRooms, Filter: ButtonAnyBoost, KeepShowCalc: true}
+	expireMessage(message.ID, MessageState{ChannelID: message.ChannelID, Result: state})
+
+	message = fs.Messages[message.ID]
+	if len(message.Components) != 1 {
+		t.Fatalf("expected only the ShowCalc button to be left, got %+v", message.Components)
+	}
+	findButton(t, message, ButtonShowCalc)
+	if len(message.Attachments) != 1 {
+		t.Fatalf("expected the image to stay attached, got %+v", message.Attachments)
+	}
+	if data, ok := fs.File(message.Attachments[0]); !ok || len(data) == 0 {
+		t.Errorf("the image was not uploaded again")
+	}
+
+	entry, tracked := messageStore.Get(message.ID)
+	if !tracked || !entry.ShowCalcOnly {
+		t.Errorf("expected the ShowCalc button to be tracked, got %+v", entry)
+	}
+}
+
+func TestAnnouncementScenario(t *testing.T) {
+	fs := setupScenario(t, "announcing", "slow", "opted-out")
+	fs.Channels = []*discordgo.Channel{
+		{ID: "announcing-channel", GuildID: "announcing", Name: defaultAnnouncementChannel, Type: discordgo.ChannelTypeGuildText},
+		{ID: "slow-channel", GuildID: "slow", Name: defaultAnnouncementChannel, Type: discordgo.ChannelTypeGuildText},
+		{ID: "opted-out-channel", GuildID: "opted-out", Name: defaultAnnouncementChannel, Type: discordgo.ChannelTypeGuildText},
+	}
+
+	configs := map[string]GuildConfig{
+		"announcing": {Threshold: 1000, Features: map[string]bool{FeatureAnnouncements: true}},
+		"slow":       {Threshold: 1, Features: map[string]bool{FeatureAnnouncements: true}},
+		"opted-out":  {Threshold: 1000, Features: map[string]bool{FeatureAnnouncements: false}},
+	}
+	for guildID, config := range configs {
+		if _, err := guildConfigs.Update(guildID, func(c *GuildConfig) { *c = config }); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	rooms := []string{"1A", "2B", "3C", "4E", "5A", "1C", "2F", "3G"}
+	result, boostRooms, err := ChattriggersHandle(slices.Clone(rooms), "1:00", "lobby", "player", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(boostRooms) != len(result.BoostRooms) {
+		t.Errorf("expected a name for each of the %d boosts, got %+v", len(result.BoostRooms), boostRooms)
+	}
+
+	sends := fs.Calls("ChannelMessageSendComplex")
+	if len(sends) != 1 || sends[0].ChannelID != "announcing-channel" {
+		t.Fatalf("expected one announcement in announcing-channel, got %+v", sends)
+	}
+
+	var announcement *discordgo.Message
+	for _, message := range fs.Messages {
+		if message.ChannelID == "announcing-channel" {
+			announcement = message
+		}
+	}
+	if announcement == nil || len(announcement.Attachments) != 1 || announcement.Attachments[0].Filename != "seed.png" {
+		t.Fatalf("expected the announcement to have the seed image, got %+v", announcement)
+	}
+	findButton(t, announcement, ButtonShowCalc+"|")
+
+	records := seedHistory.Find(func(SeedRecord) bool { return true })
+	if len(records) != 1 || !records[0].Announced {
+		t.Errorf("expected the seed to be recorded as announced, got %+v", records)
+	}
+
+	// Another player reporting the same seed is recorded but not announced again
+	fs.Reset()
+	if _, _, err := ChattriggersHandle(slices.Clone(rooms), "0:50", "lobby", "other", false); err != nil {
+		t.Fatal(err)
+	}
+	if sends := fs.Calls("ChannelMessageSendComplex"); len(sends) != 0 {
+		t.Errorf("the seed was announced again: %+v", sends)
+	}
+	records = seedHistory.Find(func(r SeedRecord) bool { return r.IGN == "other" })
+	if len(records) != 1 || records[0].Announced {
+		t.Errorf("expected the second report to be recorded unannounced, got %+v", records)
+	}
+}
@@ -7,6 +7,8 @@ import (
 	"image/color"
 	"math"
 	"os"
+	"path/filepath"
+	"strconv"
 	"strings"
 
 	"atlantis_calc/calc"
@@ -21,13 +23,44 @@ var (
 	brilliantMoveColor = color.RGBA{48, 162, 197, 200}
 )
 
+// moveQualityColor is the highlight color of a boost with the given quality
+func moveQualityColor(quality calc.MoveQuality) color.RGBA {
+	switch quality {
+	case calc.BrilliantMove:
+		return brilliantMoveColor
+	case calc.GreatMove:
+		return greatMoveColor
+	default:
+		return bestMoveColor
+	}
+}
+
+// stratAnalysis is the analysis of every strat by room, Setup computes it
+var stratAnalysis map[string][]calc.StratAnalysis
+
+// stratQuality is the computed label of a strat, or the curated one when
+// the strat wasn't analyzed
+func stratQuality(room string, stratInd int) calc.MoveQuality {
+	if strats := stratAnalysis[room]; stratInd < len(strats) {
+		return strats[stratInd].Quality
+	}
+	return calc.RoomMap[room].BoostStrats[stratInd].Quality
+}
+
+// AssetDir is the directory containing the font and images directories
+var AssetDir = "."
+
+func assetPath(name string) string {
+	return filepath.Join(AssetDir, name)
+}
+
 func drawCalcResults(roomList []string, calcResults []calc.CalcSeedResult) (bytes.Buffer, error) {
 	if roomList[len(roomList)-1] != "finish room" {
 		roomList = append(roomList, "finish room")
 	}
 
 	tempDC := gg.NewContext(1, 1)
-	if err := tempDC.LoadFontFace("font/minecraft_font.ttf", 24); err != nil {
+	if err := tempDC.LoadFontFace(assetPath("font/minecraft_font.ttf"), 24); err != nil {
 		log.Warn(err)
 		return bytes.Buffer{}, err
 	}
@@ -53,7 +86,7 @@ func drawCalcResults(roomList []string, calcResults []calc.CalcSeedResult) (byte
 
 	dc := gg.NewContext(width, height)
 
-	bgFile, err := os.Open("images/background.png")
+	bgFile, err := os.Open(assetPath("images/background.png"))
 	if err != nil {
 		log.Warn(err)
 		return bytes.Buffer{}, err
@@ -82,7 +115,7 @@ func drawCalcResults(roomList []string, calcResults []calc.CalcSeedResult) (byte
 	dc.DrawImage(bgImage, int(x/scale), int(y/scale))
 	dc.Pop()
 
-	if err := dc.LoadFontFace("font/minecraft_font.ttf", 24); err != nil {
+	if err := dc.LoadFontFace(assetPath("font/minecraft_font.ttf"), 24); err != nil {
 		log.Warn(err)
 		return bytes.Buffer{}, err
 	}
@@ -111,7 +144,7 @@ func drawCalcResults(roomList []string, calcResults []calc.CalcSeedResult) (byte
 	for _, br := range res.BoostRooms {
 		roomsOutput[br.Ind].highlight = true
 		roomsOutput[br.Ind].checkpoint = calc.RoomMap[roomList[br.Ind]].BoostStrats[br.StratInd].Name
-		roomsOutput[br.Ind].moveQuality = calc.RoomMap[roomList[br.Ind]].BoostStrats[br.StratInd].Quality
+		roomsOutput[br.Ind].moveQuality = stratQuality(roomList[br.Ind], br.StratInd)
 		if math.Abs(br.Pacelock) >= 1e-6 {
 			roomsOutput[br.Ind].pacelock = fmt.Sprintf("pacelock %.1fs", math.Round(br.Pacelock*10)/10)
 		}
@@ -146,23 +179,11 @@ func drawCalcResults(roomList []string, calcResults []calc.CalcSeedResult) (byte
 		if room.highlight {
 			// First draw background with move quality color
 			dc.Push()
-			switch room.moveQuality {
-			case calc.BrilliantMove:
-				dc.SetRGBA(float64(brilliantMoveColor.R)/255,
-					float64(brilliantMoveColor.G)/255,
-					float64(brilliantMoveColor.B)/255,
-					float64(brilliantMoveColor.A)/255)
-			case calc.GreatMove:
-				dc.SetRGBA(float64(greatMoveColor.R)/255,
-					float64(greatMoveColor.G)/255,
-					float64(greatMoveColor.B)/255,
-					float64(greatMoveColor.A)/255)
-			default: // BestMove
-				dc.SetRGBA(float64(bestMoveColor.R)/255,
-					float64(bestMoveColor.G)/255,
-					float64(bestMoveColor.B)/255,
-					float64(bestMoveColor.A)/255)
-			}
+			fill := moveQualityColor(room.moveQuality)
+			dc.SetRGBA(float64(fill.R)/255,
+				float64(fill.G)/255,
+				float64(fill.B)/255,
+				float64(fill.A)/255)
 			dc.DrawRoundedRectangle(rectX, rectY, rectWidth, rectHeight, 10)
 			dc.Fill()
 			dc.Pop()
@@ -170,7 +191,7 @@ func drawCalcResults(roomList []string, calcResults []calc.CalcSeedResult) (byte
 			// Draw icon based on move quality
 			switch room.moveQuality {
 			case calc.BrilliantMove:
-				if img, err := gg.LoadImage("images/brilliant.png"); err == nil {
+				if img, err := gg.LoadImage(assetPath("images/brilliant.png")); err == nil {
 					iconSize := rectHeight
 					imgHeight := float64(img.Bounds().Dy())
 					scale := iconSize / imgHeight
@@ -182,7 +203,7 @@ func drawCalcResults(roomList []string, calcResults []calc.CalcSeedResult) (byte
 					dc.Pop()
 				}
 			case calc.GreatMove:
-				if img, err := gg.LoadImage("images/great.png"); err == nil {
+				if img, err := gg.LoadImage(assetPath("images/great.png")); err == nil {
 					iconSize := rectHeight
 					imgHeight := float64(img.Bounds().Dy())
 					scale := iconSize / imgHeight
@@ -194,7 +215,7 @@ func drawCalcResults(roomList []string, calcResults []calc.CalcSeedResult) (byte
 					dc.Pop()
 				}
 			default: // BestMove
-				if img, err := gg.LoadImage("images/best.png"); err == nil {
+				if img, err := gg.LoadImage(assetPath("images/best.png")); err == nil {
 					iconSize := rectHeight
 					imgHeight := float64(img.Bounds().Dy())
 					scale := iconSize / imgHeight
@@ -281,3 +302,27 @@ func FormatTime(seconds float64) string {
 	}
 	return fmt.Sprintf("%.1f", remainingSeconds)
 }
+
+// ParseTime parses a time written as "m:ss.s" or as plain seconds, the
+// inverse of FormatTime
+func ParseTime(text string) (float64, error) {
+	text = strings.TrimSpace(text)
+
+	minutesText, secondsText, hasMinutes := strings.Cut(text, ":")
+	if !hasMinutes {
+		secondsText = minutesText
+		minutesText = "0"
+	}
+
+	minutes, err := strconv.Atoi(minutesText)
+	if err != nil || minutes < 0 {
+		return 0, fmt.Errorf("\"%s\" is not a valid time, use something like 2:05.3", text)
+	}
+
+	seconds, err := strconv.ParseFloat(secondsText, 64)
+	if err != nil || math.IsNaN(seconds) || math.IsInf(seconds, 0) || seconds < 0 || (hasMinutes && seconds >= 60) {
+		return 0, fmt.Errorf("\"%s\" is not a valid time, use something like 2:05.3", text)
+	}
+
+	return float64(minutes*60) + seconds, nil
+}
@@ -0,0 +1,40 @@
+package discord
+
+import "testing"
+
+func TestParseTime(t *testing.T) {
+	tests := []struct {
+		text    string
+		seconds float64
+		valid   bool
+	}{
+		{"2:05.3", 125.3, true},
+		{" 59.9 ", 59.9, true},
+		{"75", 75, true},
+		{"0:00", 0, true},
+		{"1:60", 0, false},
+		{"-1:00", 0, false},
+		{"-5", 0, false},
+		{"abc", 0, false},
+		{"", 0, false},
+		{"NaN", 0, false},
+		{"1:NaN", 0, false},
+		{"Inf", 0, false},
+		{"inf", 0, false},
+		{"+Inf", 0, false},
+		{"-Inf", 0, false},
+		{"1:inf", 0, false},
+	}
+
+	for _, test := range tests {
+		seconds, err := ParseTime(test.text)
+		switch {
+		case test.valid && err != nil:
+			t.Errorf("%q: unexpected error: %v", test.text, err)
+		case !test.valid && err == nil:
+			t.Errorf("%q: expected an error, got %v", test.text, seconds)
+		case test.valid && seconds != test.seconds:
+			t.Errorf("%q: got %v, want %v", test.text, seconds, test.seconds)
+		}
+	}
+}
@@ -0,0 +1,380 @@
+package discord
+
+import (
+	"fmt"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"atlantis_calc/calc"
+
+	"github.com/bwmarrin/discordgo"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	seedHistoryFile = "seed_history.json"
+	// seedHistoryLimit is how many seeds are kept, the oldest go first
+	seedHistoryLimit = 5000
+	seedsPerPage     = 10
+	// seedsComponentPrefix starts the custom IDs of the /seeds page buttons
+	seedsComponentPrefix = "seeds"
+)
+
+// seedPeriods are the periods /seeds best can look back
+var seedPeriods = map[string]time.Duration{
+	"day":   24 * time.Hour,
+	"week":  7 * 24 * time.Hour,
+	"month": 30 * 24 * time.Hour,
+}
+
+// SeedRecord is a seed reported by the ChatTriggers module
+type SeedRecord struct {
+	Rooms     []string            `json:"rooms"`
+	IGN       string              `json:"ign"`
+	Lobby     string              `json:"lobby"`
+	Time      time.Time           `json:"time"`
+	Result    calc.CalcSeedResult `json:"result"`
+	Announced bool                `json:"announced"`
+}
+
+var seedHistory *SeedHistory
+
+// seedHistorySaveDelay is how long a reported seed may wait before the
+// history is written, so a burst of reports is saved at once
+var seedHistorySaveDelay = 30 * time.Second
+
+// SeedHistory keeps the reported seeds on disk, oldest first. Adding a seed
+// only schedules a save, Close writes whatever is still pending.
+type SeedHistory struct {
+	mutex     sync.RWMutex
+	records   []SeedRecord
+	dirty     bool
+	saveTimer *time.Timer
+
+	// saveMutex keeps the saves in order, so an older snapshot never
+	// overwrites a newer one
+	saveMutex sync.Mutex
+}
+
+// NewSeedHistory loads the stored seeds
+func NewSeedHistory() (*SeedHistory, error) {
+	history := &SeedHistory{}
+	if err := loadJSON(seedHistoryFile, &history.records); err != nil {
+		return nil, err
+	}
+	return history, nil
+}
+
+// Add records a seed and schedules saving the history
+func (h *SeedHistory) Add(record SeedRecord) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	h.records = append(h.records, record)
+	if len(h.records) > seedHistoryLimit {
+		h.records = slices.Clone(h.records[len(h.records)-seedHistoryLimit:])
+	}
+
+	h.dirty = true
+	if h.saveTimer == nil {
+		h.saveTimer = time.AfterFunc(seedHistorySaveDelay, func() {
+			if err := h.save(); err != nil {
+				log.Errorf("Failed to save seed history: %v", err)
+			}
+		})
+	}
+}
+
+// Close cancels the scheduled save and writes the pending seeds right away
+func (h *SeedHistory) Close() error {
+	h.mutex.Lock()
+	if h.saveTimer != nil {
+		h.saveTimer.Stop()
+	}
+	h.mutex.Unlock()
+
+	return h.save()
+}
+
+// save writes the history if a seed was added since the last save. A failed
+// save is tried again with the next seed.
+func (h *SeedHistory) save() error {
+	h.saveMutex.Lock()
+	defer h.saveMutex.Unlock()
+
+	h.mutex.Lock()
+	h.saveTimer = nil
+	if !h.dirty {
+		h.mutex.Unlock()
+		return nil
+	}
+	h.dirty = false
+	// Records are only appended, or cloned when trimmed, so the snapshot
+	// doesn't change under the save
+	records := h.records[:len(h.records):len(h.records)]
+	h.mutex.Unlock()
+
+	if err := saveJSON(seedHistoryFile, records); err != nil {
+		h.mutex.Lock()
+		h.dirty = true
+		h.mutex.Unlock()
+		return err
+	}
+	return nil
+}
+
+// Find returns the seeds that match, newest first
+func (h *SeedHistory) Find(match func(SeedRecord) bool) []SeedRecord {
+	h.mutex.RLock()
+	defer h.mutex.RUnlock()
+
+	var res []SeedRecord
+	for k := len(h.records) - 1; k >= 0; k-- {
+		if match(h.records[k]) {
+			res = append(res, h.records[k])
+		}
+	}
+	return res
+}
+
+// seedQuery is what a /seeds subcommand lists, so the page buttons can list it again
+type seedQuery struct {
+	Kind string // recent, best or by
+	Arg  string // the period of best, the ign of by
+}
+
+// records returns the seeds the query lists, in the order they are shown
+func (q seedQuery) records(now time.Time) []SeedRecord {
+	switch q.Kind {
+	case "best":
+		since := time.Time{}
+		if period, exists := seedPeriods[q.Arg]; exists {
+			since = now.Add(-period)
+		}
+		records := seedHistory.Find(func(r SeedRecord) bool { return r.Time.After(since) })
+		slices.SortStableFunc(records, func(a, b SeedRecord) int {
+			return compareFloat(a.Result.BoostTime, b.Result.BoostTime)
+		})
+		return records
+	case "by":
+		return seedHistory.Find(func(r SeedRecord) bool { return strings.EqualFold(r.IGN, q.Arg) })
+	default:
+		return seedHistory.Find(func(r SeedRecord) bool { return true })
+	}
+}
+
+func (q seedQuery) title() string {
+	switch q.Kind {
+	case "best":
+		if q.Arg == "all" {
+			return "Best Seeds Ever"
+		}
+		return fmt.Sprintf("Best Seeds This %s", strings.ToUpper(q.Arg[:1])+q.Arg[1:])
+	case "by":
+		return fmt.Sprintf("Seeds Found by %s", q.Arg)
+	default:
+		return "Recent Seeds"
+	}
+}
+
+func compareFloat(a, b float64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+var seedsCommand = &discordgo.ApplicationCommand{
+	Name:        "seeds",
+	Description: "Look through the seeds found with the ChatTriggers module",
+	Options: []*discordgo.ApplicationCommandOption{
+		{
+			Type:        discordgo.ApplicationCommandOptionSubCommand,
+			Name:        "recent",
+			Description: "The latest seeds",
+		},
+		{
+			Type:        discordgo.ApplicationCommandOptionSubCommand,
+			Name:        "best",
+			Description: "The fastest seeds of a period",
+			Options: []*discordgo.ApplicationCommandOption{
+				{
+					Type:        discordgo.ApplicationCommandOptionString,
+					Name:        "period",
+					Description: "How far back to look, a week if empty",
+					Choices: []*discordgo.ApplicationCommandOptionChoice{
+						{Name: "Day", Value: "day"},
+						{Name: "Week", Value: "week"},
+						{Name: "Month", Value: "month"},
+						{Name: "All time", Value: "all"},
+					},
+				},
+			},
+		},
+		{
+			Type:        discordgo.ApplicationCommandOptionSubCommand,
+			Name:        "by",
+			Description: "The seeds a player found",
+			Options: []*discordgo.ApplicationCommandOption{
+				{
+					Type:        discordgo.ApplicationCommandOptionString,
+					Name:        "ign",
+					Description: "Minecraft name of the player",
+					Required:    true,
+					MaxLength:   16,
+				},
+			},
+		},
+	},
+}
+
+func seedsHandler(s Session, i *discordgo.InteractionCreate) {
+	logUserInteraction(i, "command", "seeds")
+
+	options := i.ApplicationCommandData().Options
+	if len(options) == 0 {
+		return
+	}
+
+	query := seedQuery{Kind: options[0].Name}
+	switch query.Kind {
+	case "best":
+		query.Arg = "week"
+		for _, opt := range options[0].Options {
+			if opt.Name == "period" {
+				query.Arg = opt.StringValue()
+			}
+		}
+	case "by":
+		for _, opt := range options[0].Options {
+			if opt.Name == "ign" {
+				query.Arg = strings.TrimSpace(opt.StringValue())
+			}
+		}
+	}
+
+	embed, components := seedsPage(query, 0, time.Now())
+	err := s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{
+			Embeds:     []*discordgo.MessageEmbed{embed},
+			Components: components,
+		},
+	})
+	if err != nil {
+		log.Errorf("Failed to respond to seeds command: %v", err)
+	}
+}
+
+// seedsButtonHandler turns the page of a /seeds message
+func seedsButtonHandler(s Session, i *discordgo.InteractionCreate) {
+	logUserInteraction(i, "button click", i.MessageComponentData().CustomID)
+
+	query, page, err := decodeSeedsButtonID(i.MessageComponentData().CustomID)
+	if err != nil {
+		log.Error(err)
+		s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+			Type: discordgo.InteractionResponseChannelMessageWithSource,
+			Data: &discordgo.InteractionResponseData{
+				Content: "HA. Buttons not working.",
+				Flags:   discordgo.MessageFlagsEphemeral,
+			},
+		})
+		return
+	}
+
+	embed, components := seedsPage(query, page, time.Now())
+	err = s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseUpdateMessage,
+		Data: &discordgo.InteractionResponseData{
+			Embeds:     []*discordgo.MessageEmbed{embed},
+			Components: components,
+		},
+	})
+	if err != nil {
+		log.Errorf("Failed to turn seeds page: %v", err)
+	}
+}
+
+// encodeSeedsButtonID packs the query and page a /seeds button shows as
+// "seeds|kind|page|arg", the arg last as it is typed by users
+func encodeSeedsButtonID(query seedQuery, page int) string {
+	return fmt.Sprintf("%s|%s|%d|%s", seedsComponentPrefix, query.Kind, page, query.Arg)
+}
+
+func decodeSeedsButtonID(customID string) (seedQuery, int, error) {
+	parts := strings.SplitN(customID, "|", 4)
+	if len(parts) != 4 || parts[0] != seedsComponentPrefix {
+		return seedQuery{}, 0, fmt.Errorf("invalid seeds button ID %q", customID)
+	}
+
+	page, err := strconv.Atoi(parts[2])
+	if err != nil {
+		return seedQuery{}, 0, fmt.Errorf("invalid page in seeds button ID %q: %w", customID, err)
+	}
+
+	return seedQuery{Kind: parts[1], Arg: parts[3]}, page, nil
+}
+
+// seedsPage renders a page of the seeds a query lists with buttons to the
+// previous and next page
+func seedsPage(query seedQuery, page int, now time.Time) (*discordgo.MessageEmbed, []discordgo.MessageComponent) {
+	records := query.records(now)
+	pages := max(1, int(math.Ceil(float64(len(records))/seedsPerPage)))
+	page = min(max(page, 0), pages-1)
+
+	var description strings.Builder
+	if len(records) == 0 {
+		description.WriteString("No seeds here yet, go play some games.")
+	}
+	for k, record := range records[page*seedsPerPage : min(len(records), (page+1)*seedsPerPage)] {
+		announced := ""
+		if record.Announced {
+			announced = " 📣"
+		}
+		description.WriteString(fmt.Sprintf("**%d.** `%s` by **%s** in %s, <t:%d:R>%s\n%s\n",
+			page*seedsPerPage+k+1, FormatTime(record.Result.BoostTime), record.IGN, record.Lobby,
+			record.Time.Unix(), announced, strings.Join(record.Rooms, ", ")))
+	}
+
+	embed := &discordgo.MessageEmbed{
+		Title:       query.title(),
+		Description: description.String(),
+		Color:       0x45D3B3,
+		Footer: &discordgo.MessageEmbedFooter{
+			Text: fmt.Sprintf("Page %d/%d, %d seeds", page+1, pages, len(records)),
+		},
+	}
+
+	components := []discordgo.MessageComponent{
+		discordgo.ActionsRow{
+			Components: []discordgo.MessageComponent{
+				discordgo.Button{
+					CustomID: encodeSeedsButtonID(query, page-1),
+					Style:    discordgo.SecondaryButton,
+					Emoji: &discordgo.ComponentEmoji{
+						Name: "⬅️",
+					},
+					Disabled: page <= 0,
+				},
+				discordgo.Button{
+					CustomID: encodeSeedsButtonID(query, page+1),
+					Style:    discordgo.SecondaryButton,
+					Emoji: &discordgo.ComponentEmoji{
+						Name: "➡️",
+					},
+					Disabled: page >= pages-1,
+				},
+			},
+		},
+	}
+
+	return embed, components
+}
@@ -0,0 +1,111 @@
+package discord
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// useSeedHistory creates an empty history that saves after delay
+func useSeedHistory(t *testing.T, delay time.Duration) (*SeedHistory, string) {
+	t.Helper()
+
+	dir := useDataDir(t)
+	previous := seedHistorySaveDelay
+	seedHistorySaveDelay = delay
+	t.Cleanup(func() { seedHistorySaveDelay = previous })
+
+	history, err := NewSeedHistory()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { history.Close() })
+	return history, filepath.Join(dir, seedHistoryFile)
+}
+
+// storedSeeds reloads the history from disk
+func storedSeeds(t *testing.T) []SeedRecord {
+	t.Helper()
+
+	history, err := NewSeedHistory()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return history.Find(func(SeedRecord) bool { return true })
+}
+
+func TestSeedHistorySavesOnClose(t *testing.T) {
+	history, file := useSeedHistory(t, time.Hour)
+
+	history.Add(SeedRecord{IGN: "alice"})
+	history.Add(SeedRecord{IGN: "bob"})
+	if _, err := os.Stat(file); !os.IsNotExist(err) {
+		t.Fatalf("the history was written right away: %v", err)
+	}
+	if records := history.Find(func(SeedRecord) bool { return true }); len(records) != 2 {
+		t.Errorf("expected the pending seeds to be listed, got %d", len(records))
+	}
+
+	if err := history.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if records := storedSeeds(t); len(records) != 2 || records[0].IGN != "bob" {
+		t.Errorf("got stored seeds %+v", records)
+	}
+}
+
+func TestSeedHistorySavesAfterDelay(t *testing.T) {
+	history, _ := useSeedHistory(t, 10*time.Millisecond)
+
+	history.Add(SeedRecord{IGN: "alice"})
+	deadline := time.Now().Add(5 * time.Second)
+	for len(storedSeeds(t)) != 1 {
+		if time.Now().After(deadline) {
+			t.Fatal("the history wasn't saved")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	// Later seeds schedule another save
+	history.Add(SeedRecord{IGN: "bob"})
+	for len(storedSeeds(t)) != 2 {
+		if time.Now().After(deadline) {
+			t.Fatal("the second seed wasn't saved")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestSeedHistoryRetriesFailedSave(t *testing.T) {
+	history, _ := useSeedHistory(t, time.Hour)
+	dir := DataDir
+
+	history.Add(SeedRecord{IGN: "alice"})
+	breakDataDir(t)
+	if err := history.Close(); err == nil {
+		t.Fatal("expected the save to fail")
+	}
+
+	DataDir = dir
+	if err := history.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if records := storedSeeds(t); len(records) != 1 {
+		t.Errorf("the failed save wasn't tried again, got %+v", records)
+	}
+}
+
+func TestSeedHistoryLimit(t *testing.T) {
+	history, _ := useSeedHistory(t, time.Hour)
+
+	for k := 0; k < seedHistoryLimit+10; k++ {
+		history.Add(SeedRecord{Lobby: "mini" + string(rune('A'+k%26))})
+	}
+	if err := history.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if records := storedSeeds(t); len(records) != seedHistoryLimit {
+		t.Errorf("expected %d seeds, got %d", seedHistoryLimit, len(records))
+	}
+}
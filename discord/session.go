@@ -0,0 +1,71 @@
+package discord
+
+import (
+	"github.com/bwmarrin/discordgo"
+)
+
+// Session is the part of the Discord API the handlers use. DiscordSession
+// implements it on top of a real connection and FakeSession records the calls
+// so handlers can run offline.
+type Session interface {
+	InteractionRespond(interaction *discordgo.Interaction, resp *discordgo.InteractionResponse, options ...discordgo.RequestOption) error
+	// InteractionResponse returns the message an interaction was responded with
+	InteractionResponse(interaction *discordgo.Interaction, options ...discordgo.RequestOption) (*discordgo.Message, error)
+	InteractionResponseEdit(interaction *discordgo.Interaction, newresp *discordgo.WebhookEdit, options ...discordgo.RequestOption) (*discordgo.Message, error)
+
+	ChannelMessage(channelID, messageID string, options ...discordgo.RequestOption) (*discordgo.Message, error)
+	ChannelMessageSend(channelID string, content string, options ...discordgo.RequestOption) (*discordgo.Message, error)
+	ChannelMessageSendComplex(channelID string, data *discordgo.MessageSend, options ...discordgo.RequestOption) (*discordgo.Message, error)
+	ChannelMessageEdit(channelID, messageID, content string, options ...discordgo.RequestOption) (*discordgo.Message, error)
+	ChannelMessageEditComplex(m *discordgo.MessageEdit, options ...discordgo.RequestOption) (*discordgo.Message, error)
+
+	Channel(channelID string, options ...discordgo.RequestOption) (*discordgo.Channel, error)
+	GuildChannels(guildID string, options ...discordgo.RequestOption) ([]*discordgo.Channel, error)
+	// UserChannelCreate returns the DM channel with a user, opening it if needed
+	UserChannelCreate(recipientID string, options ...discordgo.RequestOption) (*discordgo.Channel, error)
+
+	// BotUser returns the user the bot is logged in as, nil before it is
+	BotUser() *discordgo.User
+	// GuildIDs returns the guilds the bot is in
+	GuildIDs() []string
+	// BotPermissions returns the permissions the bot has in a channel
+	BotPermissions(channelID string) (int64, error)
+}
+
+// DiscordSession is a Session backed by a discordgo connection, answering
+// the bot user, guild and permission lookups from its state cache
+type DiscordSession struct {
+	*discordgo.Session
+}
+
+// NewDiscordSession creates a session for the given bot token. It doesn't
+// connect until Open is called.
+func NewDiscordSession(token string) (*DiscordSession, error) {
+	session, err := discordgo.New("Bot " + token)
+	if err != nil {
+		return nil, err
+	}
+	return &DiscordSession{session}, nil
+}
+
+func (ds *DiscordSession) BotUser() *discordgo.User {
+	if ds.State == nil {
+		return nil
+	}
+	return ds.State.User
+}
+
+func (ds *DiscordSession) GuildIDs() []string {
+	ds.State.RLock()
+	defer ds.State.RUnlock()
+
+	guildIDs := make([]string, 0, len(ds.State.Guilds))
+	for _, guild := range ds.State.Guilds {
+		guildIDs = append(guildIDs, guild.ID)
+	}
+	return guildIDs
+}
+
+func (ds *DiscordSession) BotPermissions(channelID string) (int64, error) {
+	return ds.State.UserChannelPermissions(ds.State.User.ID, channelID)
+}
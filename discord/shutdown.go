@@ -0,0 +1,131 @@
+package discord
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+
+	"github.com/bwmarrin/discordgo"
+	log "github.com/sirupsen/logrus"
+)
+
+// inFlight tracks the interactions being handled so shutdown can wait for them
+var inFlight struct {
+	sync.Mutex
+	closed bool
+	wg     sync.WaitGroup
+}
+
+// beginInteraction registers an interaction being handled. It returns false
+// once the bot is shutting down.
+func beginInteraction() bool {
+	inFlight.Lock()
+	defer inFlight.Unlock()
+
+	if inFlight.closed {
+		return false
+	}
+	inFlight.wg.Add(1)
+	return true
+}
+
+func endInteraction() {
+	inFlight.wg.Done()
+}
+
+// stopInteractions refuses new interactions and waits until the ones being
+// handled are done or ctx expires
+func stopInteractions(ctx context.Context) error {
+	inFlight.Lock()
+	inFlight.closed = true
+	inFlight.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		inFlight.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("interactions still running: %w", ctx.Err())
+	}
+}
+
+// respondShuttingDown tells the user the bot can't handle their interaction right now
+func respondShuttingDown(s Session, i *discordgo.InteractionCreate) {
+	// Autocomplete can't show a message
+	if i.Type == discordgo.InteractionApplicationCommandAutocomplete {
+		return
+	}
+
+	err := s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{
+			Content: "I'm restarting, try again in a minute.",
+			Flags:   discordgo.MessageFlagsEphemeral,
+		},
+	})
+	if err != nil {
+		log.Errorf("Failed to respond during shutdown: %v", err)
+	}
+}
+
+// shutdown stops the bot in order: no new interactions, background work and
+// the web UI stopped, message states persisted or finalized, and optionally
+// the commands removed. It gives up when ctx expires.
+func shutdown(ctx context.Context, session *DiscordSession, stopBackground context.CancelFunc, web *http.Server, registeredCommands []*discordgo.ApplicationCommand) error {
+	done := make(chan error, 1)
+	go func() {
+		var errs []error
+
+		log.Info("Waiting for running interactions...")
+		if err := stopInteractions(ctx); err != nil {
+			errs = append(errs, err)
+		}
+
+		stopBackground()
+
+		if web != nil {
+			if err := web.Shutdown(ctx); err != nil {
+				errs = append(errs, fmt.Errorf("error stopping the web UI: %w", err))
+			}
+		}
+
+		var finalize func(string, MessageState)
+		if cfg.FinalizeMessages {
+			log.Info("Removing buttons of live messages...")
+			finalize = finalizeMessage
+		}
+		if err := messageStore.Close(finalize); err != nil {
+			errs = append(errs, fmt.Errorf("error saving message states: %w", err))
+		}
+
+		if err := seedHistory.Close(); err != nil {
+			errs = append(errs, fmt.Errorf("error saving seed history: %w", err))
+		}
+
+		if cfg.UnregisterCommands {
+			log.Info("Removing commands...")
+			for _, cmd := range registeredCommands {
+				err := session.ApplicationCommandDelete(cmd.ApplicationID, cmd.GuildID, cmd.ID, discordgo.WithContext(ctx))
+				if err != nil {
+					errs = append(errs, fmt.Errorf("error removing '%v' command: %w", cmd.Name, err))
+				}
+			}
+		}
+
+		done <- errors.Join(errs...)
+	}()
+
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return fmt.Errorf("shutdown didn't finish in time: %w", ctx.Err())
+	}
+}
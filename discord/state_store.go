@@ -0,0 +1,229 @@
+package discord
+
+import (
+	"slices"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// MessageState is what the bot remembers about a message it sent
+type MessageState struct {
+	ChannelID string `json:"channel_id"`
+	// Result is only set for messages whose buttons rely on server-side state
+	Result *ResultState `json:"result,omitempty"`
+	// Quiz is set for quizzes whose state doesn't fit in their custom IDs
+	Quiz *quizState `json:"quiz,omitempty"`
+	// ShowCalcOnly is set once only the ShowCalc button is left on the message
+	ShowCalcOnly bool `json:"show_calc_only,omitempty"`
+	// ShowCalcMessageID is the message with the detailed calculation, if one was sent
+	ShowCalcMessageID string    `json:"show_calc_message_id,omitempty"`
+	ExpiresAt         time.Time `json:"expires_at"`
+}
+
+// clone returns a copy of the state that shares no memory with the original
+func (m MessageState) clone() MessageState {
+	if m.Result != nil {
+		result := *m.Result
+		result.Rooms = slices.Clone(result.Rooms)
+		result.Results = slices.Clone(result.Results)
+		m.Result = &result
+	}
+	if m.Quiz != nil {
+		quiz := quizState{Rooms: slices.Clone(m.Quiz.Rooms), Picks: slices.Clone(m.Quiz.Picks)}
+		m.Quiz = &quiz
+	}
+	return m
+}
+
+// MessageStateStore keeps the state of messages for a limited time. It is
+// safe for concurrent use and persists every change to disk.
+type MessageStateStore struct {
+	mutex    sync.Mutex
+	file     string
+	states   map[string]MessageState
+	timers   map[string]*time.Timer
+	onExpire func(messageID string, state MessageState)
+	// closed stops new expiry timers once the store is closed
+	closed bool
+}
+
+// NewMessageStateStore creates a store persisted to the given file in
+// DataDir. onExpire is called, without any lock held, whenever a state expires.
+func NewMessageStateStore(file string, onExpire func(messageID string, state MessageState)) *MessageStateStore {
+	return &MessageStateStore{
+		file:     file,
+		states:   make(map[string]MessageState),
+		timers:   make(map[string]*time.Timer),
+		onExpire: onExpire,
+	}
+}
+
+// Load reads the stored states and schedules their expiry from the stored
+// deadlines. States that expired in the meantime expire right away.
+func (ms *MessageStateStore) Load() error {
+	stored := make(map[string]MessageState)
+	if err := loadJSON(ms.file, &stored); err != nil {
+		return err
+	}
+
+	ms.mutex.Lock()
+	defer ms.mutex.Unlock()
+
+	for messageID, state := range stored {
+		ms.states[messageID] = state
+		ms.schedule(messageID, time.Until(state.ExpiresAt))
+	}
+
+	return nil
+}
+
+// Get returns a copy of the state of a message
+func (ms *MessageStateStore) Get(messageID string) (MessageState, bool) {
+	ms.mutex.Lock()
+	defer ms.mutex.Unlock()
+
+	state, exists := ms.states[messageID]
+	return state.clone(), exists
+}
+
+// Put stores the state of a message for ttl
+func (ms *MessageStateStore) Put(messageID string, state MessageState, ttl time.Duration) {
+	ms.mutex.Lock()
+	defer ms.mutex.Unlock()
+
+	state = state.clone()
+	state.ExpiresAt = time.Now().Add(ttl)
+	ms.states[messageID] = state
+	ms.schedule(messageID, ttl)
+	ms.save()
+}
+
+// Update changes the state of a message. A positive ttl also restarts its
+// expiry, and creates the state if the message had none. It reports whether
+// the message has a state afterwards.
+func (ms *MessageStateStore) Update(messageID string, update func(*MessageState), ttl time.Duration) bool {
+	ms.mutex.Lock()
+	defer ms.mutex.Unlock()
+
+	state, exists := ms.states[messageID]
+	if !exists && ttl <= 0 {
+		return false
+	}
+
+	state = state.clone()
+	update(&state)
+	if ttl > 0 {
+		state.ExpiresAt = time.Now().Add(ttl)
+		ms.schedule(messageID, ttl)
+	}
+	ms.states[messageID] = state.clone()
+	ms.save()
+
+	return true
+}
+
+// Touch restarts the expiry of a message's state
+func (ms *MessageStateStore) Touch(messageID string, ttl time.Duration) {
+	ms.mutex.Lock()
+	defer ms.mutex.Unlock()
+
+	state, exists := ms.states[messageID]
+	if !exists {
+		return
+	}
+
+	state.ExpiresAt = time.Now().Add(ttl)
+	ms.states[messageID] = state
+	ms.schedule(messageID, ttl)
+	ms.save()
+}
+
+// Delete forgets the state of a message without calling onExpire
+func (ms *MessageStateStore) Delete(messageID string) {
+	ms.mutex.Lock()
+	defer ms.mutex.Unlock()
+
+	if timer, exists := ms.timers[messageID]; exists {
+		timer.Stop()
+		delete(ms.timers, messageID)
+	}
+	delete(ms.states, messageID)
+	ms.save()
+}
+
+// Len returns the number of stored states
+func (ms *MessageStateStore) Len() int {
+	ms.mutex.Lock()
+	defer ms.mutex.Unlock()
+
+	return len(ms.states)
+}
+
+// Close stops every expiry timer so no state expires anymore, and saves the
+// states so the next Load picks them up. If finalize is not nil it is called
+// for every state, without any lock held, and the states are dropped instead.
+func (ms *MessageStateStore) Close(finalize func(messageID string, state MessageState)) error {
+	ms.mutex.Lock()
+	ms.closed = true
+	for messageID, timer := range ms.timers {
+		timer.Stop()
+		delete(ms.timers, messageID)
+	}
+
+	states := ms.states
+	if finalize != nil {
+		ms.states = make(map[string]MessageState)
+	}
+	err := saveJSON(ms.file, ms.states)
+	ms.mutex.Unlock()
+
+	if finalize != nil {
+		for messageID, state := range states {
+			finalize(messageID, state)
+		}
+	}
+
+	return err
+}
+
+// schedule (re)starts the expiry timer of a message. The caller must hold the lock.
+func (ms *MessageStateStore) schedule(messageID string, ttl time.Duration) {
+	if timer, exists := ms.timers[messageID]; exists {
+		timer.Stop()
+	}
+	if ms.closed {
+		return
+	}
+
+	ms.timers[messageID] = time.AfterFunc(max(ttl, 0), func() {
+		ms.expire(messageID)
+	})
+}
+
+func (ms *MessageStateStore) expire(messageID string) {
+	ms.mutex.Lock()
+	state, exists := ms.states[messageID]
+	// The expiry may have been pushed back after the timer fired
+	if !exists || time.Now().Before(state.ExpiresAt) {
+		ms.mutex.Unlock()
+		return
+	}
+
+	delete(ms.states, messageID)
+	delete(ms.timers, messageID)
+	ms.save()
+	ms.mutex.Unlock()
+
+	if ms.onExpire != nil {
+		ms.onExpire(messageID, state)
+	}
+}
+
+// save persists the states. The caller must hold the lock.
+func (ms *MessageStateStore) save() {
+	if err := saveJSON(ms.file, ms.states); err != nil {
+		log.Errorf("Failed to save message states: %v", err)
+	}
+}
@@ -0,0 +1,187 @@
+package discord
+
+import (
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+// expiries records the states a store expired
+type expiries struct {
+	mutex  sync.Mutex
+	states map[string]MessageState
+	done   chan string
+}
+
+func newExpiries() *expiries {
+	return &expiries{states: make(map[string]MessageState), done: make(chan string, 10)}
+}
+
+func (e *expiries) onExpire(messageID string, state MessageState) {
+	e.mutex.Lock()
+	e.states[messageID] = state
+	e.mutex.Unlock()
+	e.done <- messageID
+}
+
+// wait waits for a message to expire
+func (e *expiries) wait(t *testing.T, messageID string) MessageState {
+	t.Helper()
+
+	select {
+	case expired := <-e.done:
+		if expired != messageID {
+			t.Fatalf("%s expired instead of %s", expired, messageID)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("%s never expired", messageID)
+	}
+
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	return e.states[messageID]
+}
+
+func TestStateStoreTouchAfterExpiry(t *testing.T) {
+	useDataDir(t)
+	expired := newExpiries()
+	store := NewMessageStateStore("states.json", expired.onExpire)
+	defer store.Close(nil)
+
+	store.Put("message", MessageState{ChannelID: "channel"}, 10*time.Millisecond)
+	expired.wait(t, "message")
+
+	store.Touch("message", time.Hour)
+	if _, exists := store.Get("message"); exists {
+		t.Error("touching an expired state brought it back")
+	}
+	select {
+	case messageID := <-expired.done:
+		t.Errorf("%s expired twice", messageID)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestStateStoreTouchPushesExpiryBack(t *testing.T) {
+	useDataDir(t)
+	expired := newExpiries()
+	store := NewMessageStateStore("states.json", expired.onExpire)
+	defer store.Close(nil)
+
+	store.Put("message", MessageState{ChannelID: "channel"}, 20*time.Millisecond)
+	store.Touch("message", time.Hour)
+
+	select {
+	case messageID := <-expired.done:
+		t.Errorf("%s expired after being touched", messageID)
+	case <-time.After(60 * time.Millisecond):
+	}
+	if _, exists := store.Get("message"); !exists {
+		t.Error("the touched state is gone")
+	}
+}
+
+func TestStateStoreUpdateThenExpire(t *testing.T) {
+	useDataDir(t)
+	expired := newExpiries()
+	store := NewMessageStateStore("states.json", expired.onExpire)
+	defer store.Close(nil)
+
+	store.Put("message", MessageState{ChannelID: "channel"}, 20*time.Millisecond)
+	if !store.Update("message", func(m *MessageState) { m.ShowCalcMessageID = "calc" }, 0) {
+		t.Fatal("the state to update is missing")
+	}
+	if store.Update("missing", func(m *MessageState) { m.ShowCalcMessageID = "calc" }, 0) {
+		t.Error("updating without a ttl created a state")
+	}
+
+	if state := expired.wait(t, "message"); state.ShowCalcMessageID != "calc" || state.ChannelID != "channel" {
+		t.Errorf("expired with %+v, want the updated state", state)
+	}
+	if store.Len() != 0 {
+		t.Errorf("%d states are left after expiring", store.Len())
+	}
+}
+
+func TestStateStoreClose(t *testing.T) {
+	useDataDir(t)
+	expired := newExpiries()
+	store := NewMessageStateStore("states.json", expired.onExpire)
+	store.Put("message", MessageState{ChannelID: "channel"}, time.Hour)
+	store.Put("soon", MessageState{ChannelID: "channel"}, 30*time.Millisecond)
+
+	if err := store.Close(nil); err != nil {
+		t.Fatal(err)
+	}
+	store.Put("late", MessageState{ChannelID: "channel"}, 0)
+	select {
+	case messageID := <-expired.done:
+		t.Errorf("%s expired after the store was closed", messageID)
+	case <-time.After(60 * time.Millisecond):
+	}
+
+	reloaded := NewMessageStateStore("states.json", nil)
+	if err := reloaded.Load(); err != nil {
+		t.Fatal(err)
+	}
+	if state, exists := reloaded.Get("message"); !exists || state.ChannelID != "channel" {
+		t.Errorf("the state wasn't persisted, got %+v", state)
+	}
+
+	var finalized []string
+	if err := reloaded.Close(func(messageID string, _ MessageState) { finalized = append(finalized, messageID) }); err != nil {
+		t.Fatal(err)
+	}
+	if len(finalized) == 0 {
+		t.Error("closing with finalize finalized nothing")
+	}
+	emptied := NewMessageStateStore("states.json", nil)
+	if err := emptied.Load(); err != nil {
+		t.Fatal(err)
+	}
+	if emptied.Len() != 0 {
+		t.Errorf("%d finalized states were kept", emptied.Len())
+	}
+	emptied.Close(nil)
+}
+
+func TestMigrateMessageStates(t *testing.T) {
+	dir := useDataDir(t)
+	legacy := `{
+		"states": {
+			"live": {"channel_id": "channel", "rooms": ["1a"], "index": 2, "filter": "any", "keep_show_calc": true, "expires_at": "2099-01-01T00:00:00Z"},
+			"show-calc": {"channel_id": "channel", "rooms": ["1a"], "filter": "any", "keep_show_calc": true, "expires_at": "2020-01-01T00:00:00Z", "show_calc_expires_at": "2099-01-01T00:00:00Z"}
+		},
+		"show_calc_messages": {"live": "calc"}
+	}`
+	if err := os.WriteFile(filepath.Join(dir, legacyMessageStatesFile), []byte(legacy), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := migrateMessageStates(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, legacyMessageStatesFile)); !os.IsNotExist(err) {
+		t.Errorf("the legacy file is still there: %v", err)
+	}
+
+	var states map[string]MessageState
+	if err := loadJSON(messageStatesFile, &states); err != nil {
+		t.Fatal(err)
+	}
+	live := states["live"]
+	if live.Result == nil || live.Result.Index != 2 || live.ShowCalcMessageID != "calc" || live.ShowCalcOnly {
+		t.Errorf("live message migrated as %+v", live)
+	}
+	showCalc := states["show-calc"]
+	if !showCalc.ShowCalcOnly || showCalc.ExpiresAt.Year() != 2099 {
+		t.Errorf("ShowCalc message migrated as %+v", showCalc)
+	}
+
+	// Nothing left to migrate
+	if err := migrateMessageStates(); err != nil {
+		t.Fatal(err)
+	}
+}
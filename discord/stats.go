@@ -0,0 +1,154 @@
+package discord
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// StatsProvider fetches the current number of players in Parkour Duels
+type StatsProvider interface {
+	PlayerCount(ctx context.Context) (int, error)
+}
+
+// PlayerCountSample is a player count observed at a point in time
+type PlayerCountSample struct {
+	Count int       `json:"count"`
+	Time  time.Time `json:"time"`
+}
+
+// HypixelStatsProvider reads player counts from the Hypixel public API
+type HypixelStatsProvider struct {
+	APIKey  string
+	BaseURL string
+	// Mode is the key of the Parkour Duels mode in the DUELS game counts
+	Mode   string
+	Client *http.Client
+}
+
+// NewHypixelStatsProvider creates a provider for the Hypixel API with the given key
+func NewHypixelStatsProvider(apiKey string) *HypixelStatsProvider {
+	return &HypixelStatsProvider{
+		APIKey:  apiKey,
+		BaseURL: "https://api.hypixel.net",
+		Mode:    "DUELS_PARKOUR_EIGHT",
+		Client:  &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+type hypixelCountsResponse struct {
+	Success bool   `json:"success"`
+	Cause   string `json:"cause"`
+	Games   map[string]struct {
+		Players int            `json:"players"`
+		Modes   map[string]int `json:"modes"`
+	} `json:"games"`
+}
+
+// PlayerCount fetches the current Parkour Duels player count
+func (p *HypixelStatsProvider) PlayerCount(ctx context.Context) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, p.BaseURL+"/v2/counts", nil)
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("API-Key", p.APIKey)
+
+	resp, err := p.Client.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("error requesting player counts: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var counts hypixelCountsResponse
+	if err := json.NewDecoder(resp.Body).Decode(&counts); err != nil {
+		return 0, fmt.Errorf("error decoding player counts (status %d): %w", resp.StatusCode, err)
+	}
+
+	if !counts.Success {
+		return 0, fmt.Errorf("hypixel API returned an error (status %d): %s", resp.StatusCode, counts.Cause)
+	}
+
+	count, exists := counts.Games["DUELS"].Modes[p.Mode]
+	if !exists {
+		return 0, fmt.Errorf("no player count found for mode %s", p.Mode)
+	}
+
+	return count, nil
+}
+
+// FakeStatsProvider returns preset player counts, one per call, repeating the
+// last one once they run out. It is meant for running the bot locally.
+type FakeStatsProvider struct {
+	Counts []int
+	Err    error
+
+	mutex sync.Mutex
+	calls int
+}
+
+// PlayerCount returns the next preset count
+func (p *FakeStatsProvider) PlayerCount(ctx context.Context) (int, error) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	if p.Err != nil {
+		return 0, p.Err
+	}
+
+	if len(p.Counts) == 0 {
+		return 0, fmt.Errorf("fake stats provider has no counts")
+	}
+
+	count := p.Counts[min(p.calls, len(p.Counts)-1)]
+	p.calls++
+	return count, nil
+}
+
+// CachedStatsProvider caches the player count of another provider for a TTL
+// and remembers the previously polled sample so a trend can be shown
+type CachedStatsProvider struct {
+	provider StatsProvider
+	ttl      time.Duration
+
+	mutex    sync.Mutex
+	current  PlayerCountSample
+	previous PlayerCountSample
+}
+
+// NewCachedStatsProvider wraps provider with a cache of the specified TTL
+func NewCachedStatsProvider(provider StatsProvider, ttl time.Duration) *CachedStatsProvider {
+	return &CachedStatsProvider{
+		provider: provider,
+		ttl:      ttl,
+	}
+}
+
+// PlayerCount returns the cached player count, polling the provider if the cache expired
+func (p *CachedStatsProvider) PlayerCount(ctx context.Context) (int, error) {
+	current, _, err := p.Sample(ctx)
+	return current.Count, err
+}
+
+// Sample returns the current sample and the one polled before it. The
+// previous sample has a zero Time if there was no earlier poll.
+func (p *CachedStatsProvider) Sample(ctx context.Context) (PlayerCountSample, PlayerCountSample, error) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	if !p.current.Time.IsZero() && time.Since(p.current.Time) < p.ttl {
+		return p.current, p.previous, nil
+	}
+
+	count, err := p.provider.PlayerCount(ctx)
+	if err != nil {
+		return PlayerCountSample{}, PlayerCountSample{}, err
+	}
+
+	p.previous = p.current
+	p.current = PlayerCountSample{Count: count, Time: time.Now()}
+
+	return p.current, p.previous, nil
+}
@@ -0,0 +1,205 @@
+package discord
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// hypixelServer answers /v2/counts with the body, and fails the test if the
+// API key isn't sent
+func hypixelServer(t *testing.T, status int, body string) (*HypixelStatsProvider, *int) {
+	t.Helper()
+
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if r.URL.Path != "/v2/counts" {
+			t.Errorf("requested %s", r.URL.Path)
+		}
+		if key := r.Header.Get("API-Key"); key != "key" {
+			t.Errorf("got API key %q", key)
+		}
+		w.WriteHeader(status)
+		w.Write([]byte(body))
+	}))
+	t.Cleanup(server.Close)
+
+	provider := NewHypixelStatsProvider("key")
+	provider.BaseURL = server.URL
+	provider.Client = server.Client()
+	return provider, &requests
+}
+
+func TestHypixelPlayerCount(t *testing.T) {
+	tests := []struct {
+		name   string
+		status int
+		body   string
+		count  int
+		err    string
+	}{
+		{
+			name:   "count",
+			status: http.StatusOK,
+			body:   `{"success": true, "games": {"DUELS": {"players": 9000, "modes": {"DUELS_PARKOUR_EIGHT": 57, "DUELS_SW_DUEL": 300}}}}`,
+			count:  57,
+		},
+		{
+			name:   "nobody playing",
+			status: http.StatusOK,
+			body:   `{"success": true, "games": {"DUELS": {"modes": {"DUELS_PARKOUR_EIGHT": 0}}}}`,
+			count:  0,
+		},
+		{
+			name:   "API error",
+			status: http.StatusForbidden,
+			body:   `{"success": false, "cause": "Invalid API key"}`,
+			err:    "Invalid API key",
+		},
+		{
+			name:   "missing mode",
+			status: http.StatusOK,
+			body:   `{"success": true, "games": {"DUELS": {"modes": {"DUELS_SW_DUEL": 300}}}}`,
+			err:    "no player count found for mode DUELS_PARKOUR_EIGHT",
+		},
+		{
+			name:   "missing game",
+			status: http.StatusOK,
+			body:   `{"success": true, "games": {}}`,
+			err:    "no player count found",
+		},
+		{
+			name:   "not JSON",
+			status: http.StatusBadGateway,
+			body:   `<html>502 Bad Gateway</html>`,
+			err:    "error decoding player counts (status 502)",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			provider, _ := hypixelServer(t, test.status, test.body)
+
+			count, err := provider.PlayerCount(context.Background())
+			if test.err != "" {
+				if err == nil || !strings.Contains(err.Error(), test.err) {
+					t.Errorf("got error %v, want it to contain %q", err, test.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if count != test.count {
+				t.Errorf("got %d players, want %d", count, test.count)
+			}
+		})
+	}
+}
+
+func TestCachedStatsProviderReusesHypixelCount(t *testing.T) {
+	provider, requests := hypixelServer(t, http.StatusOK,
+		`{"success": true, "games": {"DUELS": {"modes": {"DUELS_PARKOUR_EIGHT": 57}}}}`)
+	cached := NewCachedStatsProvider(provider, time.Hour)
+
+	for k := 0; k < 3; k++ {
+		count, err := cached.PlayerCount(context.Background())
+		if err != nil || count != 57 {
+			t.Fatalf("got %d, %v", count, err)
+		}
+	}
+	if *requests != 1 {
+		t.Errorf("expected one request within the TTL, got %d", *requests)
+	}
+}
+
+func TestCachedStatsProviderSamples(t *testing.T) {
+	fake := &FakeStatsProvider{Counts: []int{42, 57}}
+
+	cached := NewCachedStatsProvider(fake, time.Hour)
+	current, previous, err := cached.Sample(context.Background())
+	if err != nil || current.Count != 42 || !previous.Time.IsZero() {
+		t.Fatalf("got %+v, %+v, %v", current, previous, err)
+	}
+	if current, _, _ = cached.Sample(context.Background()); current.Count != 42 {
+		t.Errorf("expected the cached count, got %d", current.Count)
+	}
+
+	// Without a TTL every sample polls, and the one before becomes previous
+	expired := NewCachedStatsProvider(&FakeStatsProvider{Counts: []int{42, 57}}, 0)
+	first, _, _ := expired.Sample(context.Background())
+	current, previous, err = expired.Sample(context.Background())
+	if err != nil || current.Count != 57 || previous != first {
+		t.Errorf("got %+v, %+v, %v", current, previous, err)
+	}
+}
+
+func TestCachedStatsProviderError(t *testing.T) {
+	fake := &FakeStatsProvider{Counts: []int{42}}
+	cached := NewCachedStatsProvider(fake, 0)
+	if _, err := cached.PlayerCount(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	fake.Err = errors.New("hypixel is down")
+	if _, err := cached.PlayerCount(context.Background()); err == nil {
+		t.Fatal("expected the error of the provider")
+	}
+
+	// The failed poll doesn't replace the last good sample
+	fake.Err = nil
+	current, previous, err := cached.Sample(context.Background())
+	if err != nil || current.Count != 42 || previous.Count != 42 {
+		t.Errorf("got %+v, %+v, %v", current, previous, err)
+	}
+}
+
+func TestPlayerCountScenario(t *testing.T) {
+	fs := setupScenario(t)
+	fake := &FakeStatsProvider{Counts: []int{42, 57}}
+	playerCountStats = NewCachedStatsProvider(fake, 0)
+	t.Cleanup(func() { playerCountStats = nil })
+
+	embedOf := func(id string) *discordgo.MessageEmbed {
+		t.Helper()
+
+		i := commandInteraction(id, "channel", "playercount")
+		HandleInteraction(fs, i)
+		message := fs.Messages[interactionMessageID(i.Interaction)]
+		if message == nil || len(message.Embeds) != 1 {
+			t.Fatalf("got response %+v", message)
+		}
+		return message.Embeds[0]
+	}
+
+	first := embedOf("1")
+	if first.Fields[0].Value != "42" || first.Fields[1].Value != "No earlier sample yet" {
+		t.Errorf("got fields %q, %q", first.Fields[0].Value, first.Fields[1].Value)
+	}
+
+	second := embedOf("2")
+	if second.Fields[0].Value != "57" || !strings.HasPrefix(second.Fields[1].Value, "📈 +15 since") {
+		t.Errorf("got fields %q, %q", second.Fields[0].Value, second.Fields[1].Value)
+	}
+
+	fake.Err = errors.New("hypixel is down")
+	i := commandInteraction("3", "channel", "playercount")
+	HandleInteraction(fs, i)
+	if content := responseContent(t, fs, i); !strings.Contains(content, "Couldn't get the player count") {
+		t.Errorf("got %q", content)
+	}
+
+	playerCountStats = nil
+	i = commandInteraction("4", "channel", "playercount")
+	HandleInteraction(fs, i)
+	if content := responseContent(t, fs, i); !strings.Contains(content, "aren't set up") {
+		t.Errorf("got %q", content)
+	}
+}
@@ -0,0 +1,65 @@
+package discord
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// DataDir is the directory where the bot keeps its persistent data
+var DataDir = "data"
+
+// loadJSON reads the JSON file with the given name in DataDir into v.
+// A missing file is not an error and leaves v untouched.
+func loadJSON(name string, v any) error {
+	data, err := os.ReadFile(filepath.Join(DataDir, name))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("error reading %s: %w", name, err)
+	}
+
+	if err := json.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("error decoding %s: %w", name, err)
+	}
+
+	return nil
+}
+
+// saveJSON writes v as JSON to the file with the given name in DataDir. The
+// file is replaced atomically so a crash never leaves half-written data behind.
+func saveJSON(name string, v any) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return fmt.Errorf("error encoding %s: %w", name, err)
+	}
+
+	if err := os.MkdirAll(DataDir, 0o755); err != nil {
+		return fmt.Errorf("error creating data directory: %w", err)
+	}
+
+	path := filepath.Join(DataDir, name)
+	tmp, err := os.CreateTemp(DataDir, name+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("error creating temporary file for %s: %w", name, err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("error writing %s: %w", name, err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("error writing %s: %w", name, err)
+	}
+
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("error replacing %s: %w", name, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,564 @@
+package discord
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"math"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"atlantis_calc/calc"
+
+	"github.com/bwmarrin/discordgo"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	tournamentsFile = "tournaments.json"
+	// maxTournamentSeeds is how many seeds a tournament can have
+	maxTournamentSeeds = 10
+	// standingsShown is how many players the standings list, to fit in an embed field
+	standingsShown = 20
+	// standingsNameLength is how much of a player's name the standings show,
+	// nicknames can be twice as long and would overflow the embed field
+	standingsNameLength = 16
+)
+
+// tournamentError is a mistake of the user, shown to them as is
+type tournamentError string
+
+func (e tournamentError) Error() string {
+	return string(e)
+}
+
+// TournamentSeed is a seed of a tournament with the calc's best time on it
+type TournamentSeed struct {
+	Rooms   []string `json:"rooms"`
+	Optimal float64  `json:"optimal"`
+}
+
+// Participant is a player in a tournament with their best time per seed
+type Participant struct {
+	Name string `json:"name"`
+	// Times holds the best submitted time by seed index
+	Times map[int]float64 `json:"times,omitempty"`
+}
+
+// Tournament is an event where everyone plays the same seeds
+type Tournament struct {
+	Name         string                  `json:"name"`
+	CreatedBy    string                  `json:"created_by"`
+	CreatedAt    time.Time               `json:"created_at"`
+	Seeds        []TournamentSeed        `json:"seeds"`
+	Participants map[string]*Participant `json:"participants,omitempty"`
+	Closed       bool                    `json:"closed,omitempty"`
+	// The standings message kept up to date with every submission
+	StandingsChannelID string `json:"standings_channel_id,omitempty"`
+	StandingsMessageID string `json:"standings_message_id,omitempty"`
+}
+
+// Standing is the result of a participant so far
+type Standing struct {
+	UserID string
+	Name   string
+	// Played is how many seeds they submitted a time for
+	Played int
+	// Behind is how much slower than the calc they were over the played seeds
+	Behind float64
+}
+
+// Standings ranks the participants: more seeds played first, then the least
+// time lost to the calc, then by name
+func (t *Tournament) Standings() []Standing {
+	standings := make([]Standing, 0, len(t.Participants))
+	for userID, participant := range t.Participants {
+		standing := Standing{UserID: userID, Name: participant.Name}
+		for index, submitted := range participant.Times {
+			standing.Played++
+			standing.Behind += submitted - t.Seeds[index].Optimal
+		}
+		standings = append(standings, standing)
+	}
+
+	slices.SortFunc(standings, func(a, b Standing) int {
+		if a.Played != b.Played {
+			return b.Played - a.Played
+		}
+		if c := compareFloat(a.Behind, b.Behind); c != 0 {
+			return c
+		}
+		return strings.Compare(a.Name, b.Name)
+	})
+	return standings
+}
+
+// clone copies the tournament so changing the copy leaves t alone
+func (t *Tournament) clone() *Tournament {
+	copied := *t
+	copied.Seeds = slices.Clone(t.Seeds)
+	copied.Participants = make(map[string]*Participant, len(t.Participants))
+	for userID, participant := range t.Participants {
+		p := *participant
+		p.Times = maps.Clone(participant.Times)
+		copied.Participants[userID] = &p
+	}
+	return &copied
+}
+
+// TournamentStore keeps the latest tournament of every guild on disk
+type TournamentStore struct {
+	mutex       sync.RWMutex
+	tournaments map[string]*Tournament
+}
+
+// NewTournamentStore loads the stored tournaments
+func NewTournamentStore() (*TournamentStore, error) {
+	store := &TournamentStore{tournaments: make(map[string]*Tournament)}
+	if err := loadJSON(tournamentsFile, &store.tournaments); err != nil {
+		return nil, err
+	}
+	return store, nil
+}
+
+// Get returns a copy of the latest tournament of a guild, ok is false if it never had one
+func (ts *TournamentStore) Get(guildID string) (*Tournament, bool) {
+	ts.mutex.RLock()
+	defer ts.mutex.RUnlock()
+
+	tournament, exists := ts.tournaments[guildID]
+	if !exists {
+		return nil, false
+	}
+	return tournament.clone(), true
+}
+
+// Update changes the tournament of a guild and persists it. update gets nil
+// if the guild has none and may return an error to change nothing.
+func (ts *TournamentStore) Update(guildID string, update func(t *Tournament) (*Tournament, error)) (*Tournament, error) {
+	ts.mutex.Lock()
+	defer ts.mutex.Unlock()
+
+	var current *Tournament
+	if tournament, exists := ts.tournaments[guildID]; exists {
+		current = tournament.clone()
+	}
+
+	updated, err := update(current)
+	if err != nil {
+		return nil, err
+	}
+
+	// Only kept once saved, so a failed save leaves the store as it was
+	stored := maps.Clone(ts.tournaments)
+	stored[guildID] = updated
+	if err := saveJSON(tournamentsFile, stored); err != nil {
+		return nil, fmt.Errorf("error saving tournaments: %w", err)
+	}
+	ts.tournaments = stored
+	return updated.clone(), nil
+}
+
+var tournaments *TournamentStore
+
+var tournamentCommand = &discordgo.ApplicationCommand{
+	Name:        "tournament",
+	Description: "Play the same seeds against each other",
+	Options: []*discordgo.ApplicationCommandOption{
+		{
+			Type:        discordgo.ApplicationCommandOptionSubCommand,
+			Name:        "create",
+			Description: "Start a tournament, needs Manage Server",
+			Options: []*discordgo.ApplicationCommandOption{
+				{
+					Type:        discordgo.ApplicationCommandOptionString,
+					Name:        "name",
+					Description: "Name of the tournament",
+					Required:    true,
+					MaxLength:   64,
+				},
+				{
+					Type:        discordgo.ApplicationCommandOptionString,
+					Name:        "seeds",
+					Description: "8 rooms per seed separated by commas, seeds separated by ;",
+					Required:    true,
+				},
+			},
+		},
+		{
+			Type:        discordgo.ApplicationCommandOptionSubCommand,
+			Name:        "join",
+			Description: "Join the tournament",
+		},
+		{
+			Type:        discordgo.ApplicationCommandOptionSubCommand,
+			Name:        "submit",
+			Description: "Submit your time on a seed, your best one counts",
+			Options: []*discordgo.ApplicationCommandOption{
+				{
+					Type:        discordgo.ApplicationCommandOptionInteger,
+					Name:        "seed",
+					Description: "Number of the seed",
+					Required:    true,
+					MinValue:    func() *float64 { v := 1.0; return &v }(),
+					MaxValue:    maxTournamentSeeds,
+				},
+				{
+					Type:        discordgo.ApplicationCommandOptionString,
+					Name:        "time",
+					Description: "Your time like 2:14.3",
+					Required:    true,
+				},
+			},
+		},
+		{
+			Type:        discordgo.ApplicationCommandOptionSubCommand,
+			Name:        "standings",
+			Description: "Show the standings",
+		},
+		{
+			Type:        discordgo.ApplicationCommandOptionSubCommand,
+			Name:        "close",
+			Description: "End the tournament, needs Manage Server",
+		},
+	},
+}
+
+func tournamentHandler(s Session, i *discordgo.InteractionCreate) {
+	logUserInteraction(i, "command", "tournament")
+
+	respond := func(content string) {
+		err := s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+			Type: discordgo.InteractionResponseChannelMessageWithSource,
+			Data: &discordgo.InteractionResponseData{
+				Content:         content,
+				Flags:           discordgo.MessageFlagsEphemeral,
+				AllowedMentions: &discordgo.MessageAllowedMentions{},
+			},
+		})
+		if err != nil {
+			log.Errorf("Failed to respond to tournament command: %v", err)
+		}
+	}
+
+	if i.GuildID == "" || i.Member == nil || i.Member.User == nil {
+		respond("Tournaments only work in a server.")
+		return
+	}
+
+	options := i.ApplicationCommandData().Options
+	if len(options) == 0 {
+		respond("You sent an incomplete command.")
+		return
+	}
+
+	subcommand := options[0]
+	values := make(map[string]*discordgo.ApplicationCommandInteractionDataOption)
+	for _, opt := range subcommand.Options {
+		values[opt.Name] = opt
+	}
+
+	// respondUpdateError tells the user why a tournament update failed
+	respondUpdateError := func(err error) {
+		var userErr tournamentError
+		if errors.As(err, &userErr) {
+			respond(userErr.Error())
+			return
+		}
+		log.Errorf("Failed to update tournament of guild %s: %v", i.GuildID, err)
+		respond("I couldn't save the tournament, go tell the developer.")
+	}
+
+	isAdmin := i.Member.Permissions&discordgo.PermissionManageServer != 0
+	userID := i.Member.User.ID
+
+	switch subcommand.Name {
+	case "create":
+		if !isAdmin {
+			respond("You need the Manage Server permission to create a tournament.")
+			return
+		}
+		seeds, err := parseTournamentSeeds(values["seeds"].StringValue())
+		if err != nil {
+			respond(err.Error())
+			return
+		}
+
+		tournament, err := tournaments.Update(i.GuildID, func(t *Tournament) (*Tournament, error) {
+			if t != nil && !t.Closed {
+				return nil, tournamentError(fmt.Sprintf("%s is still running, close it first.", t.Name))
+			}
+			return &Tournament{
+				Name:      values["name"].StringValue(),
+				CreatedBy: userID,
+				CreatedAt: time.Now(),
+				Seeds:     seeds,
+			}, nil
+		})
+		if err != nil {
+			respondUpdateError(err)
+			return
+		}
+
+		// The standings are a message of their own so every submission can edit them
+		message, err := s.ChannelMessageSendComplex(i.ChannelID, &discordgo.MessageSend{
+			Embeds: []*discordgo.MessageEmbed{createStandingsEmbed(tournament)},
+		})
+		if err != nil {
+			log.Errorf("Failed to post tournament standings: %v", err)
+			respond("I created the tournament but couldn't post the standings here, check my permissions.")
+			return
+		}
+		_, err = tournaments.Update(i.GuildID, func(t *Tournament) (*Tournament, error) {
+			t.StandingsChannelID = message.ChannelID
+			t.StandingsMessageID = message.ID
+			return t, nil
+		})
+		if err != nil {
+			log.Errorf("Failed to save tournament standings message: %v", err)
+		}
+		respond(fmt.Sprintf("%s is on! Players can `/tournament join` now.", tournament.Name))
+
+	case "join":
+		name := i.Member.User.Username
+		if i.Member.Nick != "" {
+			name = i.Member.Nick
+		}
+		tournament, err := tournaments.Update(i.GuildID, func(t *Tournament) (*Tournament, error) {
+			if err := checkTournamentOpen(t); err != nil {
+				return nil, err
+			}
+			if _, joined := t.Participants[userID]; joined {
+				return nil, tournamentError(fmt.Sprintf("You're already in %s.", t.Name))
+			}
+			if t.Participants == nil {
+				t.Participants = make(map[string]*Participant)
+			}
+			t.Participants[userID] = &Participant{Name: name}
+			return t, nil
+		})
+		if err != nil {
+			respondUpdateError(err)
+			return
+		}
+		updateStandings(tournament)
+		respond(fmt.Sprintf("You're in %s. Good luck!", tournament.Name))
+
+	case "submit":
+		index := int(values["seed"].IntValue()) - 1
+		submitted, err := ParseTime(values["time"].StringValue())
+		if err != nil {
+			respond(err.Error())
+			return
+		}
+		if submitted <= 0 || math.IsInf(submitted, 0) || math.IsNaN(submitted) {
+			respond("Your time has to be more than 0 seconds.")
+			return
+		}
+
+		improved := false
+		tournament, err := tournaments.Update(i.GuildID, func(t *Tournament) (*Tournament, error) {
+			if err := checkTournamentOpen(t); err != nil {
+				return nil, err
+			}
+			participant, joined := t.Participants[userID]
+			if !joined {
+				return nil, tournamentError("You have to `/tournament join` first.")
+			}
+			if index < 0 || index >= len(t.Seeds) {
+				return nil, tournamentError(fmt.Sprintf("%s only has %d seeds.", t.Name, len(t.Seeds)))
+			}
+			if best, exists := participant.Times[index]; exists && best <= submitted {
+				return t, nil
+			}
+			if participant.Times == nil {
+				participant.Times = make(map[int]float64)
+			}
+			participant.Times[index] = submitted
+			improved = true
+			return t, nil
+		})
+		if err != nil {
+			respondUpdateError(err)
+			return
+		}
+
+		optimal := tournament.Seeds[index].Optimal
+		if !improved {
+			respond(fmt.Sprintf("You already have %s on seed %d, that one counts.",
+				FormatTime(tournament.Participants[userID].Times[index]), index+1))
+			return
+		}
+		updateStandings(tournament)
+		respond(fmt.Sprintf("Submitted %s on seed %d, %s the calc's %s.",
+			FormatTime(submitted), index+1, formatBehind(submitted-optimal), FormatTime(optimal)))
+
+	case "standings":
+		tournament, exists := tournaments.Get(i.GuildID)
+		if !exists {
+			respond("There's no tournament here yet.")
+			return
+		}
+		err := s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+			Type: discordgo.InteractionResponseChannelMessageWithSource,
+			Data: &discordgo.InteractionResponseData{
+				Embeds: []*discordgo.MessageEmbed{createStandingsEmbed(tournament)},
+			},
+		})
+		if err != nil {
+			log.Errorf("Failed to show tournament standings: %v", err)
+		}
+
+	case "close":
+		if !isAdmin {
+			respond("You need the Manage Server permission to close a tournament.")
+			return
+		}
+		tournament, err := tournaments.Update(i.GuildID, func(t *Tournament) (*Tournament, error) {
+			if err := checkTournamentOpen(t); err != nil {
+				return nil, err
+			}
+			t.Closed = true
+			return t, nil
+		})
+		if err != nil {
+			respondUpdateError(err)
+			return
+		}
+		updateStandings(tournament)
+
+		content := fmt.Sprintf("%s is over.", tournament.Name)
+		if standings := tournament.Standings(); len(standings) > 0 {
+			content += fmt.Sprintf(" <@%s> wins!", standings[0].UserID)
+		}
+		err = s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+			Type: discordgo.InteractionResponseChannelMessageWithSource,
+			Data: &discordgo.InteractionResponseData{
+				Content: content,
+				Embeds:  []*discordgo.MessageEmbed{createStandingsEmbed(tournament)},
+			},
+		})
+		if err != nil {
+			log.Errorf("Failed to announce tournament end: %v", err)
+		}
+
+	default:
+		respond(fmt.Sprintf("Unknown subcommand \"%s\".", subcommand.Name))
+	}
+}
+
+func checkTournamentOpen(t *Tournament) error {
+	if t == nil || t.Closed {
+		return tournamentError("There's no tournament running here.")
+	}
+	return nil
+}
+
+// parseTournamentSeeds reads seeds written as rooms separated by commas,
+// seeds separated by semicolons, and calcs the best time of each
+func parseTournamentSeeds(text string) ([]TournamentSeed, error) {
+	var seeds []TournamentSeed
+	for k, seedText := range strings.Split(text, ";") {
+		if strings.TrimSpace(seedText) == "" {
+			continue
+		}
+
+		var rooms []string
+		for _, room := range strings.Split(seedText, ",") {
+			rooms = append(rooms, strings.ToLower(strings.TrimSpace(room)))
+		}
+		if len(rooms) != 8 {
+			return nil, fmt.Errorf("Seed %d has %d rooms, it needs 8.", k+1, len(rooms))
+		}
+		if valid, err := validateInput(rooms); !valid {
+			return nil, fmt.Errorf("Seed %d: %v", k+1, err)
+		}
+
+		results, err := calc.CalcSeed(append([]string{}, rooms...))
+		if err != nil {
+			log.Errorf("Failed to calc tournament seed %v: %v", rooms, err)
+			return nil, fmt.Errorf("I couldn't calc seed %d.", k+1)
+		}
+		seeds = append(seeds, TournamentSeed{Rooms: rooms, Optimal: results[0].BoostTime})
+	}
+
+	if len(seeds) == 0 {
+		return nil, fmt.Errorf("A tournament needs at least one seed.")
+	}
+	if len(seeds) > maxTournamentSeeds {
+		return nil, fmt.Errorf("A tournament can have at most %d seeds.", maxTournamentSeeds)
+	}
+	return seeds, nil
+}
+
+// formatBehind describes how a time compares to the calc's
+func formatBehind(behind float64) string {
+	if behind < 0 {
+		return fmt.Sprintf("%.1fs faster than", -behind)
+	}
+	return fmt.Sprintf("%.1fs slower than", behind)
+}
+
+// updateStandings edits the standings message of a tournament
+func updateStandings(tournament *Tournament) {
+	if tournament.StandingsMessageID == "" {
+		return
+	}
+
+	embeds := []*discordgo.MessageEmbed{createStandingsEmbed(tournament)}
+	_, err := s.ChannelMessageEditComplex(&discordgo.MessageEdit{
+		Channel: tournament.StandingsChannelID,
+		ID:      tournament.StandingsMessageID,
+		Embeds:  &embeds,
+	})
+	if err != nil {
+		log.Errorf("Failed to update tournament standings: %v", err)
+	}
+}
+
+// shortName cuts a name to standingsNameLength characters
+func shortName(name string) string {
+	if runes := []rune(name); len(runes) > standingsNameLength {
+		return string(runes[:standingsNameLength])
+	}
+	return name
+}
+
+func createStandingsEmbed(tournament *Tournament) *discordgo.MessageEmbed {
+	var seeds strings.Builder
+	for k, seed := range tournament.Seeds {
+		seeds.WriteString(fmt.Sprintf("**%d.** %s (calc %s)\n", k+1, strings.Join(seed.Rooms, ", "), FormatTime(seed.Optimal)))
+	}
+
+	var standings strings.Builder
+	ranked := tournament.Standings()
+	if len(ranked) == 0 {
+		standings.WriteString("Nobody joined yet.")
+	} else {
+		standings.WriteString("```\n")
+		for k, standing := range ranked[:min(len(ranked), standingsShown)] {
+			standings.WriteString(fmt.Sprintf("%2d. %-16s %2d/%d %+7.1fs\n",
+				k+1, shortName(standing.Name), standing.Played, len(tournament.Seeds), standing.Behind))
+		}
+		standings.WriteString("```")
+	}
+
+	title := tournament.Name
+	footer := "Submit with /tournament submit, time lost to the calc over the seeds played"
+	if tournament.Closed {
+		title += " (final)"
+		footer = "Time lost to the calc over the seeds played"
+	}
+
+	return &discordgo.MessageEmbed{
+		Title: title,
+		Color: 0x45D3B3,
+		Fields: []*discordgo.MessageEmbedField{
+			{Name: "Seeds", Value: seeds.String()},
+			{Name: "Standings", Value: standings.String()},
+		},
+		Footer: &discordgo.MessageEmbedFooter{Text: footer},
+	}
+}
@@ -0,0 +1,171 @@
+package discord
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// embedFieldLimit is the most characters Discord accepts in an embed field value
+const embedFieldLimit = 1024
+
+func checkFieldLengths(t *testing.T, embed *discordgo.MessageEmbed) {
+	t.Helper()
+
+	for _, field := range embed.Fields {
+		if length := utf8.RuneCountInString(field.Value); length > embedFieldLimit {
+			t.Errorf("field %q is %d characters long", field.Name, length)
+		}
+	}
+}
+
+func TestEmbedsFitLongNames(t *testing.T) {
+	tournament := &Tournament{
+		Name:         "Weekly",
+		Seeds:        []TournamentSeed{{Rooms: []string{"1a", "2b", "3c", "4e", "5a", "1c", "2f", "3g"}, Optimal: 120}},
+		Participants: make(map[string]*Participant),
+	}
+	daily := &DailyChallenge{
+		Date:        "2025-01-01",
+		Rooms:       tournament.Seeds[0].Rooms,
+		Optimal:     120,
+		Submissions: make(map[string]*DailySubmission),
+	}
+	for k := 0; k < 30; k++ {
+		name := fmt.Sprintf("%02d%s", k, strings.Repeat("ñ", 30))
+		tournament.Participants[fmt.Sprint(k)] = &Participant{Name: name, Times: map[int]float64{0: 125}}
+		daily.Submissions[fmt.Sprint(k)] = &DailySubmission{Name: name, Time: 125}
+	}
+
+	checkFieldLengths(t, createStandingsEmbed(tournament))
+	checkFieldLengths(t, createDailySummaryEmbed(daily))
+}
+
+// subcommandInteraction runs a subcommand in a guild as the scenario user,
+// with Manage Server if admin is set
+func subcommandInteraction(id, guildID, command, subcommand string, admin bool, options ...*discordgo.ApplicationCommandInteractionDataOption) *discordgo.InteractionCreate {
+	i := commandInteraction(id, guildID+"-channel", command, &discordgo.ApplicationCommandInteractionDataOption{
+		Name:    subcommand,
+		Type:    discordgo.ApplicationCommandOptionSubCommand,
+		Options: options,
+	})
+	i.GuildID = guildID
+	if admin {
+		i.Member.Permissions = discordgo.PermissionManageServer
+	}
+	return i
+}
+
+func stringOption(name, value string) *discordgo.ApplicationCommandInteractionDataOption {
+	return &discordgo.ApplicationCommandInteractionDataOption{Name: name, Type: discordgo.ApplicationCommandOptionString, Value: value}
+}
+
+func integerOption(name string, value int) *discordgo.ApplicationCommandInteractionDataOption {
+	return &discordgo.ApplicationCommandInteractionDataOption{Name: name, Type: discordgo.ApplicationCommandOptionInteger, Value: float64(value)}
+}
+
+// responseContent returns what an interaction was answered with
+func responseContent(t *testing.T, fs *FakeSession, i *discordgo.InteractionCreate) string {
+	t.Helper()
+
+	message, exists := fs.Messages[interactionMessageID(i.Interaction)]
+	if !exists {
+		t.Fatalf("interaction %s got no response", i.ID)
+	}
+	return message.Content
+}
+
+func TestTournamentScenario(t *testing.T) {
+	fs := setupScenario(t, "guild")
+	seeds := strings.Join(scenarioRooms, ",") + ";" + strings.Join(scenarioRooms, ",")
+
+	steps := []struct {
+		name        string
+		interaction *discordgo.InteractionCreate
+		response    string
+	}{
+		{"create without permission", subcommandInteraction("1", "guild", "tournament", "create", false,
+			stringOption("name", "Weekly"), stringOption("seeds", seeds)), "Manage Server"},
+		{"create", subcommandInteraction("2", "guild", "tournament", "create", true,
+			stringOption("name", "Weekly"), stringOption("seeds", seeds)), "Weekly is on"},
+		{"create while running", subcommandInteraction("3", "guild", "tournament", "create", true,
+			stringOption("name", "Other"), stringOption("seeds", seeds)), "close it first"},
+		{"submit before joining", subcommandInteraction("4", "guild", "tournament", "submit", false,
+			integerOption("seed", 1), stringOption("time", "2:10")), "join` first"},
+		{"join", subcommandInteraction("5", "guild", "tournament", "join", false), "You're in Weekly"},
+		{"join twice", subcommandInteraction("6", "guild", "tournament", "join", false), "already in"},
+		{"submit", subcommandInteraction("7", "guild", "tournament", "submit", false,
+			integerOption("seed", 1), stringOption("time", "2:10")), "Submitted 2:10.0 on seed 1"},
+		{"submit slower", subcommandInteraction("8", "guild", "tournament", "submit", false,
+			integerOption("seed", 1), stringOption("time", "2:20")), "You already have 2:10.0"},
+		{"submit NaN", subcommandInteraction("9", "guild", "tournament", "submit", false,
+			integerOption("seed", 1), stringOption("time", "NaN")), "not a valid time"},
+		{"submit missing seed", subcommandInteraction("10", "guild", "tournament", "submit", false,
+			integerOption("seed", 3), stringOption("time", "2:10")), "only has 2 seeds"},
+		{"submit faster", subcommandInteraction("11", "guild", "tournament", "submit", false,
+			integerOption("seed", 1), stringOption("time", "2:05")), "Submitted 2:05.0 on seed 1"},
+		{"close", subcommandInteraction("12", "guild", "tournament", "close", true), "Weekly is over. <@user> wins!"},
+		{"join closed", subcommandInteraction("13", "guild", "tournament", "join", false), "no tournament running"},
+	}
+
+	for _, step := range steps {
+		HandleInteraction(fs, step.interaction)
+		if content := responseContent(t, fs, step.interaction); !strings.Contains(content, step.response) {
+			t.Errorf("%s: got %q, want it to contain %q", step.name, content, step.response)
+		}
+	}
+
+	tournament, exists := tournaments.Get("guild")
+	if !exists || !tournament.Closed || len(tournament.Seeds) != 2 {
+		t.Fatalf("got tournament %+v", tournament)
+	}
+	if times := tournament.Participants["user"].Times; len(times) != 1 || times[0] != 125 {
+		t.Errorf("expected the best time to be kept, got %v", times)
+	}
+
+	// The standings message follows every change
+	standings := fs.Messages[tournament.StandingsMessageID]
+	if standings == nil || len(standings.Embeds) != 1 {
+		t.Fatalf("the standings message is missing: %+v", standings)
+	}
+	var fields strings.Builder
+	for _, field := range standings.Embeds[0].Fields {
+		fields.WriteString(field.Value)
+	}
+	if !strings.Contains(fields.String(), "player") {
+		t.Errorf("the standings don't list the player: %q", fields.String())
+	}
+}
+
+func TestTournamentUpdateKeepsFailedSaveOut(t *testing.T) {
+	useDataDir(t)
+	store, err := NewTournamentStore()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	create := func(name string) func(*Tournament) (*Tournament, error) {
+		return func(*Tournament) (*Tournament, error) { return &Tournament{Name: name}, nil }
+	}
+	if _, err := store.Update("guild", create("Weekly")); err != nil {
+		t.Fatal(err)
+	}
+
+	breakDataDir(t)
+	if _, err := store.Update("guild", create("Broken")); err == nil {
+		t.Fatal("expected the save to fail")
+	}
+	if _, err := store.Update("other", create("Broken")); err == nil {
+		t.Fatal("expected the save to fail")
+	}
+
+	if tournament, _ := store.Get("guild"); tournament.Name != "Weekly" {
+		t.Errorf("the failed update was kept: %+v", tournament)
+	}
+	if _, exists := store.Get("other"); exists {
+		t.Error("the failed update of another guild was kept")
+	}
+}
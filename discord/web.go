@@ -0,0 +1,302 @@
+package discord
+
+import (
+	"errors"
+	"fmt"
+	"html/template"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+
+	"atlantis_calc/calc"
+
+	log "github.com/sirupsen/logrus"
+)
+
+type webSlot struct {
+	Name     string
+	Label    string
+	Selected string
+	Options  []string
+}
+
+type webLink struct {
+	Label  string
+	URL    string
+	Active bool
+}
+
+type webPage struct {
+	Slots       []webSlot
+	Error       string
+	HasResult   bool
+	ImageURL    string
+	Position    string
+	Previous    string
+	Next        string
+	Filters     []webLink
+	Calculation string
+}
+
+var webTemplate = template.Must(template.New("calc").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>PKD Calc</title>
+<style>
+body { background: #1e1f22; color: #dbdee1; font-family: sans-serif; margin: 2em; }
+form { display: grid; grid-template-columns: repeat(4, max-content); gap: 0.5em 1em; margin-bottom: 1em; }
+select, button { background: #2b2d31; color: #dbdee1; border: 1px solid #45D3B3; padding: 0.3em; }
+a { color: #45D3B3; margin-right: 1em; }
+a.active { font-weight: bold; text-decoration: none; }
+span.disabled { color: #6d6f78; margin-right: 1em; }
+.error { color: #f23f43; }
+pre { background: #2b2d31; padding: 1em; display: inline-block; }
+</style>
+</head>
+<body>
+<h1>PKD Calc</h1>
+<form method="get" action="/">
+{{range .Slots}}<label>{{.Label}}
+<select name="{{.Name}}">
+<option value=""></option>
+{{$selected := .Selected}}{{range .Options}}<option value="{{.}}"{{if eq . $selected}} selected{{end}}>{{.}}</option>
+{{end}}</select>
+</label>
+{{end}}<button type="submit">Calc</button>
+</form>
+{{if .Error}}<p class="error">{{.Error}}</p>{{end}}
+{{if .HasResult}}
+<p>
+{{range .Filters}}<a href="{{.URL}}"{{if .Active}} class="active"{{end}}>{{.Label}}</a>{{end}}
+</p>
+<p>
+{{if .Previous}}<a href="{{.Previous}}">&larr; Previous</a>{{else}}<span class="disabled">&larr; Previous</span>{{end}}
+{{.Position}}
+{{if .Next}}<a href="{{.Next}}">Next &rarr;</a>{{else}}<span class="disabled">Next &rarr;</span>{{end}}
+</p>
+<img src="{{.ImageURL}}" alt="calc result">
+<h2>How did you get this?</h2>
+<pre>{{.Calculation}}</pre>
+{{end}}
+</body>
+</html>
+`))
+
+// serveWebUI serves the calculator web page on addr in the background. The
+// returned server is for shutting it down.
+func serveWebUI(addr string) *http.Server {
+	server := &http.Server{Addr: addr, Handler: webHandler()}
+	go func() {
+		log.Infof("Serving web UI on %s", addr)
+		if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Errorf("Web UI stopped: %v", err)
+		}
+	}()
+
+	return server
+}
+
+// webHandler routes the pages of the web UI
+func webHandler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /{$}", webCalcPageHandler)
+	mux.HandleFunc("GET /result.png", webCalcImageHandler)
+	return mux
+}
+
+// webQuery holds the calculator parameters parsed from a web UI request.
+type webQuery struct {
+	Rooms  []string
+	Filter string
+	Index  int
+}
+
+func (q webQuery) values(filter string, index int) url.Values {
+	values := url.Values{}
+	for i, room := range q.Rooms {
+		values.Set(fmt.Sprintf("room_%d", i+1), room)
+	}
+	values.Set("filter", filter)
+	values.Set("index", strconv.Itoa(index))
+	return values
+}
+
+// parseWebQuery reads the rooms, filter and index from r. It returns
+// ok=false without an error when no rooms were submitted yet.
+func parseWebQuery(r *http.Request) (webQuery, bool, error) {
+	query := r.URL.Query()
+
+	q := webQuery{Filter: "any"}
+	submitted := false
+	for i := 1; i <= 8; i++ {
+		room := strings.ToLower(strings.TrimSpace(query.Get(fmt.Sprintf("room_%d", i))))
+		if room != "" {
+			submitted = true
+		}
+		q.Rooms = append(q.Rooms, room)
+	}
+	if !submitted {
+		return q, false, nil
+	}
+
+	for i, room := range q.Rooms {
+		if room == "" {
+			return q, true, fmt.Errorf("Please choose a room for room %d", i+1)
+		}
+	}
+
+	valid, err := validateInput(q.Rooms)
+	if !valid {
+		return q, true, err
+	}
+
+	for i, room := range q.Rooms {
+		if calc.RoomMap[room].Difficulty != slotDifficulty(i+1) {
+			return q, true, fmt.Errorf("Room %d can't be %s", i+1, room)
+		}
+	}
+
+	if filter := query.Get("filter"); filter != "" {
+		if _, ok := filterCodes[filter]; !ok {
+			return q, true, fmt.Errorf("Unknown filter \"%s\"", filter)
+		}
+		q.Filter = filter
+	}
+
+	if index := query.Get("index"); index != "" {
+		q.Index, err = strconv.Atoi(index)
+		if err != nil || q.Index < 0 {
+			return q, true, fmt.Errorf("Invalid result index \"%s\"", index)
+		}
+	}
+
+	return q, true, nil
+}
+
+// webResults runs the calc for q and returns the filtered results, clamping
+// q.Index into range.
+func webResults(q *webQuery) ([]calc.CalcSeedResult, error) {
+	rooms := make([]string, len(q.Rooms))
+	copy(rooms, q.Rooms)
+
+	res, err := calc.CalcSeed(rooms)
+	if err != nil {
+		return nil, err
+	}
+
+	filtered := getFilteredResults(&ResultState{
+		Rooms:   q.Rooms,
+		Results: res,
+		Filter:  filterCodes[q.Filter],
+	})
+	if len(filtered) == 0 {
+		return nil, fmt.Errorf("no results available for this filter")
+	}
+
+	if q.Index >= len(filtered) {
+		q.Index = len(filtered) - 1
+	}
+
+	return filtered, nil
+}
+
+func webSlots(selected []string) []webSlot {
+	slots := make([]webSlot, 0, 8)
+	for i := 1; i <= 8; i++ {
+		var options []string
+		for name, room := range calc.RoomMap {
+			if name == "finish room" || room.Difficulty != slotDifficulty(i) {
+				continue
+			}
+			options = append(options, name)
+		}
+		sort.Strings(options)
+
+		slot := webSlot{
+			Name:    fmt.Sprintf("room_%d", i),
+			Label:   fmt.Sprintf("Room %d", i),
+			Options: options,
+		}
+		if i-1 < len(selected) {
+			slot.Selected = selected[i-1]
+		}
+		slots = append(slots, slot)
+	}
+	return slots
+}
+
+func webCalcPageHandler(w http.ResponseWriter, r *http.Request) {
+	q, submitted, err := parseWebQuery(r)
+
+	page := webPage{Slots: webSlots(q.Rooms)}
+	status := http.StatusOK
+	switch {
+	case err != nil:
+		page.Error = err.Error()
+		status = http.StatusBadRequest
+	case submitted:
+		results, err := webResults(&q)
+		if err != nil {
+			log.Error(err)
+			page.Error = "Something's broken, go tell the developer"
+			status = http.StatusInternalServerError
+			break
+		}
+
+		page.HasResult = true
+		page.ImageURL = "/result.png?" + q.values(q.Filter, q.Index).Encode()
+		page.Position = fmt.Sprintf("%d / %d", q.Index+1, len(results))
+		if q.Index > 0 {
+			page.Previous = "/?" + q.values(q.Filter, q.Index-1).Encode()
+		}
+		if q.Index < len(results)-1 {
+			page.Next = "/?" + q.values(q.Filter, q.Index+1).Encode()
+		}
+		for _, f := range []struct{ label, value string }{{"2 Boost", "2"}, {"3 Boost", "3"}, {"Any Boost", "any"}} {
+			page.Filters = append(page.Filters, webLink{
+				Label:  f.label,
+				URL:    "/?" + q.values(f.value, 0).Encode(),
+				Active: f.value == q.Filter,
+			})
+		}
+
+		rooms := make([]string, len(q.Rooms))
+		copy(rooms, q.Rooms)
+		page.Calculation = strings.NewReplacer("```\n", "", "```", "", "**", "").
+			Replace(formatDetailedCalculation(rooms, results[q.Index], calc.RoomMap))
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(status)
+	if err := webTemplate.Execute(w, page); err != nil {
+		log.Errorf("Failed to render web UI: %v", err)
+	}
+}
+
+func webCalcImageHandler(w http.ResponseWriter, r *http.Request) {
+	q, submitted, err := parseWebQuery(r)
+	if err != nil || !submitted {
+		http.Error(w, "invalid rooms", http.StatusBadRequest)
+		return
+	}
+
+	results, err := webResults(&q)
+	if err != nil {
+		log.Error(err)
+		http.Error(w, "calculation failed", http.StatusInternalServerError)
+		return
+	}
+
+	img, err := drawCalcResults(q.Rooms, []calc.CalcSeedResult{results[q.Index]})
+	if err != nil {
+		log.Error(err)
+		http.Error(w, "drawing failed", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "image/png")
+	w.Write(img.Bytes())
+}
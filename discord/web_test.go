@@ -0,0 +1,148 @@
+package discord
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+// webQueryOf fills the room slots in order, then sets the extra parameters
+func webQueryOf(rooms []string, extra ...string) string {
+	values := url.Values{}
+	for i, room := range rooms {
+		values.Set(fmt.Sprintf("room_%d", i+1), room)
+	}
+	for k := 0; k+1 < len(extra); k += 2 {
+		values.Set(extra[k], extra[k+1])
+	}
+	return values.Encode()
+}
+
+// webGet requests a path from the web UI
+func webGet(t *testing.T, method, target string) (*http.Response, string) {
+	t.Helper()
+
+	recorder := httptest.NewRecorder()
+	webHandler().ServeHTTP(recorder, httptest.NewRequest(method, target, nil))
+	resp := recorder.Result()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return resp, string(body)
+}
+
+func TestWebCalcPage(t *testing.T) {
+	setupScenario(t)
+
+	swapped := append([]string{}, scenarioRooms...)
+	swapped[0], swapped[7] = swapped[7], swapped[0]
+
+	tests := []struct {
+		name   string
+		query  string
+		status int
+		want   []string
+	}{
+		{"empty form", "", http.StatusOK, []string{`<select name="room_8">`}},
+		{"result", webQueryOf(scenarioRooms), http.StatusOK,
+			[]string{`<img src="/result.png?`, "1 / ", "How did you get this?", `class="active">Any Boost`}},
+		{"two boosts", webQueryOf(scenarioRooms, "filter", "2"), http.StatusOK, []string{`class="active">2 Boost`}},
+		{"rooms keep their selection", webQueryOf(scenarioRooms), http.StatusOK, []string{`<option value="3g" selected>`}},
+		{"room missing", webQueryOf(scenarioRooms[:7]), http.StatusBadRequest, []string{"Please choose a room for room 8"}},
+		{"unknown room", webQueryOf(append([]string{"zzzz"}, scenarioRooms[1:]...)), http.StatusBadRequest, []string{"I don&#39;t know a room called &#34;zzzz&#34;"}},
+		{"room twice", webQueryOf(append([]string{"2b"}, scenarioRooms[1:]...)), http.StatusBadRequest, []string{"appears more than once"}},
+		{"room in the wrong slot", webQueryOf(swapped), http.StatusBadRequest, []string{"Room 1 can&#39;t be 3g"}},
+		{"unknown filter", webQueryOf(scenarioRooms, "filter", "4"), http.StatusBadRequest, []string{"Unknown filter &#34;4&#34;"}},
+		{"negative index", webQueryOf(scenarioRooms, "index", "-1"), http.StatusBadRequest, []string{"Invalid result index &#34;-1&#34;"}},
+		{"index not a number", webQueryOf(scenarioRooms, "index", "two"), http.StatusBadRequest, []string{"Invalid result index"}},
+		{"markup is escaped", webQueryOf(scenarioRooms, "filter", "<script>"), http.StatusBadRequest, []string{"&lt;script&gt;"}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			resp, body := webGet(t, http.MethodGet, "/?"+test.query)
+			if resp.StatusCode != test.status {
+				t.Errorf("got status %d, want %d", resp.StatusCode, test.status)
+			}
+			if contentType := resp.Header.Get("Content-Type"); !strings.HasPrefix(contentType, "text/html") {
+				t.Errorf("got content type %q", contentType)
+			}
+			for _, want := range test.want {
+				if !strings.Contains(body, want) {
+					t.Errorf("the page doesn't contain %q", want)
+				}
+			}
+			if strings.Contains(body, "<script>") {
+				t.Error("the page contains unescaped markup")
+			}
+		})
+	}
+}
+
+func TestWebCalcPageClampsIndex(t *testing.T) {
+	setupScenario(t)
+
+	_, first := webGet(t, http.MethodGet, "/?"+webQueryOf(scenarioRooms))
+	if !strings.Contains(first, `<span class="disabled">&larr; Previous</span>`) {
+		t.Error("the first result links to a previous one")
+	}
+
+	resp, last := webGet(t, http.MethodGet, "/?"+webQueryOf(scenarioRooms, "index", "1000"))
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("got status %d", resp.StatusCode)
+	}
+	if !strings.Contains(last, `<span class="disabled">Next &rarr;</span>`) || strings.Contains(last, "1001 / ") {
+		t.Error("the index past the last result wasn't clamped")
+	}
+}
+
+func TestWebCalcImage(t *testing.T) {
+	setupScenario(t)
+
+	resp, body := webGet(t, http.MethodGet, "/result.png?"+webQueryOf(scenarioRooms, "index", "1"))
+	if resp.StatusCode != http.StatusOK || resp.Header.Get("Content-Type") != "image/png" {
+		t.Fatalf("got status %d, content type %q", resp.StatusCode, resp.Header.Get("Content-Type"))
+	}
+	if !bytes.HasPrefix([]byte(body), []byte("\x89PNG")) {
+		t.Error("the image isn't a PNG")
+	}
+
+	for _, query := range []string{
+		"",
+		webQueryOf(scenarioRooms[:7]),
+		webQueryOf(scenarioRooms, "filter", "4"),
+		webQueryOf(scenarioRooms, "index", "-1"),
+	} {
+		resp, body := webGet(t, http.MethodGet, "/result.png?"+query)
+		if resp.StatusCode != http.StatusBadRequest || !strings.Contains(body, "invalid rooms") {
+			t.Errorf("%q got status %d: %s", query, resp.StatusCode, body)
+		}
+	}
+}
+
+func TestWebRoutes(t *testing.T) {
+	setupScenario(t)
+
+	tests := []struct {
+		method string
+		target string
+		status int
+	}{
+		{http.MethodPost, "/", http.StatusMethodNotAllowed},
+		{http.MethodPost, "/result.png", http.StatusMethodNotAllowed},
+		{http.MethodGet, "/missing", http.StatusNotFound},
+		{http.MethodHead, "/", http.StatusOK},
+	}
+
+	for _, test := range tests {
+		if resp, _ := webGet(t, test.method, test.target); resp.StatusCode != test.status {
+			t.Errorf("%s %s got status %d, want %d", test.method, test.target, resp.StatusCode, test.status)
+		}
+	}
+}
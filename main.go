@@ -2,12 +2,20 @@ package main
 
 import (
 	"log"
-	"atlantis_calc/discord" 
+	"os"
+
+	"atlantis_calc/config"
+	"atlantis_calc/discord"
 )
 
 func main() {
+	cfg, err := config.Load(os.Args[1:])
+	if err != nil {
+		log.Fatalf("Invalid configuration: %v", err)
+	}
+
 	// Start the bot
-	if err := discord.StartDiscordBot(); err != nil {
+	if err := discord.StartDiscordBot(cfg); err != nil {
 		log.Fatalf("Failed to start bot: %v", err)
 	}
 }